@@ -0,0 +1,415 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"server/logging"
+	"server/metrics"
+	"server/store"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recentDeliveries ...
+// Delivery attempts kept in memory for the API, the log file has all of them
+const recentDeliveries = 1000
+
+const (
+	workers   = 4
+	queueSize = 1000
+)
+
+// Failed deliveries are retried after backoffMin, doubling up to backoffMax
+var (
+	backoffMin = time.Second
+	backoffMax = time.Minute
+)
+
+var (
+	firedTotal = metrics.NewCounterVec(
+		"vsr_alerts_fired_total",
+		"Alert events raised, by rule",
+		"rule",
+	)
+	deliveriesTotal = metrics.NewCounterVec(
+		"vsr_alert_deliveries_total",
+		"Webhook delivery attempts, by rule and result (delivered, failed, abandoned)",
+		"rule", "result",
+	)
+)
+
+// job ...
+type job struct {
+	event   Event
+	webhook Webhook
+	body    []byte
+}
+
+// Alerter ...
+// Evaluates watch rules against every new transcript and delivers matches to webhooks
+type Alerter struct {
+	config Config
+	client *http.Client
+
+	mu       sync.RWMutex
+	matchers map[string]*matcher
+	recent   []Delivery
+	history  *os.File // The delivery log
+	log      *logging.Logger
+
+	queue   chan job
+	closed  bool
+	stop    chan struct{}
+	workers sync.WaitGroup
+}
+
+// New ...
+func New(config Config) (*Alerter, error) {
+	err := os.MkdirAll(config.Path, 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := os.OpenFile(filepath.Join(config.Path, "deliveries.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Alerter{
+		config:   config,
+		client:   &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+		matchers: make(map[string]*matcher),
+		recent:   make([]Delivery, 0),
+		history:  history,
+		log:      logging.New("alerts"),
+		queue:    make(chan job, queueSize),
+		stop:     make(chan struct{}),
+	}
+
+	for _, rule := range config.Rules {
+		rule.Static = true
+		err = a.add(rule)
+		if err != nil {
+			history.Close()
+			return nil, err
+		}
+	}
+
+	// Rules registered through the API in previous runs
+	raw, err := ioutil.ReadFile(a.rulesPath())
+	if err == nil {
+		var rules []Rule
+		err = json.Unmarshal(raw, &rules)
+		if err != nil {
+			history.Close()
+			return nil, fmt.Errorf("could not parse %s: %v", a.rulesPath(), err)
+		}
+
+		for _, rule := range rules {
+			rule.Static = false
+			err = a.add(rule)
+			if err != nil {
+				a.log.Warn("Skipping stored alert rule", "rule", rule.ID, "err", err)
+			}
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		a.workers.Add(1)
+		go a.work()
+	}
+
+	return a, nil
+}
+
+// Add ...
+// Registers a rule, replacing a previously registered one with the same ID
+func (a *Alerter) Add(rule Rule) error {
+	rule.Static = false
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.add(rule)
+	if err != nil {
+		return err
+	}
+
+	return a.save()
+}
+
+// Remove ...
+func (a *Alerter) Remove(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	m, ok := a.matchers[id]
+	if !ok {
+		return fmt.Errorf("rule %s does not exist", id)
+	}
+
+	if m.rule.Static {
+		return fmt.Errorf("rule %s is part of the configuration file and can't be removed", id)
+	}
+
+	delete(a.matchers, id)
+	return a.save()
+}
+
+// Rules ...
+// Every rule, sorted by ID, without webhook secrets
+func (a *Alerter) Rules() []Rule {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(a.matchers))
+	for _, m := range a.matchers {
+		rules = append(rules, m.rule.Redacted())
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	return rules
+}
+
+// Deliveries ...
+// The most recent delivery attempts, newest first
+func (a *Alerter) Deliveries() []Delivery {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	result := make([]Delivery, 0, len(a.recent))
+	for i := len(a.recent) - 1; i >= 0; i-- {
+		result = append(result, a.recent[i])
+	}
+
+	return result
+}
+
+// Transcript ...
+// Evaluates every rule against a new transcript, deliveries happen in the background
+func (a *Alerter) Transcript(entry store.Entry) {
+	a.mu.RLock()
+	jobs := make([]job, 0)
+	for _, m := range a.matchers {
+		for _, o := range m.match(entry) {
+			event := m.event(newID(), entry, o)
+
+			body, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+
+			jobs = append(jobs, job{event: event, webhook: m.rule.Webhook, body: body})
+		}
+	}
+	a.mu.RUnlock()
+
+	for _, j := range jobs {
+		a.log.Info("Rule matched", "rule", j.event.Rule, "text", j.event.Text, "channel", j.event.Channel,
+			"segment", entry.CorrelationID, "event", j.event.ID)
+		firedTotal.Inc(j.event.Rule)
+
+		if !a.enqueue(j) {
+			a.record(Delivery{Event: j.event.ID, Rule: j.event.Rule, URL: j.webhook.URL, At: time.Now(), Error: "delivery queue is full or closed", Final: true})
+			deliveriesTotal.Inc(j.event.Rule, "abandoned")
+		}
+	}
+}
+
+// enqueue hands the job to the workers unless the queue is full or closed
+func (a *Alerter) enqueue(j job) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return false
+	}
+
+	select {
+	case a.queue <- j:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown ...
+// Finishes queued deliveries, abandoning the rest when the context is done
+func (a *Alerter) Shutdown(ctx context.Context) {
+	a.mu.Lock()
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		a.log.Warn("Webhook deliveries did not finish in time, abandoning")
+		close(a.stop)
+		<-done
+	}
+
+	a.history.Close()
+}
+
+func (a *Alerter) add(rule Rule) error {
+	err := rule.Validate()
+	if err != nil {
+		return err
+	}
+
+	if existing, ok := a.matchers[rule.ID]; ok && existing.rule.Static {
+		return fmt.Errorf("rule %s is part of the configuration file and can't be replaced", rule.ID)
+	}
+
+	m, err := compile(rule)
+	if err != nil {
+		return err
+	}
+
+	a.matchers[rule.ID] = m
+	return nil
+}
+
+func (a *Alerter) rulesPath() string {
+	return filepath.Join(a.config.Path, "rules.json")
+}
+
+// save persists the rules registered through the API. Must be called with the lock held.
+func (a *Alerter) save() error {
+	rules := make([]Rule, 0)
+	for _, m := range a.matchers {
+		if !m.rule.Static {
+			rules = append(rules, m.rule)
+		}
+	}
+
+	raw, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Written next to the file and renamed so a crash never leaves it half written
+	tmp := a.rulesPath() + ".tmp"
+	err = ioutil.WriteFile(tmp, raw, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, a.rulesPath())
+}
+
+func (a *Alerter) work() {
+	defer a.workers.Done()
+
+	for j := range a.queue {
+		a.deliver(j)
+	}
+}
+
+// deliver retries with exponential backoff until the webhook accepts the event or the attempts run out
+func (a *Alerter) deliver(j job) {
+	backoff := backoffMin
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-a.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		status, err := post(ctx, a.client, j.webhook, j.event.ID, j.body)
+		cancel()
+
+		delivery := Delivery{
+			Event:     j.event.ID,
+			Rule:      j.event.Rule,
+			URL:       j.webhook.URL,
+			Attempt:   attempt,
+			At:        time.Now(),
+			Status:    status,
+			Delivered: err == nil,
+			Final:     err == nil || attempt >= a.config.MaxAttempts,
+		}
+
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		a.record(delivery)
+
+		if err == nil {
+			deliveriesTotal.Inc(j.event.Rule, "delivered")
+			return
+		}
+
+		if delivery.Final {
+			a.log.Error("Giving up on delivery", "event", j.event.ID, "rule", j.event.Rule, "channel", j.event.Channel,
+				"attempts", attempt, "err", err)
+			deliveriesTotal.Inc(j.event.Rule, "abandoned")
+			return
+		}
+
+		deliveriesTotal.Inc(j.event.Rule, "failed")
+
+		select {
+		case <-time.After(backoff):
+		case <-a.stop:
+			a.record(Delivery{Event: j.event.ID, Rule: j.event.Rule, URL: j.webhook.URL, At: time.Now(), Error: "shutting down", Final: true})
+			deliveriesTotal.Inc(j.event.Rule, "abandoned")
+			return
+		}
+
+		backoff *= 2
+		if backoff > backoffMax {
+			backoff = backoffMax
+		}
+	}
+}
+
+// record appends to the delivery log and the in-memory window of recent deliveries
+func (a *Alerter) record(delivery Delivery) {
+	raw, err := json.Marshal(delivery)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err = a.history.Write(append(raw, '\n'))
+	if err != nil {
+		a.log.Error("Could not write to the delivery log", "err", err)
+	}
+
+	a.recent = append(a.recent, delivery)
+	if len(a.recent) > recentDeliveries {
+		a.recent = a.recent[len(a.recent)-recentDeliveries:]
+	}
+}
+
+func newID() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,141 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeliver(t *testing.T) {
+	backoffMin, backoffMax = 20*time.Millisecond, 40*time.Millisecond
+	defer func() {
+		backoffMin, backoffMax = time.Second, time.Minute
+	}()
+
+	tests := []struct {
+		name      string
+		failures  int // Requests the receiver rejects before accepting
+		attempts  int
+		delivered bool
+	}{
+		{name: "first attempt", failures: 0, attempts: 1, delivered: true},
+		{name: "after retries", failures: 2, attempts: 3, delivered: true},
+		{name: "giving up", failures: 10, attempts: 4, delivered: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var received []time.Time
+
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				received = append(received, time.Now())
+				if len(received) <= test.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer receiver.Close()
+
+			dir, err := ioutil.TempDir("", "alerts")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			a, err := New(Config{Path: dir, MaxAttempts: 4, Timeout: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a.deliver(job{event: Event{ID: "event", Rule: "rule"}, webhook: Webhook{URL: receiver.URL}, body: []byte("{}")})
+			a.Shutdown(context.Background())
+
+			deliveries := a.Deliveries()
+			if len(deliveries) != test.attempts || len(received) != test.attempts {
+				t.Fatalf("%d deliveries recorded and %d received, want %d", len(deliveries), len(received), test.attempts)
+			}
+
+			// Newest first, only the last attempt is final
+			last := deliveries[0]
+			if last.Attempt != test.attempts || !last.Final || last.Delivered != test.delivered {
+				t.Errorf("last delivery = %+v, want attempt %d, final, delivered %v", last, test.attempts, test.delivered)
+			}
+
+			for _, d := range deliveries[1:] {
+				if d.Final || d.Delivered || d.Status != http.StatusServiceUnavailable {
+					t.Errorf("earlier delivery = %+v, want a failed, non-final attempt", d)
+				}
+			}
+
+			// Backing off 20ms, 40ms, then capped at 40ms
+			for i := 1; i < len(received); i++ {
+				want := backoffMin << uint(i-1)
+				if want > backoffMax {
+					want = backoffMax
+				}
+
+				if gap := received[i].Sub(received[i-1]); gap < want {
+					t.Errorf("attempt %d came %v after the previous one, want at least %v", i+1, gap, want)
+				}
+			}
+
+			history, err := ioutil.ReadFile(filepath.Join(dir, "deliveries.jsonl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if lines := bytes.Count(history, []byte("\n")); lines != test.attempts {
+				t.Errorf("delivery log has %d lines, want %d", lines, test.attempts)
+			}
+		})
+	}
+}
+
+func TestShutdownAbandonsRetries(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	dir, err := ioutil.TempDir("", "alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := New(Config{Path: dir, MaxAttempts: 5, Timeout: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.enqueue(job{event: Event{ID: "event", Rule: "rule"}, webhook: Webhook{URL: receiver.URL}, body: []byte("{}")}) {
+		t.Fatal("could not enqueue the delivery")
+	}
+
+	// The first attempt fails, then the worker waits a second before retrying
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	a.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("shutdown took %v, want the retry abandoned", elapsed)
+	}
+
+	deliveries := a.Deliveries()
+	if len(deliveries) != 2 || deliveries[0].Error != "shutting down" || !deliveries[0].Final {
+		t.Errorf("deliveries = %+v, want a failed attempt then an abandoned one", deliveries)
+	}
+}
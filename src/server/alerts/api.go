@@ -0,0 +1,61 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/api"
+	"strings"
+)
+
+// APIPrefix ...
+const APIPrefix = "/api/alerts"
+
+// APIHandler ...
+//
+// GET    /api/alerts/rules       - list rules, without webhook secrets
+// POST   /api/alerts/rules       - register or replace a rule, body is a Rule
+// DELETE /api/alerts/rules/{id}  - remove a rule
+// GET    /api/alerts/deliveries  - the most recent delivery attempts, newest first
+func (a *Alerter) APIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, APIPrefix), "/")
+		parts := strings.Split(path, "/")
+
+		switch {
+		case path == "rules" && r.Method == http.MethodGet:
+			api.WriteJSON(w, http.StatusOK, a.Rules())
+
+		case path == "rules" && r.Method == http.MethodPost:
+			var rule Rule
+			err := json.NewDecoder(r.Body).Decode(&rule)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid rule: %v", err))
+				return
+			}
+
+			err = a.Add(rule)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			api.WriteJSON(w, http.StatusCreated, rule.Redacted())
+
+		case len(parts) == 2 && parts[0] == "rules" && r.Method == http.MethodDelete:
+			err := a.Remove(parts[1])
+			if err != nil {
+				api.WriteError(w, http.StatusNotFound, err)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+
+		case path == "deliveries" && r.Method == http.MethodGet:
+			api.WriteJSON(w, http.StatusOK, a.Deliveries())
+
+		default:
+			api.WriteError(w, http.StatusNotFound, fmt.Errorf("no route for %s %s", r.Method, r.URL.Path))
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package alerts
+
+import (
+	"regexp"
+	"server/store"
+	"server/transcriber/recognizers"
+	"strings"
+	"time"
+)
+
+// matcher ...
+// A rule compiled for evaluation
+type matcher struct {
+	rule     Rule
+	channels map[string]bool
+	phrases  [][]string // Keywords are single word phrases
+	patterns []*regexp.Regexp
+}
+
+// occurrence ...
+// Matched words of a transcript, by index
+type occurrence struct {
+	first int
+	last  int
+}
+
+func compile(rule Rule) (*matcher, error) {
+	m := &matcher{
+		rule:     rule,
+		channels: make(map[string]bool),
+	}
+
+	for _, channel := range rule.Channels {
+		m.channels[channel] = true
+	}
+
+	for _, text := range append(append([]string{}, rule.Keywords...), rule.Phrases...) {
+		phrase := make([]string, 0)
+		for _, word := range strings.Fields(text) {
+			if term := recognizers.Normalize(word); term != "" {
+				phrase = append(phrase, term)
+			}
+		}
+
+		if len(phrase) > 0 {
+			m.phrases = append(m.phrases, phrase)
+		}
+	}
+
+	for _, pattern := range rule.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		m.patterns = append(m.patterns, re)
+	}
+
+	return m, nil
+}
+
+// match returns every occurrence of the rule's phrases and patterns in the entry
+func (m *matcher) match(entry store.Entry) []occurrence {
+	if len(m.channels) > 0 && !m.channels[entry.Channel] {
+		return nil
+	}
+
+	if entry.Confidence < m.rule.MinConfidence {
+		return nil
+	}
+
+	// Rules see the words as recognized, compliance monitoring must not be blinded by the content filter
+	words := entry.Recognized()
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = recognizers.Normalize(word.Word)
+	}
+
+	result := make([]occurrence, 0)
+	for _, phrase := range m.phrases {
+		for i := range terms {
+			if o, ok := matchPhrase(terms, i, phrase); ok {
+				result = append(result, o)
+			}
+		}
+	}
+
+	if len(m.patterns) == 0 {
+		return result
+	}
+
+	// Patterns run over the plain text, byte offsets are mapped back to words
+	text := ""
+	starts := make([]int, len(words))
+	for i, word := range words {
+		if i > 0 {
+			text += " "
+		}
+
+		starts[i] = len(text)
+		text += word.Word
+	}
+
+	wordAt := func(offset int) int {
+		i := 0
+		for i+1 < len(starts) && starts[i+1] <= offset {
+			i++
+		}
+
+		return i
+	}
+
+	for _, re := range m.patterns {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[1] == loc[0] {
+				continue
+			}
+
+			result = append(result, occurrence{first: wordAt(loc[0]), last: wordAt(loc[1] - 1)})
+		}
+	}
+
+	return result
+}
+
+// matchPhrase matches the phrase from the given word, skipping words that are punctuation only
+func matchPhrase(terms []string, from int, phrase []string) (occurrence, bool) {
+	if terms[from] != phrase[0] {
+		return occurrence{}, false
+	}
+
+	i := from
+	for _, term := range phrase[1:] {
+		i++
+		for i < len(terms) && terms[i] == "" {
+			i++
+		}
+
+		if i >= len(terms) || terms[i] != term {
+			return occurrence{}, false
+		}
+	}
+
+	return occurrence{first: from, last: i}, true
+}
+
+// event describes an occurrence, with a few words of context on either side
+func (m *matcher) event(id string, entry store.Entry, o occurrence) Event {
+	recognized := entry.Recognized()
+
+	text := func(from, to int) string {
+		if from < 0 {
+			from = 0
+		}
+
+		if to > len(recognized) {
+			to = len(recognized)
+		}
+
+		texts := make([]string, 0, to-from)
+		for _, word := range recognized[from:to] {
+			texts = append(texts, word.Word)
+		}
+
+		return strings.Join(texts, " ")
+	}
+
+	start := recognized[o.first].Start.Duration()
+	event := Event{
+		ID:         id,
+		Rule:       m.rule.ID,
+		Channel:    entry.Channel,
+		Segment:    entry.Segment,
+		Text:       text(o.first, o.last+1),
+		Context:    text(o.first-contextWords, o.last+1+contextWords),
+		MediaTime:  entry.MediaStart + start.Seconds(),
+		Confidence: entry.Confidence,
+		DetectedAt: time.Now(),
+	}
+
+	if entry.WallClock != nil {
+		clock := entry.WallClock.Add(start)
+		event.WallClock = &clock
+	}
+
+	return event
+}
+
+// contextWords ...
+// Words of context on either side of a match
+const contextWords = 8
@@ -0,0 +1,103 @@
+package alerts
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Config ...
+type Config struct {
+	Path        string `json:"path"`        // Rules registered through the API and the delivery log are kept here
+	MaxAttempts int    `json:"maxAttempts"` // Delivery attempts per event before giving up
+	Timeout     int    `json:"timeout"`     // Seconds per delivery attempt
+	Rules       []Rule `json:"rules"`       // Read-only rules, always loaded
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Path:        "_alerts",
+		MaxAttempts: 5,
+		Timeout:     10,
+		Rules:       make([]Rule, 0),
+	}
+}
+
+// Webhook ...
+type Webhook struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"` // Signs the payload with HMAC-SHA256 when set
+}
+
+// Rule ...
+// Fires for every occurrence of any of its keywords, phrases or patterns in a new transcript
+type Rule struct {
+	ID            string   `json:"id"`
+	Channels      []string `json:"channels,omitempty"` // All channels when empty
+	Keywords      []string `json:"keywords,omitempty"`
+	Phrases       []string `json:"phrases,omitempty"`  // Consecutive words, regardless of case and punctuation
+	Patterns      []string `json:"patterns,omitempty"` // Regular expressions over the transcript text, words separated by single spaces
+	MinConfidence float32  `json:"minConfidence,omitempty"`
+	Webhook       Webhook  `json:"webhook"`
+	Static        bool     `json:"static"` // Loaded from the configuration file, can't be removed through the API
+}
+
+var ruleID = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Validate ...
+func (r Rule) Validate() error {
+	if !ruleID.MatchString(r.ID) {
+		return fmt.Errorf("rule id %q must only contain letters, digits, '-' and '_'", r.ID)
+	}
+
+	if len(r.Keywords)+len(r.Phrases)+len(r.Patterns) == 0 {
+		return fmt.Errorf("rule %s: no keywords, phrases or patterns", r.ID)
+	}
+
+	if r.Webhook.URL == "" {
+		return fmt.Errorf("rule %s: missing webhook url", r.ID)
+	}
+
+	_, err := compile(r)
+	return err
+}
+
+// Redacted ...
+// The rule without its webhook secret, as listed by the API
+func (r Rule) Redacted() Rule {
+	if r.Webhook.Secret != "" {
+		r.Webhook.Secret = "********"
+	}
+
+	return r
+}
+
+// Event ...
+// The webhook payload
+type Event struct {
+	ID         string     `json:"id"`
+	Rule       string     `json:"rule"`
+	Channel    string     `json:"channel"`
+	Segment    string     `json:"segment"`
+	Text       string     `json:"text"` // The matched words
+	Context    string     `json:"context"`
+	MediaTime  float64    `json:"mediaTime"` // Absolute media time of the first matched word, in seconds
+	WallClock  *time.Time `json:"wallClock,omitempty"`
+	Confidence float32    `json:"confidence"`
+	DetectedAt time.Time  `json:"detectedAt"`
+}
+
+// Delivery ...
+// A single delivery attempt, as recorded in the delivery log
+type Delivery struct {
+	Event     string    `json:"event"`
+	Rule      string    `json:"rule"`
+	URL       string    `json:"url"`
+	Attempt   int       `json:"attempt"`
+	At        time.Time `json:"at"`
+	Status    int       `json:"status,omitempty"` // HTTP status, 0 when no response was received
+	Error     string    `json:"error,omitempty"`
+	Delivered bool      `json:"delivered"`
+	Final     bool      `json:"final"` // No further attempts will be made
+}
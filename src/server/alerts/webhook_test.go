@@ -0,0 +1,87 @@
+package alerts
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"1","rule":"weather"}`)
+	signature := Sign("secret", "1600000000", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"valid", "secret", "1600000000", body, signature, true},
+		{"other secret", "other", "1600000000", body, signature, false},
+		{"replayed with another timestamp", "secret", "1600000001", body, signature, false},
+		{"tampered body", "secret", "1600000000", []byte(`{"id":"2","rule":"weather"}`), signature, false},
+		{"without the scheme", "secret", "1600000000", body, signature[len("sha256="):], false},
+		{"empty", "secret", "1600000000", body, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Verify(test.secret, test.timestamp, test.body, test.signature); got != test.want {
+				t.Errorf("Verify = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPost(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+
+	tests := []struct {
+		name     string
+		secret   string
+		status   int
+		signed   bool
+		accepted bool
+	}{
+		{name: "signed", secret: "secret", status: http.StatusOK, signed: true, accepted: true},
+		{name: "unsigned", status: http.StatusNoContent, accepted: true},
+		{name: "rejected", secret: "secret", status: http.StatusInternalServerError, signed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var verified, signed bool
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ := ioutil.ReadAll(r.Body)
+				signature := r.Header.Get(SignatureHeader)
+
+				signed = signature != ""
+				verified = Verify(test.secret, r.Header.Get(TimestampHeader), received, signature)
+
+				if r.Header.Get(EventHeader) != "1" {
+					t.Errorf("event header = %q, want 1", r.Header.Get(EventHeader))
+				}
+
+				w.WriteHeader(test.status)
+			}))
+			defer receiver.Close()
+
+			status, err := post(context.Background(), receiver.Client(), Webhook{URL: receiver.URL, Secret: test.secret}, "1", body)
+			if status != test.status {
+				t.Errorf("status = %d, want %d", status, test.status)
+			}
+
+			if (err == nil) != test.accepted {
+				t.Errorf("err = %v, want accepted %v", err, test.accepted)
+			}
+
+			if signed != test.signed || (signed && !verified) {
+				t.Errorf("signed = %v, verified = %v, want signed %v", signed, verified, test.signed)
+			}
+		})
+	}
+}
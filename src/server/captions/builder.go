@@ -0,0 +1,93 @@
+package captions
+
+import (
+	"server/transcriber/recognizers"
+	"strings"
+	"time"
+)
+
+// Cue ...
+type Cue struct {
+	Start   time.Duration
+	End     time.Duration
+	Text    string // Plain text, escaped by the writers
+	Speaker int    // 0 when unknown
+	Voice   string // WebVTT voice span the text is wrapped in, e.g. "Speaker 1"
+}
+
+// Options ...
+type Options struct {
+	MaxWords    int           // Words per cue, matching the client's grouping by default
+	MaxDuration time.Duration // Longest a single cue stays on screen
+	MaxGap      time.Duration // A pause longer than this starts a new cue
+}
+
+// DefaultOptions ...
+var DefaultOptions = Options{
+	MaxWords:    10,
+	MaxDuration: 5 * time.Second,
+	MaxGap:      1500 * time.Millisecond,
+}
+
+// Build ...
+// Groups timed words into cues, as nanosecond accuracy would otherwise make words appear one by one.
+// A cue never spans two speakers.
+func Build(words []recognizers.TimedWord, options Options) []Cue {
+	cues := make([]Cue, 0)
+	group := make([]recognizers.TimedWord, 0, options.MaxWords)
+
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+
+		text := make([]string, 0, len(group))
+		for _, word := range group {
+			text = append(text, word.Word)
+		}
+
+		cues = append(cues, Cue{
+			Start:   group[0].Start.Duration(),
+			End:     group[len(group)-1].End.Duration(),
+			Text:    strings.Join(text, " "),
+			Speaker: group[0].Speaker,
+		})
+
+		group = group[:0]
+	}
+
+	for _, word := range words {
+		if len(group) > 0 {
+			first := group[0]
+			last := group[len(group)-1]
+
+			full := options.MaxWords > 0 && len(group) >= options.MaxWords
+			long := options.MaxDuration > 0 && word.End.Duration()-first.Start.Duration() > options.MaxDuration
+			paused := options.MaxGap > 0 && word.Start.Duration()-last.End.Duration() > options.MaxGap
+			turn := word.Speaker != last.Speaker
+
+			if full || long || paused || turn {
+				flush()
+			}
+		}
+
+		group = append(group, word)
+	}
+
+	flush()
+	return cues
+}
+
+// Window ...
+// Returns the cues overlapping [start, end), used for splitting cues into segments
+func Window(cues []Cue, start time.Duration, end time.Duration) []Cue {
+	result := make([]Cue, 0)
+
+	for _, cue := range cues {
+		if cue.End > start && cue.Start < end {
+			result = append(result, cue)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,131 @@
+package captions
+
+import (
+	"reflect"
+	"server/transcriber/recognizers"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+// spoken is a word of the speaker from start to end, in milliseconds
+func spoken(word string, start int, end int, speaker int) recognizers.TimedWord {
+	return recognizers.TimedWord{
+		Start:   recognizers.FromDuration(ms(start)),
+		End:     recognizers.FromDuration(ms(end)),
+		Word:    word,
+		Speaker: speaker,
+	}
+}
+
+// steady is count words of 200ms, every 300ms from start
+func steady(count int, start int) []recognizers.TimedWord {
+	words := make([]recognizers.TimedWord, 0, count)
+	for i := 0; i < count; i++ {
+		words = append(words, spoken(string(rune('a'+i)), start+i*300, start+i*300+200, 0))
+	}
+
+	return words
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		words   []recognizers.TimedWord
+		options Options
+		want    []Cue
+	}{
+		{"no words", nil, DefaultOptions, []Cue{}},
+		{
+			name:    "words per cue",
+			words:   steady(12, 0),
+			options: DefaultOptions,
+			want: []Cue{
+				{Start: 0, End: ms(2900), Text: "a b c d e f g h i j"},
+				{Start: ms(3000), End: ms(3500), Text: "k l"},
+			},
+		},
+		{
+			name:    "duration per cue",
+			words:   steady(12, 0),
+			options: Options{MaxDuration: 2 * time.Second},
+			want: []Cue{
+				{Start: 0, End: ms(2000), Text: "a b c d e f g"}, // Exactly the longest duration
+				{Start: ms(2100), End: ms(3500), Text: "h i j k l"},
+			},
+		},
+		{
+			name: "pauses",
+			words: []recognizers.TimedWord{
+				spoken("Good", 0, 400, 0), spoken("evening.", 500, 900, 0),
+				spoken("Tonight", 2400, 2800, 0), // 1.5s after, not a pause
+				spoken("headlines", 4400, 4900, 0),
+			},
+			options: DefaultOptions,
+			want: []Cue{
+				{Start: 0, End: ms(2800), Text: "Good evening. Tonight"},
+				{Start: ms(4400), End: ms(4900), Text: "headlines"},
+			},
+		},
+		{
+			name: "speaker turns",
+			words: []recognizers.TimedWord{
+				spoken("Welcome", 0, 400, 1), spoken("back.", 500, 800, 1),
+				spoken("Thanks.", 900, 1300, 2),
+				spoken("So", 1400, 1600, 1),
+			},
+			options: Options{},
+			want: []Cue{
+				{Start: 0, End: ms(800), Text: "Welcome back.", Speaker: 1},
+				{Start: ms(900), End: ms(1300), Text: "Thanks.", Speaker: 2},
+				{Start: ms(1400), End: ms(1600), Text: "So", Speaker: 1},
+			},
+		},
+		{
+			name:    "unlimited",
+			words:   steady(12, 1000),
+			options: Options{},
+			want:    []Cue{{Start: ms(1000), End: ms(4500), Text: "a b c d e f g h i j k l"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Build(test.words, test.options); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Build = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: ms(2000), Text: "first"},
+		{Start: ms(3000), End: ms(7000), Text: "across"},
+		{Start: ms(8000), End: ms(9000), Text: "last"},
+	}
+
+	tests := []struct {
+		start, end int
+		want       []string
+	}{
+		{0, 6000, []string{"first", "across"}},
+		{6000, 12000, []string{"across", "last"}},
+		{2000, 3000, []string{}},
+		{9000, 12000, []string{}},
+	}
+
+	for _, test := range tests {
+		got := make([]string, 0)
+		for _, cue := range Window(cues, ms(test.start), ms(test.end)) {
+			got = append(got, cue.Text)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Window(%d, %d) = %v, want %v", test.start, test.end, got, test.want)
+		}
+	}
+}
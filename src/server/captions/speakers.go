@@ -0,0 +1,77 @@
+package captions
+
+import "fmt"
+
+// Ways of labelling speakers in cue text
+const (
+	SpeakersNone  = ""      // Unlabelled
+	SpeakersVoice = "voice" // WebVTT voice spans, "<v Speaker 1>", dash prefixes in SRT which has no equivalent
+	SpeakersDash  = "dash"  // "- " when the speaker changes, the broadcast convention
+)
+
+// Style ...
+// How captions are presented on a channel
+type Style struct {
+	Speakers string `json:"speakers"` // none, voice or dash
+}
+
+// Validate ...
+func (s Style) Validate() error {
+	switch s.Speakers {
+	case SpeakersNone, SpeakersVoice, SpeakersDash:
+		return nil
+	}
+
+	return fmt.Errorf("unknown speaker labels %q", s.Speakers)
+}
+
+// LabelVTT ...
+// Labels the cues' speakers for WebVTT output, cues without a known speaker are left as they are
+func (s Style) LabelVTT(cues []Cue) []Cue {
+	if s.Speakers == SpeakersVoice {
+		result := make([]Cue, 0, len(cues))
+		for _, cue := range cues {
+			if cue.Speaker != 0 {
+				cue.Voice = fmt.Sprintf("Speaker %d", cue.Speaker)
+			}
+
+			result = append(result, cue)
+		}
+
+		return result
+	}
+
+	return s.LabelSRT(cues)
+}
+
+// LabelSRT ...
+// Labels the cues' speakers for SRT output
+func (s Style) LabelSRT(cues []Cue) []Cue {
+	if s.Speakers == SpeakersNone {
+		return cues
+	}
+
+	return label(cues, func(cue Cue, changed bool) string {
+		if changed {
+			return "- " + cue.Text
+		}
+
+		return cue.Text
+	})
+}
+
+func label(cues []Cue, text func(cue Cue, changed bool) string) []Cue {
+	result := make([]Cue, 0, len(cues))
+	previous := 0
+
+	for i, cue := range cues {
+		if cue.Speaker != 0 {
+			cue.Text = text(cue, i > 0 && cue.Speaker != previous)
+			previous = cue.Speaker
+		}
+
+		result = append(result, cue)
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package captions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabel(t *testing.T) {
+	cues := []Cue{
+		{Text: "Welcome back.", Speaker: 1},
+		{Text: "Joining us tonight", Speaker: 1},
+		{Text: "Thanks.", Speaker: 2},
+		{Text: "[music]"},
+		{Text: "So,", Speaker: 1},
+	}
+
+	tests := []struct {
+		speakers string
+		vtt      []Cue
+		srt      []string
+	}{
+		{
+			speakers: SpeakersNone,
+			vtt:      cues,
+			srt:      []string{"Welcome back.", "Joining us tonight", "Thanks.", "[music]", "So,"},
+		},
+		{
+			speakers: SpeakersVoice,
+			vtt: []Cue{
+				{Text: "Welcome back.", Speaker: 1, Voice: "Speaker 1"},
+				{Text: "Joining us tonight", Speaker: 1, Voice: "Speaker 1"},
+				{Text: "Thanks.", Speaker: 2, Voice: "Speaker 2"},
+				{Text: "[music]"},
+				{Text: "So,", Speaker: 1, Voice: "Speaker 1"},
+			},
+			srt: []string{"Welcome back.", "Joining us tonight", "- Thanks.", "[music]", "- So,"},
+		},
+		{
+			speakers: SpeakersDash,
+			vtt: []Cue{
+				{Text: "Welcome back.", Speaker: 1},
+				{Text: "Joining us tonight", Speaker: 1},
+				{Text: "- Thanks.", Speaker: 2},
+				{Text: "[music]"},
+				{Text: "- So,", Speaker: 1},
+			},
+			srt: []string{"Welcome back.", "Joining us tonight", "- Thanks.", "[music]", "- So,"},
+		},
+	}
+
+	for _, test := range tests {
+		style := Style{Speakers: test.speakers}
+		if err := style.Validate(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := style.LabelVTT(cues); !reflect.DeepEqual(got, test.vtt) {
+			t.Errorf("%q: LabelVTT = %+v, want %+v", test.speakers, got, test.vtt)
+		}
+
+		srt := make([]string, 0)
+		for _, cue := range style.LabelSRT(cues) {
+			srt = append(srt, cue.Text)
+		}
+
+		if !reflect.DeepEqual(srt, test.srt) {
+			t.Errorf("%q: LabelSRT = %q, want %q", test.speakers, srt, test.srt)
+		}
+	}
+
+	// Labelling returns new cues
+	if cues[2].Text != "Thanks." || cues[2].Voice != "" {
+		t.Errorf("labelling changed the cues: %+v", cues[2])
+	}
+
+	if err := (Style{Speakers: "names"}).Validate(); err == nil {
+		t.Error("unknown speaker labels are valid")
+	}
+}
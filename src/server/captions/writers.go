@@ -0,0 +1,110 @@
+package captions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// vttEscaper keeps cue text from being read as markup or as a cue timing line
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// srtEscaper keeps cue text from being read as a cue timing line, SRT has no escapes
+var srtEscaper = strings.NewReplacer("-->", "->")
+
+// WriteVTT ...
+func WriteVTT(w io.Writer, cues []Cue) error {
+	return WriteVTTSegment(w, cues, "")
+}
+
+// WriteVTTSegment ...
+// Writes a WebVTT document, timestampMap is the X-TIMESTAMP-MAP value required for HLS subtitle segments
+func WriteVTTSegment(w io.Writer, cues []Cue, timestampMap string) error {
+	var buf bytes.Buffer
+
+	buf.WriteString("WEBVTT\n")
+	if timestampMap != "" {
+		buf.WriteString("X-TIMESTAMP-MAP=" + timestampMap + "\n")
+	}
+	buf.WriteString("\n")
+
+	for _, cue := range cues {
+		text := vttEscaper.Replace(cue.Text)
+		if cue.Voice != "" {
+			text = fmt.Sprintf("<v %s>%s", vttEscaper.Replace(cue.Voice), text)
+		}
+
+		fmt.Fprintf(&buf, "%s --> %s\n%s\n\n", timestamp(cue.Start, "."), timestamp(cue.End, "."), text)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteSRT ...
+func WriteSRT(w io.Writer, cues []Cue) error {
+	var buf bytes.Buffer
+
+	for i, cue := range cues {
+		fmt.Fprintf(&buf, "%d\n%s --> %s\n%s\n\n", i+1, timestamp(cue.Start, ","), timestamp(cue.End, ","), srtEscaper.Replace(cue.Text))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Segment ...
+type Segment struct {
+	URI           string
+	Duration      time.Duration
+	Discontinuity bool // Follows an encoder restart, matching the media playlist
+}
+
+// WritePlaylist ...
+// Writes a subtitle media playlist, ended playlists are marked as VOD.
+// discontinuitySequence counts the discontinuities that have left a live playlist's window.
+func WritePlaylist(w io.Writer, segments []Segment, mediaSequence int, discontinuitySequence int, ended bool) error {
+	var buf bytes.Buffer
+
+	target := 0.0
+	for _, segment := range segments {
+		target = math.Max(target, math.Ceil(segment.Duration.Seconds()))
+	}
+
+	buf.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&buf, "#EXT-X-TARGETDURATION:%d\n", int(target))
+	fmt.Fprintf(&buf, "#EXT-X-MEDIA-SEQUENCE:%d\n", mediaSequence)
+	if discontinuitySequence > 0 {
+		fmt.Fprintf(&buf, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", discontinuitySequence)
+	}
+	if ended {
+		buf.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	}
+
+	for _, segment := range segments {
+		if segment.Discontinuity {
+			buf.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+
+		fmt.Fprintf(&buf, "#EXTINF:%.3f,\n%s\n", segment.Duration.Seconds(), segment.URI)
+	}
+
+	if ended {
+		buf.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func timestamp(d time.Duration, separator string) string {
+	if d < 0 {
+		d = 0
+	}
+
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, separator, ms%1000)
+}
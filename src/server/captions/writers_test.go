@@ -0,0 +1,125 @@
+package captions
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var written = []Cue{
+	{Start: ms(1500), End: ms(3250), Text: "Tom & Jerry <live>", Voice: "Speaker 1"},
+	{Start: time.Hour + 2*time.Minute + ms(3004), End: time.Hour + 2*time.Minute + ms(5000), Text: "a --> b"},
+}
+
+func TestWriteVTT(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteVTTSegment(&buf, written, "MPEGTS:900000,LOCAL:00:00:00.000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `WEBVTT
+X-TIMESTAMP-MAP=MPEGTS:900000,LOCAL:00:00:00.000
+
+00:00:01.500 --> 00:00:03.250
+<v Speaker 1>Tom &amp; Jerry &lt;live&gt;
+
+01:02:03.004 --> 01:02:05.000
+a --&gt; b
+
+`
+	if buf.String() != want {
+		t.Errorf("WriteVTTSegment =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	WriteVTT(&buf, nil)
+	if buf.String() != "WEBVTT\n\n" {
+		t.Errorf("WriteVTT without cues = %q", buf.String())
+	}
+}
+
+func TestWriteSRT(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSRT(&buf, written)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SRT has no voice spans nor markup escapes
+	want := `1
+00:00:01,500 --> 00:00:03,250
+Tom & Jerry <live>
+
+2
+01:02:03,004 --> 01:02:05,000
+a -> b
+
+`
+	if buf.String() != want {
+		t.Errorf("WriteSRT =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWritePlaylist(t *testing.T) {
+	segments := []Segment{
+		{URI: "12.vtt", Duration: ms(6000)},
+		{URI: "13.vtt", Duration: ms(6006)},
+		{URI: "14.vtt", Duration: ms(2000), Discontinuity: true},
+	}
+
+	tests := []struct {
+		name                  string
+		discontinuitySequence int
+		ended                 bool
+		want                  string
+	}{
+		{
+			name: "live",
+			want: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:7
+#EXT-X-MEDIA-SEQUENCE:12
+#EXTINF:6.000,
+12.vtt
+#EXTINF:6.006,
+13.vtt
+#EXT-X-DISCONTINUITY
+#EXTINF:2.000,
+14.vtt
+`,
+		},
+		{
+			name:                  "ended",
+			discontinuitySequence: 2,
+			ended:                 true,
+			want: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:7
+#EXT-X-MEDIA-SEQUENCE:12
+#EXT-X-DISCONTINUITY-SEQUENCE:2
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXTINF:6.000,
+12.vtt
+#EXTINF:6.006,
+13.vtt
+#EXT-X-DISCONTINUITY
+#EXTINF:2.000,
+14.vtt
+#EXT-X-ENDLIST
+`,
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := WritePlaylist(&buf, segments, 12, test.discontinuitySequence, test.ended)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if buf.String() != test.want {
+			t.Errorf("%s: WritePlaylist =\n%s\nwant\n%s", test.name, buf.String(), test.want)
+		}
+	}
+}
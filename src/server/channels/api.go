@@ -0,0 +1,104 @@
+package channels
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/api"
+	"strings"
+)
+
+// APIPrefix ...
+const APIPrefix = "/api/channels"
+
+// APIHandler ...
+//
+// GET    /api/channels                   - list channels
+// POST   /api/channels                   - add a channel, body is a channel Config
+// GET    /api/channels/{name}            - channel status
+// DELETE /api/channels/{name}            - remove a channel
+// GET    /api/channels/{name}/encoder    - encoder process status
+// GET    /api/channels/{name}/transcript - the channel's transcript history
+// POST   /api/channels/{name}/restart    - restart a channel
+//
+// The transcript can be limited to a time range, with ?from=&to= in media seconds or ?since=&until= as
+// RFC 3339 wall-clock times, and rendered as captions with ?format=vtt or ?format=srt instead of JSON.
+func (m *Manager) APIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, APIPrefix), "/")
+		parts := strings.Split(path, "/")
+
+		switch {
+		case path == "" && r.Method == http.MethodGet:
+			api.WriteJSON(w, http.StatusOK, m.List())
+
+		case path == "" && r.Method == http.MethodPost:
+			var config Config
+			err := json.NewDecoder(r.Body).Decode(&config)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid channel config: %v", err))
+				return
+			}
+
+			err = m.Add(config)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			writeStatus(w, http.StatusCreated, m.Get(config.Name))
+
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			channel := m.Get(parts[0])
+			if channel == nil {
+				api.WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s does not exist", parts[0]))
+				return
+			}
+
+			api.WriteJSON(w, http.StatusOK, channel.Status())
+
+		case len(parts) == 1 && r.Method == http.MethodDelete:
+			err := m.Remove(parts[0])
+			if err != nil {
+				api.WriteError(w, http.StatusNotFound, err)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+
+		case len(parts) == 2 && parts[1] == "encoder" && r.Method == http.MethodGet:
+			channel := m.Get(parts[0])
+			if channel == nil {
+				api.WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s does not exist", parts[0]))
+				return
+			}
+
+			api.WriteJSON(w, http.StatusOK, channel.Status().Encoder)
+
+		case len(parts) == 2 && parts[1] == "transcript" && r.Method == http.MethodGet:
+			m.serveTranscript(w, r, parts[0])
+
+		case len(parts) == 2 && parts[1] == "restart" && r.Method == http.MethodPost:
+			err := m.Restart(parts[0])
+			if err != nil {
+				api.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			writeStatus(w, http.StatusOK, m.Get(parts[0]))
+
+		default:
+			api.WriteError(w, http.StatusNotFound, fmt.Errorf("no route for %s %s", r.Method, r.URL.Path))
+		}
+	})
+}
+
+// writeStatus guards against the channel having been removed in the meantime
+func writeStatus(w http.ResponseWriter, status int, channel *Channel) {
+	if channel == nil {
+		api.WriteError(w, http.StatusConflict, fmt.Errorf("channel was removed"))
+		return
+	}
+
+	api.WriteJSON(w, status, channel.Status())
+}
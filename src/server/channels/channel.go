@@ -0,0 +1,251 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"server/costs"
+	"server/encoder"
+	"server/encoder/strategies"
+	"server/logging"
+	"server/store"
+	"server/subtitles"
+	"server/transcriber"
+	"server/transcriber/recognizers/cache"
+	"server/transcriber/recognizers/failover"
+	"server/transcriber/translators"
+	"sync"
+)
+
+// Channel ...
+// A single input with its own encoder process, transcriber pipeline and output directory
+type Channel struct {
+	config      Config
+	encoderPath string
+	outputPath  string
+	store       *store.Store
+	meter       *costs.Meter
+	cache       *cache.Cache
+	files       http.Handler
+	log         *logging.Logger
+
+	mu          sync.Mutex
+	running     bool
+	closed      bool
+	encoder     *encoder.Encoder
+	transcriber *transcriber.Transcriber
+	subtitles   *subtitles.Publisher
+}
+
+func newChannel(config Config, encoderPath string, outputPath string, store *store.Store, meter *costs.Meter, cache *cache.Cache) *Channel {
+	return &Channel{
+		config:      config,
+		encoderPath: encoderPath,
+		outputPath:  outputPath,
+		store:       store,
+		meter:       meter,
+		cache:       cache,
+		files:       http.StripPrefix(config.Prefix(), http.FileServer(http.Dir(outputPath))),
+		log:         logging.New("channels", "channel", config.Name),
+	}
+}
+
+// Start ...
+func (c *Channel) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return fmt.Errorf("channel %s was removed", c.config.Name)
+	}
+
+	if c.running {
+		return nil
+	}
+
+	err := os.RemoveAll(c.outputPath)
+	if err != nil {
+		c.log.Warn("Could not remove pre-existing output directory", "path", c.outputPath, "err", err)
+	}
+
+	err = os.MkdirAll(c.outputPath, 0777)
+	if err != nil {
+		return err
+	}
+
+	encoderConfig := c.config.Encoder
+	encoderConfig.ListSize = c.config.DVR.segments()
+
+	enc, err := encoder.New(c.config.Name, c.encoderPath, c.outputPath, encoderConfig)
+	if err != nil {
+		return err
+	}
+
+	var translator translators.Adapter
+	if c.config.Translation.Enabled() {
+		translator, err = transcriber.NewTranslator(c.config.Translation)
+		if err != nil {
+			return err
+		}
+	}
+
+	subs, err := subtitles.New(subtitles.Config{
+		Channel:     c.config.Name,
+		OutputPath:  c.outputPath,
+		Window:      encoderConfig.ListSize,
+		Languages:   c.config.Recognizer.Languages(),
+		Translation: c.config.Translation,
+		Translator:  translator,
+		Captions:    c.config.Captions,
+	})
+	if err != nil {
+		return err
+	}
+
+	t, err := transcriber.New(transcriber.Config{
+		EncoderPath:  c.encoderPath,
+		OutputPath:   fmt.Sprintf("%s/%s", c.outputPath, "text"), // Transcriber will output to /<channel>/text
+		SegmentsPath: fmt.Sprintf("%s/%s", c.outputPath, "0"),    // Transcriber will reference media segments that will exist in /<channel>/0
+		Recognizer:   c.config.Recognizer,
+		VAD:          c.config.VAD,
+		Filter:       c.config.Filter,
+		Subtitles:    subs,
+		Channel:      c.config.Name,
+		Store:        c.store,
+		Meter:        c.meter,
+		Budget:       c.config.Budget,
+		Cache:        c.cache,
+		Latency:      c.config.Latency,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = t.Start()
+	if err != nil {
+		return err
+	}
+
+	c.encoder = enc
+	c.transcriber = t
+	c.subtitles = subs
+	c.running = true
+
+	go enc.Run()
+
+	return nil
+}
+
+// Shutdown ...
+// Lets ffmpeg finalize its playlists, then waits for in-flight transcriptions, both bounded by the context.
+// Playlists are only marked as ended when they'll outlive the process.
+func (c *Channel) Shutdown(ctx context.Context, keepWorkspace bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shutdownLocked(ctx, keepWorkspace)
+}
+
+// Close ...
+// Shuts the channel down for good once it's removed, a concurrent restart can't start it again
+func (c *Channel) Close(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.shutdownLocked(ctx, false)
+}
+
+func (c *Channel) shutdownLocked(ctx context.Context, keepWorkspace bool) {
+	if !c.running {
+		return
+	}
+
+	c.encoder.Shutdown(ctx, keepWorkspace)
+	c.transcriber.Shutdown(ctx)
+	c.running = false
+
+	if keepWorkspace {
+		err := c.subtitles.End()
+		if err != nil {
+			c.log.Error("Could not end the subtitle playlists", "err", err)
+		}
+	}
+}
+
+// Status ...
+func (c *Channel) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Status{
+		Name:          c.config.Name,
+		PathPrefix:    c.config.Prefix(),
+		OutputPath:    c.outputPath,
+		Running:       c.running,
+		Source:        c.config.Encoder.Input.URI,
+		DVRWindow:     c.config.DVR.segments() * strategies.SegmentDuration,
+		Encoder:       c.encoderStatus(),
+		Subtitles:     c.subtitleRenditions(),
+		Recognizers:   c.recognizers(),
+		Transcription: c.progress(),
+	}
+}
+
+// progress must be called with the lock held
+func (c *Channel) progress() *transcriber.Progress {
+	if !c.running {
+		return nil
+	}
+
+	progress := c.transcriber.Progress()
+	return &progress
+}
+
+// recognizers must be called with the lock held
+func (c *Channel) recognizers() []failover.Status {
+	if c.transcriber == nil {
+		return nil
+	}
+
+	return c.transcriber.Recognizers()
+}
+
+func (c *Channel) renditions() []subtitles.Rendition {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.subtitleRenditions()
+}
+
+// subtitleRenditions must be called with the lock held
+func (c *Channel) subtitleRenditions() []subtitles.Rendition {
+	if c.subtitles == nil {
+		return []subtitles.Rendition{}
+	}
+
+	return c.subtitles.Renditions()
+}
+
+func (c *Channel) encoderStatus() encoder.Status {
+	if c.encoder == nil {
+		return encoder.Status{State: encoder.StateStopped}
+	}
+
+	return c.encoder.Status()
+}
+
+// ServeHTTP ...
+// Serves the channel's playlists, media segments and transcripts
+func (c *Channel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if c.rewritesPlaylist(r) {
+		c.servePlaylist(w, r)
+		return
+	}
+
+	c.files.ServeHTTP(w, r)
+}
@@ -0,0 +1,32 @@
+package channels
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartAfterClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "channels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "news")
+	channel := newChannel(Config{Name: "news"}, "ffmpeg", output, nil, nil, nil)
+
+	// A restart racing with the removal of the channel
+	channel.Close(context.Background())
+
+	err = channel.Start()
+	if err == nil {
+		t.Fatal("a removed channel was started again")
+	}
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("the removed channel's output directory was created again")
+	}
+}
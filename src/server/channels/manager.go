@@ -0,0 +1,204 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"server/costs"
+	"server/logging"
+	"server/store"
+	"server/transcriber/recognizers/cache"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Manager ...
+// Runs any number of channels concurrently in a single process
+type Manager struct {
+	encoderPath string
+	workDir     string
+	store       *store.Store
+	meter       *costs.Meter
+	cache       *cache.Cache
+	stopTimeout time.Duration // How long a removed or restarted channel's encoder and transcriptions get to finish
+
+	mu       sync.RWMutex
+	channels map[string]*Channel
+}
+
+// NewManager ...
+func NewManager(encoderPath string, workDir string, store *store.Store, meter *costs.Meter, cache *cache.Cache,
+	stopTimeout time.Duration) *Manager {
+	return &Manager{
+		encoderPath: encoderPath,
+		workDir:     workDir,
+		store:       store,
+		meter:       meter,
+		cache:       cache,
+		stopTimeout: stopTimeout,
+		channels:    make(map[string]*Channel),
+	}
+}
+
+// Add ...
+// Registers and starts a new channel
+func (m *Manager) Add(config Config) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+
+	if _, exists := m.channels[config.Name]; exists {
+		m.mu.Unlock()
+		return fmt.Errorf("channel %s already exists", config.Name)
+	}
+
+	for _, other := range m.channels {
+		if strings.HasPrefix(other.config.Prefix(), config.Prefix()) || strings.HasPrefix(config.Prefix(), other.config.Prefix()) {
+			m.mu.Unlock()
+			return fmt.Errorf("channel %s path prefix %s conflicts with channel %s", config.Name, config.Prefix(), other.config.Name)
+		}
+	}
+
+	channel := newChannel(config, m.encoderPath, filepath.Join(m.workDir, config.Name), m.store, m.meter, m.cache)
+	m.channels[config.Name] = channel
+	m.mu.Unlock()
+
+	err = channel.Start()
+	if err != nil {
+		m.mu.Lock()
+		if m.channels[config.Name] == channel {
+			delete(m.channels, config.Name)
+		}
+		m.mu.Unlock()
+		return fmt.Errorf("could not start channel %s: %v", config.Name, err)
+	}
+
+	channel.log.Info("Channel started", "prefix", config.Prefix())
+	return nil
+}
+
+// Remove ...
+// Shuts the channel down and removes its output directory once nothing is writing to it anymore
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	channel, exists := m.channels[name]
+	delete(m.channels, name)
+	m.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("channel %s does not exist", name)
+	}
+
+	ctx, cancel := m.stopContext()
+	channel.Close(ctx)
+	cancel()
+
+	err := os.RemoveAll(channel.outputPath)
+	if err != nil {
+		channel.log.Error("Could not remove output directory", "path", channel.outputPath, "err", err)
+	}
+
+	channel.log.Info("Channel removed")
+	return nil
+}
+
+// Restart ...
+func (m *Manager) Restart(name string) error {
+	channel := m.Get(name)
+	if channel == nil {
+		return fmt.Errorf("channel %s does not exist", name)
+	}
+
+	// Starting again clears the output directory, in-flight transcriptions must be done writing to it
+	ctx, cancel := m.stopContext()
+	channel.Shutdown(ctx, false)
+	cancel()
+
+	// Fails once the channel is removed meanwhile, its output is being deleted
+	return channel.Start()
+}
+
+// stopContext bounds the wait for a channel's encoder and in-flight transcriptions to finish
+func (m *Manager) stopContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), m.stopTimeout)
+}
+
+// Get ...
+func (m *Manager) Get(name string) *Channel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.channels[name]
+}
+
+// List ...
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]Status, 0, len(m.channels))
+	for _, channel := range m.channels {
+		result = append(result, channel.Status())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// Shutdown ...
+// Gracefully shuts down every channel in parallel, then removes the working directory unless it should be kept
+func (m *Manager) Shutdown(ctx context.Context, keepWorkspace bool) {
+	m.mu.RLock()
+	var wg sync.WaitGroup
+	for _, channel := range m.channels {
+		wg.Add(1)
+		go func(channel *Channel) {
+			defer wg.Done()
+			channel.Shutdown(ctx, keepWorkspace)
+			channel.log.Info("Channel shut down")
+		}(channel)
+	}
+	m.mu.RUnlock()
+
+	wg.Wait()
+
+	if keepWorkspace {
+		return
+	}
+
+	err := os.RemoveAll(m.workDir)
+	if err != nil {
+		logging.New("channels").Error("Could not remove working directory", "path", m.workDir, "err", err)
+	}
+}
+
+// ServeHTTP ...
+// Routes requests to the channel whose path prefix matches
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	var match *Channel
+	for _, channel := range m.channels {
+		if strings.HasPrefix(r.URL.Path, channel.config.Prefix()) {
+			match = channel
+			break
+		}
+	}
+	m.mu.RUnlock()
+
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	match.ServeHTTP(w, r)
+}
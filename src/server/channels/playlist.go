@@ -0,0 +1,152 @@
+package channels
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"server/hls"
+	"server/subtitles"
+	"strings"
+	"time"
+)
+
+// startParam ...
+// Requests a playlist starting at the given RFC 3339 wall-clock time, e.g. a search result
+const startParam = "t"
+
+// masterPlaylist ...
+// Written by ffmpeg, the subtitle renditions are added when it's served
+const masterPlaylist = "master.m3u8"
+
+// rewritesPlaylist ...
+func (c *Channel) rewritesPlaylist(r *http.Request) bool {
+	if !strings.HasSuffix(r.URL.Path, ".m3u8") {
+		return false
+	}
+
+	return r.URL.Query().Get(startParam) != "" || r.URL.Path == c.config.Prefix()+masterPlaylist
+}
+
+// servePlaylist serves a playlist rewritten on the fly: the master playlist lists the subtitle renditions,
+// and a requested start time is passed on to the variants, which get an EXT-X-START offset from their first dated segment
+func (c *Channel) servePlaylist(w http.ResponseWriter, r *http.Request) {
+	var at time.Time
+	if value := r.URL.Query().Get(startParam); value != "" {
+		var err error
+		at, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %v", startParam, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rel := strings.TrimPrefix(r.URL.Path, c.config.Prefix())
+	raw, err := ioutil.ReadFile(filepath.Join(c.outputPath, filepath.FromSlash(rel)))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if bytes.Contains(raw, []byte("#EXT-X-STREAM-INF")) {
+		raw = subtitles.AddRenditions(raw, c.renditions())
+
+		if !at.IsZero() {
+			raw = startVariantsAt(raw, at)
+		}
+	} else if !at.IsZero() {
+		raw, err = startMediaAt(raw, at)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Write(raw)
+}
+
+// liveWindow is the wall-clock range of the segments in the channel's media playlist, false until one is dated
+func (c *Channel) liveWindow() (time.Time, time.Time, bool) {
+	playlist, err := hls.ReadMediaPlaylist(filepath.Join(c.outputPath, "0", "playlist.m3u8"))
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return window(playlist)
+}
+
+// window is the wall-clock range of the playlist's segments, false when none is dated. ffmpeg only dates some of
+// the segments, the window is placed through the first dated one.
+func window(playlist hls.MediaPlaylist) (time.Time, time.Time, bool) {
+	elapsed := 0.0
+	for i, segment := range playlist.Segments {
+		if segment.ProgramDateTime == nil {
+			elapsed += segment.Duration
+			continue
+		}
+
+		start := segment.ProgramDateTime.Add(-seconds(elapsed))
+		end := *segment.ProgramDateTime
+		for _, rest := range playlist.Segments[i:] {
+			end = end.Add(seconds(rest.Duration))
+		}
+
+		return start, end, true
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// startVariantsAt passes the start time on to the variant playlists
+func startVariantsAt(master []byte, at time.Time) []byte {
+	query := url.Values{startParam: []string{at.Format(time.RFC3339Nano)}}.Encode()
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(master))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" && !strings.HasPrefix(line, "#") {
+			line = line + "?" + query
+		}
+
+		fmt.Fprintln(&out, line)
+	}
+
+	return out.Bytes()
+}
+
+// startMediaAt adds an EXT-X-START offset to the media playlist pointing at the wall-clock time
+func startMediaAt(raw []byte, at time.Time) ([]byte, error) {
+	playlist, err := hls.ParseMediaPlaylist(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	offset := 0.0
+	if start, _, ok := window(playlist); ok {
+		offset = at.Sub(start).Seconds()
+	}
+
+	// Earlier moments have left the DVR window already, start as early as possible
+	if offset < 0 {
+		offset = 0
+	}
+
+	var out bytes.Buffer
+	lines := strings.SplitN(string(raw), "\n", 2)
+	fmt.Fprintln(&out, lines[0])
+	fmt.Fprintf(&out, "#EXT-X-START:TIME-OFFSET=%.3f,PRECISE=YES\n", offset)
+	if len(lines) > 1 {
+		out.WriteString(lines[1])
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const undatedFirst = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:10
+#EXTINF:4.000000,
+segment10.ts
+#EXTINF:4.000000,
+segment11.ts
+#EXT-X-PROGRAM-DATE-TIME:2021-03-01T10:00:08.000Z
+#EXTINF:4.000000,
+segment12.ts
+#EXTINF:4.000000,
+segment13.ts
+`
+
+const dated = `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-PROGRAM-DATE-TIME:2021-03-01T10:00:00.000Z
+#EXTINF:4.000000,
+segment10.ts
+#EXTINF:4.000000,
+segment11.ts
+`
+
+const undated = `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXTINF:4.000000,
+segment10.ts
+`
+
+func TestStartMediaAt(t *testing.T) {
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		playlist string
+		at       time.Time
+		offset   string
+	}{
+		{"dated first segment", dated, start.Add(5 * time.Second), "5.000"},
+		{"undated first segment", undatedFirst, start.Add(13500 * time.Millisecond), "13.500"},
+		{"before the window", undatedFirst, start.Add(-time.Minute), "0.000"},
+		{"no dated segment", undated, start.Add(5 * time.Second), "0.000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := startMediaAt([]byte(test.playlist), test.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(string(raw), "\n")
+			want := "#EXT-X-START:TIME-OFFSET=" + test.offset + ",PRECISE=YES"
+			if lines[0] != "#EXTM3U" || lines[1] != want {
+				t.Errorf("playlist starts with %q, want %q", lines[:2], want)
+			}
+
+			if !strings.HasSuffix(string(raw), strings.SplitN(test.playlist, "\n", 2)[1]) {
+				t.Errorf("the rest of the playlist changed:\n%s", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package channels
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"server/api"
+	"server/store"
+	"strconv"
+	"time"
+)
+
+// SearchPath ...
+const SearchPath = "/api/search"
+
+// SearchResult ...
+type SearchResult struct {
+	store.Match
+	Link string `json:"link,omitempty"` // Master playlist starting at the match, while it's within the channel's DVR window
+}
+
+// SearchHandler ...
+//
+// GET /api/search?q=&channel=&since=&until=&limit=&context=
+//
+// q is a word or phrase, channel may be repeated and defaults to every channel with a history,
+// since and until are RFC 3339 wall-clock times
+func (m *Manager) SearchHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			api.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("no route for %s %s", r.Method, r.URL.Path))
+			return
+		}
+
+		query, err := parseSearchQuery(r.URL.Query())
+		if err != nil {
+			api.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		matches, err := m.store.Search(query)
+		if err != nil {
+			api.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		results := make([]SearchResult, 0, len(matches))
+		for _, match := range matches {
+			results = append(results, SearchResult{
+				Match: match,
+				Link:  m.link(match),
+			})
+		}
+
+		api.WriteJSON(w, http.StatusOK, results)
+	})
+}
+
+func parseSearchQuery(values url.Values) (store.SearchQuery, error) {
+	query := store.SearchQuery{
+		Phrase:   values.Get("q"),
+		Channels: values["channel"],
+	}
+
+	if query.Phrase == "" {
+		return query, fmt.Errorf("missing q")
+	}
+
+	// Channel names are history file names, anything else could point outside the history directory
+	for _, channel := range query.Channels {
+		if !validName.MatchString(channel) {
+			return query, fmt.Errorf("invalid channel %q", channel)
+		}
+	}
+
+	var err error
+	if value := values.Get("since"); value != "" {
+		query.Since, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid since: %v", err)
+		}
+	}
+
+	if value := values.Get("until"); value != "" {
+		query.Until, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid until: %v", err)
+		}
+	}
+
+	if value := values.Get("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid limit: %v", err)
+		}
+	}
+
+	if value := values.Get("context"); value != "" {
+		query.Context, err = strconv.Atoi(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid context: %v", err)
+		}
+	}
+
+	return query, nil
+}
+
+// link points at the channel's master playlist, starting playback at the match's wall-clock time,
+// empty once the match has left the live window
+func (m *Manager) link(match store.Match) string {
+	if match.WallClock == nil {
+		return ""
+	}
+
+	channel := m.Get(match.Channel)
+	if channel == nil {
+		return ""
+	}
+
+	start, end, ok := channel.liveWindow()
+	if !ok || match.WallClock.Before(start) || !match.WallClock.Before(end) {
+		return ""
+	}
+
+	return fmt.Sprintf("%smaster.m3u8?%s=%s", channel.config.Prefix(), startParam, url.QueryEscape(match.WallClock.Format(time.RFC3339Nano)))
+}
@@ -0,0 +1,174 @@
+package channels
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"server/api"
+	"server/captions"
+	"server/logging"
+	"server/store"
+	"server/transcriber/recognizers"
+	"strconv"
+	"time"
+)
+
+// transcriptQuery ...
+// A time range of a channel's transcript history, either on the media timeline or by wall clock
+type transcriptQuery struct {
+	From   float64 // Media time, in seconds
+	To     float64
+	Since  time.Time // Wall clock, used instead of the media range when set
+	Until  time.Time
+	Format string
+
+	Unfiltered bool // Include the words as recognized, before content filtering, for compliance review
+}
+
+func parseTranscriptQuery(values url.Values) (transcriptQuery, error) {
+	query := transcriptQuery{
+		From:       0,
+		To:         math.Inf(1),
+		Format:     values.Get("format"),
+		Unfiltered: values.Get("unfiltered") == "true",
+	}
+
+	var err error
+	if value := values.Get("from"); value != "" {
+		query.From, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid from: %v", err)
+		}
+	}
+
+	if value := values.Get("to"); value != "" {
+		query.To, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid to: %v", err)
+		}
+	}
+
+	if value := values.Get("since"); value != "" {
+		query.Since, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid since: %v", err)
+		}
+	}
+
+	if value := values.Get("until"); value != "" {
+		query.Until, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid until: %v", err)
+		}
+	}
+
+	if query.wallClock() && query.Until.IsZero() {
+		query.Until = time.Now()
+	}
+
+	switch query.Format {
+	case "":
+		query.Format = "json"
+	case "json", "vtt", "srt":
+	default:
+		return query, fmt.Errorf("unsupported format %s", query.Format)
+	}
+
+	if query.wallClock() && !query.Since.Before(query.Until) || !query.wallClock() && query.From >= query.To {
+		return query, fmt.Errorf("empty time range")
+	}
+
+	return query, nil
+}
+
+func (q transcriptQuery) wallClock() bool {
+	return !q.Since.IsZero() || !q.Until.IsZero()
+}
+
+// serveTranscript writes the channel's transcript for the requested time range as JSON entries,
+// or as WebVTT / SRT captions timed relative to the start of the range
+func (m *Manager) serveTranscript(w http.ResponseWriter, r *http.Request, channel string) {
+	if !validName.MatchString(channel) {
+		api.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid channel %q", channel))
+		return
+	}
+
+	query, err := parseTranscriptQuery(r.URL.Query())
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var entries []store.Entry
+	if query.wallClock() {
+		entries, err = m.store.RangeWallClock(channel, query.Since, query.Until)
+	} else {
+		entries, err = m.store.Range(channel, query.From, query.To)
+	}
+
+	if err == store.ErrNoHistory {
+		api.WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s has no transcript history", channel))
+		return
+	}
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if query.Format == "json" {
+		if !query.Unfiltered {
+			for i := range entries {
+				entries[i].Unfiltered = nil
+			}
+		}
+
+		api.WriteJSON(w, http.StatusOK, entries)
+		return
+	}
+
+	origin, end := query.From, query.To
+	if query.wallClock() && len(entries) > 0 {
+		// Placing the wall-clock range on the media timeline through the first entry
+		first := entries[0]
+		since := query.Since
+		if since.IsZero() {
+			since = *first.WallClock
+		}
+
+		origin = first.MediaStart - first.WallClock.Sub(since).Seconds()
+		end = origin + query.Until.Sub(since).Seconds()
+	}
+
+	words := make([]recognizers.TimedWord, 0)
+	for _, entry := range entries {
+		for _, word := range entry.AbsoluteWords() {
+			start := word.Start.Duration().Seconds()
+			if start < origin || start >= end {
+				continue
+			}
+
+			words = append(words, word.Offset(-time.Duration(origin*float64(time.Second))))
+		}
+	}
+
+	cues := captions.Build(words, captions.DefaultOptions)
+
+	var style captions.Style
+	if c := m.Get(channel); c != nil {
+		style = c.config.Captions
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if query.Format == "vtt" {
+		w.Header().Set("Content-Type", "text/vtt")
+		err = captions.WriteVTT(w, style.LabelVTT(cues))
+	} else {
+		w.Header().Set("Content-Type", "application/x-subrip")
+		err = captions.WriteSRT(w, style.LabelSRT(cues))
+	}
+
+	if err != nil {
+		logging.New("channels", "channel", channel).Warn("Could not write transcript", "format", query.Format, "err", err)
+	}
+}
@@ -0,0 +1,161 @@
+package channels
+
+import (
+	"fmt"
+	"regexp"
+	"server/captions"
+	"server/costs"
+	"server/encoder"
+	"server/encoder/strategies"
+	"server/subtitles"
+	"server/transcriber"
+	"server/transcriber/filters"
+	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/failover"
+	"server/transcriber/slo"
+	"server/transcriber/translators"
+	"server/transcriber/vad"
+	"strings"
+)
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// DVR ...
+type DVR struct {
+	Window int `json:"window"` // Seconds of media and captions available for rewinding
+}
+
+// DefaultDVR ...
+var DefaultDVR = DVR{
+	Window: 100,
+}
+
+// segments ...
+func (d DVR) segments() int {
+	window := d.Window
+	if window <= 0 {
+		window = DefaultDVR.Window
+	}
+
+	// Rounding up, the window is never shorter than configured
+	return (window + strategies.SegmentDuration - 1) / strategies.SegmentDuration
+}
+
+// Config ...
+type Config struct {
+	Name        string             `json:"name"`
+	PathPrefix  string             `json:"pathPrefix"` // Defaults to /channels/<name>/
+	DVR         DVR                `json:"dvr"`
+	Encoder     encoder.Config     `json:"encoder"`
+	Recognizer  recognizers.Config `json:"recognizer"`
+	VAD         vad.Config         `json:"vad"`         // Voice activity detection, skips recognition of segments without speech
+	Budget      costs.Budget       `json:"budget"`      // Daily and monthly recognition spending limits
+	Filter      filters.Config     `json:"filter"`      // Content filtering of the published captions
+	Translation translators.Config `json:"translation"` // Additional subtitle languages
+	Captions    captions.Style     `json:"captions"`
+	Latency     slo.Config         `json:"latency"` // Degrades the transcription when captions fall behind the live edge
+}
+
+// Prefix ...
+// The normalized HTTP path prefix the channel output is served under, always with leading and trailing slashes
+func (c Config) Prefix() string {
+	prefix := c.PathPrefix
+	if prefix == "" {
+		prefix = fmt.Sprintf("/channels/%s/", c.Name)
+	}
+
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	return prefix
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	if !validName.MatchString(c.Name) {
+		return fmt.Errorf("invalid channel name %q, only letters, digits, '-' and '_' are allowed", c.Name)
+	}
+
+	if strings.HasPrefix(c.Prefix(), "/api/") {
+		return fmt.Errorf("channel %s: path prefix %s is reserved", c.Name, c.Prefix())
+	}
+
+	err := c.Encoder.Validate()
+	if err != nil {
+		return fmt.Errorf("channel %s: %v", c.Name, err)
+	}
+
+	_, err = transcriber.NewRecognizer(c.Recognizer, c.Name)
+	if err != nil {
+		return fmt.Errorf("channel %s: %v", c.Name, err)
+	}
+
+	err = c.Budget.Validate()
+	if err != nil {
+		return fmt.Errorf("channel %s: %v", c.Name, err)
+	}
+
+	if fallback, ok := c.Budget.Recognizer(c.Recognizer); ok {
+		_, err = transcriber.NewRecognizer(fallback, c.Name)
+		if err != nil {
+			return fmt.Errorf("channel %s: budget: %v", c.Name, err)
+		}
+	}
+
+	err = c.VAD.Validate()
+	if err != nil {
+		return fmt.Errorf("channel %s: %v", c.Name, err)
+	}
+
+	err = c.Latency.Validate()
+	if err != nil {
+		return fmt.Errorf("channel %s: %v", c.Name, err)
+	}
+
+	if fast, ok := c.Latency.Recognizer(c.Recognizer); ok {
+		_, err = transcriber.NewRecognizer(fast, c.Name)
+		if err != nil {
+			return fmt.Errorf("channel %s: latency: %v", c.Name, err)
+		}
+	}
+
+	err = c.Filter.Validate()
+	if err != nil {
+		return fmt.Errorf("channel %s: %v", c.Name, err)
+	}
+
+	err = c.Captions.Validate()
+	if err != nil {
+		return fmt.Errorf("channel %s: %v", c.Name, err)
+	}
+
+	if c.Translation.Enabled() {
+		_, err = transcriber.NewTranslator(c.Translation)
+		if err != nil {
+			return fmt.Errorf("channel %s: %v", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Status ...
+type Status struct {
+	Name       string                `json:"name"`
+	PathPrefix string                `json:"pathPrefix"`
+	OutputPath string                `json:"outputPath"`
+	Running    bool                  `json:"running"`
+	Source     string                `json:"source"`
+	DVRWindow  int                   `json:"dvrWindow"`
+	Encoder    encoder.Status        `json:"encoder"`
+	Subtitles  []subtitles.Rendition `json:"subtitles"`
+	// Health of the recognizers of a failover chain, in order
+	Recognizers []failover.Status `json:"recognizers,omitempty"`
+	// The live window and how far its captions are behind, while running
+	Transcription *transcriber.Progress `json:"transcription,omitempty"`
+}
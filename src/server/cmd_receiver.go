@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"server/alerts"
+	"sync/atomic"
+)
+
+// runReceiver ...
+// Usage: vsr webhook-receiver [flags]
+// A local endpoint for testing alert rules, prints every delivery and checks its signature
+func runReceiver(args []string) int {
+	flags := flag.NewFlagSet("webhook-receiver", flag.ExitOnError)
+	addr := flags.String("addr", ":9090", "address to listen on")
+	secret := flags.String("secret", "", "webhook secret, signatures are checked when set")
+	fail := flags.Int("fail", 0, "respond 503 to the first n deliveries, to exercise retries")
+	flags.Parse(args)
+
+	var failures int32
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		event := r.Header.Get(alerts.EventHeader)
+
+		if *secret != "" && !alerts.Verify(*secret, r.Header.Get(alerts.TimestampHeader), body, r.Header.Get(alerts.SignatureHeader)) {
+			fmt.Printf("[runReceiver] Event %s: invalid signature \n", event)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if n := atomic.AddInt32(&failures, 1); int(n) <= *fail {
+			fmt.Printf("[runReceiver] Event %s: failing on purpose (%d/%d) \n", event, n, *fail)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Printf("[runReceiver] Event %s: %s \n", event, body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	fmt.Println("[runReceiver] listening on: ", *addr)
+	err := http.ListenAndServe(*addr, nil)
+	if err != nil {
+		fmt.Println("[runReceiver] err: ", err)
+		return 1
+	}
+
+	return 0
+}
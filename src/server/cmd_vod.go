@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"server/logging"
+	"server/transcriber/recognizers/cache"
+	"server/vod"
+	"syscall"
+)
+
+// runVOD ...
+// Usage: vsr vod [flags] <input file or finished playlist> <output dir>
+func runVOD(args []string) int {
+	config := vod.DefaultConfig()
+	config.EncoderPath = ffmpegPath
+
+	recognitions := cache.DefaultConfig()
+
+	flags := flag.NewFlagSet("vod", flag.ExitOnError)
+	flags.DurationVar(&config.ChunkDuration, "chunk", config.ChunkDuration, "audio duration per recognition request")
+	flags.DurationVar(&config.Overlap, "overlap", config.Overlap, "audio shared by consecutive chunks")
+	flags.IntVar(&config.Parallelism, "parallel", config.Parallelism, "chunks transcribed concurrently")
+	flags.IntVar(&config.Retries, "retries", config.Retries, "retries per chunk before the job fails")
+	flags.DurationVar(&config.SegmentDuration, "segment", config.SegmentDuration, "duration of the WebVTT segments")
+	flags.BoolVar(&config.LongRunning, "long", config.LongRunning, fmt.Sprintf("use long-running batch recognition, chunks default to %v", vod.LongRunningChunkDuration))
+	flags.DurationVar(&config.PollInterval, "poll", config.PollInterval, "polling interval for long-running recognition")
+	flags.StringVar(&config.Recognizer.Endpoint, "endpoint", config.Recognizer.Endpoint, "recognizer API endpoint override, e.g. a local fake server")
+	flags.BoolVar(&config.Recognizer.Insecure, "insecure", config.Recognizer.Insecure, "plaintext connection to the endpoint without authentication")
+	flags.StringVar(&config.Recognizer.Provider, "provider", config.Recognizer.Provider, "recognizer provider")
+	flags.StringVar(&config.Recognizer.LanguageCode, "language", config.Recognizer.LanguageCode, "spoken language (BCP-47)")
+	flags.StringVar(&config.Recognizer.Model, "model", config.Recognizer.Model, "recognizer model")
+	flags.BoolVar(&config.Recognizer.Diarization, "diarization", config.Recognizer.Diarization, "tag words with their speaker, speakers are numbered per chunk")
+	flags.StringVar(&recognitions.Path, "cache", recognitions.Path, "recognition cache directory, shared with the server by default, empty to disable")
+	flags.StringVar(&config.Captions.Speakers, "speakers", config.Captions.Speakers, "speaker labels in captions: voice or dash")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: vsr vod [flags] <input file or finished playlist> <output dir>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	log := logging.New("vod")
+
+	chunkProvided := false
+	flags.Visit(func(f *flag.Flag) {
+		chunkProvided = chunkProvided || f.Name == "chunk"
+	})
+
+	if config.LongRunning && !chunkProvided {
+		config.ChunkDuration = vod.LongRunningChunkDuration
+	}
+
+	if !config.LongRunning && config.ChunkDuration+config.Overlap > vodMaxRequestDuration {
+		log.Error("Chunk and overlap must not exceed the request limit, use -long for longer chunks",
+			"chunk", config.ChunkDuration, "overlap", config.Overlap, "limit", vodMaxRequestDuration)
+		return 2
+	}
+
+	err := config.Captions.Validate()
+	if err != nil {
+		log.Error("Invalid caption options", "err", err)
+		return 2
+	}
+
+	if recognitions.Path != "" {
+		config.Cache, err = cache.Open(recognitions)
+		if err != nil {
+			log.Error("Could not open the recognition cache", "path", recognitions.Path, "err", err)
+			return 1
+		}
+	}
+
+	config.Input = flags.Arg(0)
+
+	output, err := filepath.Abs(flags.Arg(1))
+	if err != nil {
+		log.Error("Invalid output directory", "path", flags.Arg(1), "err", err)
+		return 2
+	}
+	config.OutputPath = output
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Warn("Interrupted, completed chunks are kept for resuming")
+		cancel()
+	}()
+
+	_, err = vod.Run(ctx, config)
+	if err != nil {
+		log.Error("Failed", "input", config.Input, "err", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"server/alerts"
+	"server/channels"
+	"server/costs"
+	"server/encoder"
+	"server/health"
+	"server/logging"
+	"server/tracing"
+	"server/transcriber/filters"
+	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/cache"
+	"server/transcriber/slo"
+	"server/transcriber/vad"
+)
+
+// defaultSource is the playback source used when no configuration file is provided
+const defaultSource = "https://live.corusdigitaldev.com/groupd/live/49a91e7f-1023-430f-8d66-561055f3d0f7/live.isml/live-audio_1=96000-video=2499968.m3u8"
+
+// Shutdown ...
+type Shutdown struct {
+	Timeout       int  `json:"timeout"`       // Seconds to wait for ffmpeg and in-flight transcriptions before giving up
+	KeepWorkspace bool `json:"keepWorkspace"` // Keep the output directory, playlists are ended with EXT-X-ENDLIST
+}
+
+// Config ...
+type Config struct {
+	HTTPAddr    string            `json:"httpAddr"`
+	HistoryPath string            `json:"historyPath"` // Transcript history, kept across restarts and outside of the temporary workspace
+	Shutdown    Shutdown          `json:"shutdown"`
+	Alerts      alerts.Config     `json:"alerts"`
+	Costs       costs.Config      `json:"costs"`
+	Cache       cache.Config      `json:"cache"`   // Recognitions shared by every channel and VOD job
+	Logging     logging.Config    `json:"logging"` // Initial levels and format, changed at runtime through /api/logging
+	Tracing     tracing.Config    `json:"tracing"` // Spans of each segment's journey through the pipeline
+	Health      health.Config     `json:"health"`  // When channels are reported as not ready
+	Channels    []channels.Config `json:"channels"`
+}
+
+// Default ...
+func Default() Config {
+	return Config{
+		HTTPAddr:    ":8080",
+		HistoryPath: "_history",
+		Shutdown: Shutdown{
+			Timeout: 30,
+		},
+		Alerts:  alerts.DefaultConfig(),
+		Costs:   costs.DefaultConfig(),
+		Cache:   cache.DefaultConfig(),
+		Logging: logging.DefaultConfig(),
+		Tracing: tracing.DefaultConfig(),
+		Health:  health.DefaultConfig(),
+		Channels: []channels.Config{
+			DefaultChannel("default", defaultSource),
+		},
+	}
+}
+
+// DefaultChannel ...
+func DefaultChannel(name string, source string) channels.Config {
+	return channels.Config{
+		Name: name,
+		Encoder: encoder.Config{
+			Input: encoder.Input{
+				URI:       source,
+				Reconnect: encoder.DefaultReconnectPolicy,
+			},
+			Strategy: "x264",
+		},
+		DVR:        channels.DefaultDVR,
+		Recognizer: recognizers.DefaultConfig(),
+		VAD:        vad.DefaultConfig(),
+		Filter:     filters.DefaultConfig(),
+		Latency:    slo.DefaultConfig(),
+	}
+}
+
+// Load ...
+// Reads a JSON configuration file, any values not provided fall back to the defaults
+func Load(path string) (Config, error) {
+	config := Default()
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("could not read config file %s: %v", path, err)
+	}
+
+	var file struct {
+		HTTPAddr    string            `json:"httpAddr"`
+		HistoryPath string            `json:"historyPath"`
+		Shutdown    *Shutdown         `json:"shutdown"`
+		Alerts      *alerts.Config    `json:"alerts"`
+		Costs       *costs.Config     `json:"costs"`
+		Cache       *cache.Config     `json:"cache"`
+		Logging     *logging.Config   `json:"logging"`
+		Tracing     *tracing.Config   `json:"tracing"`
+		Health      *health.Config    `json:"health"`
+		Channels    []json.RawMessage `json:"channels"`
+	}
+
+	// Any shutdown settings not provided keep their defaults
+	file.Shutdown = &config.Shutdown
+	file.Alerts = &config.Alerts
+	file.Costs = &config.Costs
+	file.Cache = &config.Cache
+	file.Logging = &config.Logging
+	file.Tracing = &config.Tracing
+	file.Health = &config.Health
+
+	err = json.Unmarshal(raw, &file)
+	if err != nil {
+		return config, fmt.Errorf("could not parse config file %s: %v", path, err)
+	}
+
+	if file.HTTPAddr != "" {
+		config.HTTPAddr = file.HTTPAddr
+	}
+
+	if file.HistoryPath != "" {
+		config.HistoryPath = file.HistoryPath
+	}
+
+	err = config.Logging.Validate()
+	if err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	err = config.Tracing.Validate()
+	if err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	err = config.Health.Validate()
+	if err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	ruleIDs := make(map[string]bool)
+
+	for _, rule := range config.Alerts.Rules {
+		err = rule.Validate()
+		if err != nil {
+			return config, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+
+		if ruleIDs[rule.ID] {
+			return config, fmt.Errorf("invalid config file %s: duplicate alert rule %s", path, rule.ID)
+		}
+
+		ruleIDs[rule.ID] = true
+	}
+
+	if file.Channels != nil {
+		config.Channels = make([]channels.Config, 0, len(file.Channels))
+	}
+
+	names := make(map[string]bool)
+
+	for i, rawChannel := range file.Channels {
+		// Each channel starts from the defaults and only the fields it sets replace them, e.g. an input without a
+		// reconnect policy keeps the default one
+		channel := DefaultChannel("", "")
+
+		err = json.Unmarshal(rawChannel, &channel)
+		if err != nil {
+			return config, fmt.Errorf("could not parse channel #%d in config file %s: %v", i, path, err)
+		}
+
+		err = channel.Validate()
+		if err != nil {
+			return config, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+
+		if names[channel.Name] {
+			return config, fmt.Errorf("invalid config file %s: duplicate channel name %s", path, channel.Name)
+		}
+
+		names[channel.Name] = true
+		config.Channels = append(config.Channels, channel)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/encoder"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		check  func(t *testing.T, config Config)
+		errors string // Part of the expected error, empty when valid
+	}{
+		{
+			name: "defaults",
+			file: `{}`,
+			check: func(t *testing.T, config Config) {
+				if config.HTTPAddr != ":8080" || config.Shutdown.Timeout != 30 || config.Logging.Level != "info" {
+					t.Errorf("config = %+v, want the defaults", config)
+				}
+
+				if len(config.Channels) != 1 || config.Channels[0].Name != "default" || config.Channels[0].Encoder.Input.URI != defaultSource {
+					t.Errorf("channels = %+v, want the default channel", config.Channels)
+				}
+			},
+		},
+		{
+			name: "settings not provided keep their defaults",
+			file: `{"httpAddr": ":9090", "shutdown": {"keepWorkspace": true}, "logging": {"format": "json"}}`,
+			check: func(t *testing.T, config Config) {
+				if config.HTTPAddr != ":9090" || config.HistoryPath != "_history" {
+					t.Errorf("addr = %s, history = %s, want :9090 and _history", config.HTTPAddr, config.HistoryPath)
+				}
+
+				if !config.Shutdown.KeepWorkspace || config.Shutdown.Timeout != 30 {
+					t.Errorf("shutdown = %+v, want the workspace kept with the default timeout", config.Shutdown)
+				}
+
+				if config.Logging.Format != "json" || config.Logging.Level != "info" {
+					t.Errorf("logging = %+v, want json at the default level", config.Logging)
+				}
+			},
+		},
+		{
+			name: "channels start from the defaults",
+			file: `{"channels": [
+				{"name": "news", "encoder": {"input": {"uri": "https://example.com/live.m3u8", "reconnect": {"enabled": true, "delayMax": 10}}}},
+				{"name": "studio", "encoder": {"input": {"uri": "srt://0.0.0.0:9000", "listen": true}}}
+			]}`,
+			check: func(t *testing.T, config Config) {
+				if len(config.Channels) != 2 {
+					t.Fatalf("channels = %+v, want news and studio", config.Channels)
+				}
+
+				news, studio := config.Channels[0], config.Channels[1]
+				if news.Encoder.Strategy != "x264" || news.Recognizer.Provider != "gcp" {
+					t.Errorf("news = %+v, want the default strategy and recognizer", news)
+				}
+
+				// Fields of the reconnect policy that are set replace the default ones, the others are kept
+				want := encoder.DefaultReconnectPolicy
+				want.DelayMax = 10
+				if news.Encoder.Input.Reconnect != want {
+					t.Errorf("news reconnect = %+v, want %+v", news.Encoder.Input.Reconnect, want)
+				}
+
+				if studio.Encoder.Input.Kind() != encoder.SourceSRT || !studio.Encoder.Input.Listen || studio.Encoder.Input.Reconnect != encoder.DefaultReconnectPolicy {
+					t.Errorf("studio input = %+v, want a listening srt input with the default reconnect policy", studio.Encoder.Input)
+				}
+			},
+		},
+		{
+			name: "no channels",
+			file: `{"channels": []}`,
+			check: func(t *testing.T, config Config) {
+				if len(config.Channels) != 0 {
+					t.Errorf("channels = %+v, want none", config.Channels)
+				}
+			},
+		},
+		{name: "invalid json", file: `{"channels": [}`, errors: "could not parse config file"},
+		{name: "invalid channel", file: `{"channels": [{"name": "news", "encoder": {"input": {"uri": "ftp://example.com/a.ts"}}}]}`, errors: "unsupported input scheme"},
+		{name: "listening file", file: `{"channels": [{"name": "news", "encoder": {"input": {"uri": "a.mp4", "listen": true}}}]}`, errors: "listen is only supported"},
+		{name: "unnamed channel", file: `{"channels": [{"encoder": {"input": {"uri": "a.mp4"}}}]}`, errors: "invalid channel name"},
+		{
+			name:   "duplicate channel",
+			file:   `{"channels": [{"name": "news", "encoder": {"input": {"uri": "a.mp4"}}}, {"name": "news", "encoder": {"input": {"uri": "b.mp4"}}}]}`,
+			errors: "duplicate channel name news",
+		},
+		{name: "invalid logging", file: `{"logging": {"level": "loud"}}`, errors: "unknown log level"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := Load(writeConfig(t, test.file))
+
+			if test.errors != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errors) {
+					t.Fatalf("err = %v, want %q", err, test.errors)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.check(t, config)
+		})
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := Load(filepath.Join(os.TempDir(), "missing", "config.json"))
+	if err == nil || !strings.Contains(err.Error(), "could not read config file") {
+		t.Errorf("err = %v, want the file to be unreadable", err)
+	}
+}
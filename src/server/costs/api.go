@@ -0,0 +1,46 @@
+package costs
+
+import (
+	"fmt"
+	"net/http"
+	"server/api"
+	"strings"
+	"time"
+)
+
+// APIPrefix ...
+const APIPrefix = "/api/costs"
+
+// APIHandler ...
+//
+// GET /api/costs        - the current day's and month's usage, spend and budget of every channel
+// GET /api/costs/daily  - usage per day, provider and model, filtered with channel, since and until (YYYY-MM-DD)
+func (m *Meter) APIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, APIPrefix), "/")
+
+		if r.Method != http.MethodGet {
+			api.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		switch path {
+		case "":
+			api.WriteJSON(w, http.StatusOK, m.Summary())
+
+		case "daily":
+			query := r.URL.Query()
+			for _, param := range []string{"since", "until"} {
+				if _, err := time.Parse(dayFormat, query.Get(param)); query.Get(param) != "" && err != nil {
+					api.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD", param, query.Get(param)))
+					return
+				}
+			}
+
+			api.WriteJSON(w, http.StatusOK, m.Daily(query.Get("channel"), query.Get("since"), query.Get("until")))
+
+		default:
+			api.WriteError(w, http.StatusNotFound, fmt.Errorf("no route for %s %s", r.Method, r.URL.Path))
+		}
+	})
+}
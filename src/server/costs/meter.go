@@ -0,0 +1,483 @@
+package costs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/logging"
+	"server/metrics"
+	"server/transcriber/recognizers"
+	"sort"
+	"sync"
+	"time"
+)
+
+// retention ...
+// Days of usage kept, enough to compare a month with the same month last year
+const retention = 400
+
+const (
+	dayFormat   = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+// saveInterval ...
+// How often recorded usage is written to disk, it's also saved when the meter is closed
+const saveInterval = 10 * time.Second
+
+var (
+	audioSeconds = metrics.NewCounterVec(
+		"vsr_recognition_audio_seconds_total",
+		"Seconds of audio sent for recognition, by channel, provider and model",
+		"channel", "provider", "model",
+	)
+	billedSeconds = metrics.NewCounterVec(
+		"vsr_recognition_billed_seconds_total",
+		"Seconds of audio billed, rounded up to the provider's billing increment per request",
+		"channel", "provider", "model",
+	)
+	costTotal = metrics.NewCounterVec(
+		"vsr_recognition_cost_total",
+		"Estimated recognition spend since the process started, in the configured currency",
+		"channel", "provider", "model",
+	)
+	spend = metrics.NewGaugeVec(
+		"vsr_recognition_spend",
+		"Estimated recognition spend of the current day or month, in the configured currency",
+		"channel", "period",
+	)
+	overBudget = metrics.NewGaugeVec(
+		"vsr_recognition_budget_exceeded",
+		"1 while the channel's daily or monthly budget is exhausted",
+		"channel",
+	)
+)
+
+// Usage ...
+type Usage struct {
+	Requests      int     `json:"requests"`
+	Seconds       float64 `json:"seconds"`       // Audio sent
+	BilledSeconds float64 `json:"billedSeconds"` // Rounded up to the billing increments
+	Cost          float64 `json:"cost"`
+}
+
+func (u *Usage) add(other Usage) {
+	u.Requests += other.Requests
+	u.Seconds += other.Seconds
+	u.BilledSeconds += other.BilledSeconds
+	u.Cost += other.Cost
+}
+
+// Record ...
+// A channel's usage of one provider and model over a day
+type Record struct {
+	Day      string `json:"day"` // UTC, e.g. 2026-01-31
+	Channel  string `json:"channel"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Usage
+}
+
+type recordKey struct {
+	day      string
+	channel  string
+	provider string
+	model    string
+}
+
+// totalKey ...
+// A channel's spend over a day (YYYY-MM-DD) or a month (YYYY-MM)
+type totalKey struct {
+	channel string
+	period  string
+}
+
+// Meter ...
+// Meters the audio sent for recognition, estimates its cost and tracks it against the channels' budgets
+type Meter struct {
+	config Config
+	log    *logging.Logger
+
+	mu       sync.Mutex
+	records  map[recordKey]*Record
+	totals   map[totalKey]float64 // Running spend, so budgets are checked without going through every record
+	budgets  map[string]Budget
+	exceeded map[string]string // Channel to the exhausted budget's period
+	dirty    bool              // Usage was recorded since it was last saved
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open ...
+// Loads the usage recorded by previous runs
+func Open(config Config) (*Meter, error) {
+	err := os.MkdirAll(config.Path, 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Meter{
+		config:   config,
+		log:      logging.New("costs"),
+		records:  make(map[recordKey]*Record),
+		totals:   make(map[totalKey]float64),
+		budgets:  make(map[string]Budget),
+		exceeded: make(map[string]string),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	raw, err := ioutil.ReadFile(m.path())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		var records []Record
+		err = json.Unmarshal(raw, &records)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", m.path(), err)
+		}
+
+		for i := range records {
+			record := records[i]
+			m.records[record.key()] = &record
+			m.total(record.Channel, record.Day, record.Cost)
+		}
+	}
+
+	go m.saveEvery(saveInterval)
+
+	return m, nil
+}
+
+// Close ...
+// Stops saving periodically and saves the usage recorded since the last time
+func (m *Meter) Close() error {
+	close(m.stop)
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.saveIfDirty(time.Now())
+}
+
+func (m *Meter) saveEvery(interval time.Duration) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			err := m.saveIfDirty(now)
+			m.mu.Unlock()
+
+			if err != nil {
+				m.log.Error("Could not save recognition usage", "err", err)
+			}
+		}
+	}
+}
+
+// Track ...
+// Enforces the channel's budget, replacing any previous one
+func (m *Meter) Track(channel string, budget Budget) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if budget.Enabled() {
+		m.budgets[channel] = budget
+	} else {
+		delete(m.budgets, channel)
+	}
+
+	m.check(channel, time.Now())
+}
+
+// Record ...
+// Meters a single recognition request with the given seconds of audio
+func (m *Meter) Record(channel string, recognizer recognizers.Config, seconds float64) Usage {
+	billed, cost := m.config.Cost(recognizer, seconds)
+	usage := Usage{Requests: 1, Seconds: seconds, BilledSeconds: billed, Cost: cost}
+
+	provider, model := Provider(recognizer), Model(recognizer)
+	audioSeconds.Add(seconds, channel, provider, model)
+	billedSeconds.Add(billed, channel, provider, model)
+	costTotal.Add(cost, channel, provider, model)
+
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := recordKey{day: now.UTC().Format(dayFormat), channel: channel, provider: provider, model: model}
+	record, ok := m.records[key]
+	if !ok {
+		record = &Record{Day: key.day, Channel: channel, Provider: provider, Model: model}
+		m.records[key] = record
+	}
+
+	record.add(usage)
+	m.total(channel, key.day, cost)
+	m.dirty = true
+	m.check(channel, now)
+
+	return usage
+}
+
+// Exceeded ...
+// The period of the channel's exhausted budget, daily or monthly, empty while within budget
+func (m *Meter) Exceeded(channel string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.check(channel, time.Now())
+}
+
+// Summary ...
+type Summary struct {
+	Currency string           `json:"currency"`
+	Day      string           `json:"day"`
+	Month    string           `json:"month"`
+	Today    Usage            `json:"today"` // Every channel
+	Total    Usage            `json:"total"` // Every channel, this month
+	Channels []ChannelSummary `json:"channels"`
+}
+
+// ChannelSummary ...
+type ChannelSummary struct {
+	Channel   string   `json:"channel"`
+	Today     Usage    `json:"today"`
+	Month     Usage    `json:"month"`
+	Budget    *Budget  `json:"budget,omitempty"`
+	Exceeded  string   `json:"exceeded,omitempty"` // daily or monthly, while the budget is exhausted
+	Providers []Record `json:"providers"`          // This month, by provider and model
+}
+
+// Summary ...
+// The current day's and month's usage of every channel
+func (m *Meter) Summary() Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	summary := Summary{
+		Currency: m.config.Currency,
+		Day:      now.Format(dayFormat),
+		Month:    now.Format(monthFormat),
+		Channels: make([]ChannelSummary, 0),
+	}
+
+	channels := make(map[string]*ChannelSummary)
+	channel := func(name string) *ChannelSummary {
+		if _, ok := channels[name]; !ok {
+			channels[name] = &ChannelSummary{Channel: name, Providers: make([]Record, 0)}
+		}
+
+		return channels[name]
+	}
+
+	providers := make(map[recordKey]*Record)
+
+	for key, record := range m.records {
+		if !isMonth(key.day, summary.Month) {
+			continue
+		}
+
+		c := channel(key.channel)
+		c.Month.add(record.Usage)
+		summary.Total.add(record.Usage)
+
+		if key.day == summary.Day {
+			c.Today.add(record.Usage)
+			summary.Today.add(record.Usage)
+		}
+
+		monthly := recordKey{day: summary.Month, channel: key.channel, provider: key.provider, model: key.model}
+		if _, ok := providers[monthly]; !ok {
+			providers[monthly] = &Record{Day: summary.Month, Channel: key.channel, Provider: key.provider, Model: key.model}
+		}
+
+		providers[monthly].add(record.Usage)
+	}
+
+	for name, budget := range m.budgets {
+		b := budget
+		c := channel(name)
+		c.Budget = &b
+		c.Exceeded = m.check(name, now)
+	}
+
+	for _, record := range providers {
+		c := channel(record.Channel)
+		c.Providers = append(c.Providers, *record)
+	}
+
+	for _, c := range channels {
+		sort.Slice(c.Providers, func(i, j int) bool {
+			return c.Providers[i].Provider+c.Providers[i].Model < c.Providers[j].Provider+c.Providers[j].Model
+		})
+
+		summary.Channels = append(summary.Channels, *c)
+	}
+
+	sort.Slice(summary.Channels, func(i, j int) bool {
+		return summary.Channels[i].Channel < summary.Channels[j].Channel
+	})
+
+	return summary
+}
+
+// Daily ...
+// Usage per day, provider and model between the given days (inclusive, YYYY-MM-DD), oldest first.
+// An empty channel, since or until doesn't filter.
+func (m *Meter) Daily(channel string, since string, until string) []Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]Record, 0)
+	for key, record := range m.records {
+		if (channel != "" && key.channel != channel) || (since != "" && key.day < since) || (until != "" && key.day > until) {
+			continue
+		}
+
+		result = append(result, *record)
+	}
+
+	sortRecords(result)
+	return result
+}
+
+// check must be called with the lock held, it updates the channel's spend and logs budget changes
+func (m *Meter) check(channel string, now time.Time) string {
+	day := now.UTC().Format(dayFormat)
+	month := now.UTC().Format(monthFormat)
+
+	today := m.totals[totalKey{channel: channel, period: day}]
+	thisMonth := m.totals[totalKey{channel: channel, period: month}]
+
+	spend.Set(today, channel, "day")
+	spend.Set(thisMonth, channel, "month")
+
+	exceeded := ""
+	budget, ok := m.budgets[channel]
+
+	switch {
+	case !ok:
+	case budget.Monthly > 0 && thisMonth >= budget.Monthly:
+		exceeded = "monthly"
+	case budget.Daily > 0 && today >= budget.Daily:
+		exceeded = "daily"
+	}
+
+	if exceeded != m.exceeded[channel] {
+		if exceeded != "" {
+			m.log.Warn("Budget exhausted", "channel", channel, "budget", exceeded, "today", today, "month", thisMonth,
+				"currency", m.config.Currency, "action", budget.Action)
+			overBudget.Set(1, channel)
+		} else {
+			m.log.Info("Within budget again", "channel", channel)
+			overBudget.Set(0, channel)
+		}
+	}
+
+	m.exceeded[channel] = exceeded
+	return exceeded
+}
+
+// total adds to the channel's running spend of the day and its month, must be called with the lock held
+func (m *Meter) total(channel string, day string, cost float64) {
+	m.totals[totalKey{channel: channel, period: day}] += cost
+	if len(day) >= len(monthFormat) {
+		m.totals[totalKey{channel: channel, period: day[:len(monthFormat)]}] += cost
+	}
+}
+
+// saveIfDirty must be called with the lock held
+func (m *Meter) saveIfDirty(now time.Time) error {
+	if !m.dirty {
+		return nil
+	}
+
+	err := m.save(now)
+	if err != nil {
+		return err
+	}
+
+	m.dirty = false
+	return nil
+}
+
+// save must be called with the lock held, it also drops usage older than the retention
+func (m *Meter) save(now time.Time) error {
+	oldest := now.UTC().AddDate(0, 0, -retention).Format(dayFormat)
+
+	records := make([]Record, 0, len(m.records))
+	for key, record := range m.records {
+		if key.day < oldest {
+			delete(m.records, key)
+			continue
+		}
+
+		records = append(records, *record)
+	}
+
+	// Days and months before the retention are never checked against a budget again
+	for key := range m.totals {
+		if key.period < oldest[:len(key.period)] {
+			delete(m.totals, key)
+		}
+	}
+
+	sortRecords(records)
+
+	raw, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Replacing the file atomically, a crash never loses the previous usage
+	tmp := m.path() + ".tmp"
+	err = ioutil.WriteFile(tmp, raw, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, m.path())
+}
+
+func (m *Meter) path() string {
+	return filepath.Join(m.config.Path, "usage.json")
+}
+
+func (r Record) key() recordKey {
+	return recordKey{day: r.Day, channel: r.Channel, provider: r.Provider, model: r.Model}
+}
+
+func isMonth(day string, month string) bool {
+	return len(day) >= len(month) && day[:len(month)] == month
+}
+
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+
+		return a.Provider+a.Model < b.Provider+b.Model
+	})
+}
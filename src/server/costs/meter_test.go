@@ -0,0 +1,258 @@
+package costs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"server/transcriber/recognizers"
+	"testing"
+	"time"
+)
+
+var gcp = recognizers.Config{Provider: "gcp", LanguageCode: "en-US", Model: "video", UseEnhanced: true}
+
+func openMeter(t *testing.T, dir string) *Meter {
+	if dir == "" {
+		var err error
+		dir, err = ioutil.TempDir("", "costs")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { os.RemoveAll(dir) })
+	}
+
+	config := DefaultConfig()
+	config.Path = dir
+
+	m, err := Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCost(t *testing.T) {
+	config := DefaultConfig()
+	config.Prices["gcp"] = Price{PerMinute: 0.024, EnhancedPerMinute: 0.036, Models: map[string]float64{"cheap": 0.012}, Increment: 15}
+
+	tests := []struct {
+		name       string
+		recognizer recognizers.Config
+		seconds    float64
+		billed     float64
+		cost       float64
+	}{
+		{"rounded up to the increment", recognizers.Config{Provider: "gcp"}, 16, 30, 0.012},
+		{"exact increment", recognizers.Config{Provider: "gcp"}, 60, 60, 0.024},
+		{"enhanced", recognizers.Config{Provider: "gcp", UseEnhanced: true}, 60, 60, 0.036},
+		{"model price", recognizers.Config{Provider: "gcp", Model: "cheap", UseEnhanced: true}, 60, 60, 0.012},
+		{"default provider", recognizers.Config{}, 4, 15, 0.006},
+		{"local endpoints are free", recognizers.Config{Provider: "gcp", Insecure: true}, 4.5, 5, 0},
+		{"unknown provider is free", recognizers.Config{Provider: "other"}, 4.5, 4.5, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			billed, cost := config.Cost(test.recognizer, test.seconds)
+			if !near(billed, test.billed) || !near(cost, test.cost) {
+				t.Errorf("billed %v for %v, want %v for %v", billed, cost, test.billed, test.cost)
+			}
+		})
+	}
+}
+
+func TestRunningTotals(t *testing.T) {
+	m := openMeter(t, "")
+	defer m.Close()
+
+	// 60s enhanced at 0.036 per minute
+	for i := 0; i < 3; i++ {
+		m.Record("news", gcp, 60)
+	}
+	m.Record("sports", gcp, 30)
+
+	summary := m.Summary()
+	if summary.Today.Requests != 4 || !near(summary.Today.Cost, 0.126) || !near(summary.Total.Cost, 0.126) {
+		t.Errorf("today = %+v, month = %+v, want 4 requests costing 0.126", summary.Today, summary.Total)
+	}
+
+	if len(summary.Channels) != 2 || summary.Channels[0].Channel != "news" || !near(summary.Channels[0].Today.Cost, 0.108) {
+		t.Fatalf("channels = %+v, want news first at 0.108", summary.Channels)
+	}
+
+	news := summary.Channels[0].Providers
+	if len(news) != 1 || news[0].Model != "video (enhanced)" || news[0].Requests != 3 || news[0].BilledSeconds != 180 {
+		t.Errorf("news providers = %+v, want 3 requests of video (enhanced) billed 180s", news)
+	}
+
+	today := time.Now().UTC().Format(dayFormat)
+	if got := m.totals[totalKey{channel: "news", period: today}]; !near(got, 0.108) {
+		t.Errorf("running daily total = %v, want 0.108", got)
+	}
+
+	if got := m.totals[totalKey{channel: "news", period: today[:len(monthFormat)]}]; !near(got, 0.108) {
+		t.Errorf("running monthly total = %v, want 0.108", got)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   Budget
+		requests int // Of 60s at 0.036
+		exceeded string
+		switchTo string // Model switched to, empty to pause
+	}{
+		{name: "within the daily budget", budget: Budget{Daily: 0.1}, requests: 2},
+		{name: "daily budget paused", budget: Budget{Daily: 0.1, Action: ActionPause}, requests: 3, exceeded: "daily"},
+		{
+			name:     "daily budget falls back",
+			budget:   Budget{Daily: 0.1, Action: ActionFallback, FallbackModel: "default"},
+			requests: 3,
+			exceeded: "daily",
+			switchTo: "default",
+		},
+		{
+			name:     "monthly budget switches to a local recognizer",
+			budget:   Budget{Daily: 1, Monthly: 0.05, Action: ActionLocal, Local: recognizers.Config{Endpoint: "localhost:9000", Insecure: true}},
+			requests: 2,
+			exceeded: "monthly",
+			switchTo: "",
+		},
+		{name: "no budget", budget: Budget{}, requests: 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.budget.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			m := openMeter(t, "")
+			defer m.Close()
+
+			m.Track("news", test.budget)
+			for i := 0; i < test.requests; i++ {
+				m.Record("news", gcp, 60)
+			}
+
+			if got := m.Exceeded("news"); got != test.exceeded {
+				t.Fatalf("exceeded = %q, want %q", got, test.exceeded)
+			}
+
+			if test.exceeded == "" {
+				return
+			}
+
+			recognizer, ok := test.budget.Recognizer(gcp)
+			switch test.budget.Action {
+			case ActionPause:
+				if ok {
+					t.Errorf("switched to %+v, want recognition paused", recognizer)
+				}
+
+			case ActionFallback:
+				if !ok || recognizer.Model != test.switchTo || recognizer.UseEnhanced || recognizer.LanguageCode != gcp.LanguageCode {
+					t.Errorf("switched to %+v, want the unenhanced %s model", recognizer, test.switchTo)
+				}
+
+			case ActionLocal:
+				if !ok || recognizer.Endpoint != "localhost:9000" || recognizer.LanguageCode != gcp.LanguageCode || Provider(recognizer) != LocalProvider {
+					t.Errorf("switched to %+v, want the local recognizer in the channel's language", recognizer)
+				}
+			}
+
+			// Raising the budget resumes recognition
+			m.Track("news", Budget{Daily: 10, Monthly: 10})
+			if got := m.Exceeded("news"); got != "" {
+				t.Errorf("exceeded = %q after raising the budget, want within budget", got)
+			}
+		})
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget Budget
+		valid  bool
+	}{
+		{"pause by default", Budget{Daily: 1}, true},
+		{"negative", Budget{Daily: -1}, false},
+		{"fallback without a model", Budget{Daily: 1, Action: ActionFallback}, false},
+		{"local without an endpoint", Budget{Daily: 1, Action: ActionLocal}, false},
+		{"unknown action", Budget{Daily: 1, Action: "stop"}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.budget.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: err = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "costs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Usage of a previous run, including some past the retention
+	now := time.Now().UTC()
+	previous := []Record{
+		{Day: now.Format(dayFormat), Channel: "news", Provider: "gcp", Model: "video (enhanced)", Usage: Usage{Requests: 2, Seconds: 120, BilledSeconds: 120, Cost: 0.072}},
+		{Day: now.AddDate(0, 0, -retention-1).Format(dayFormat), Channel: "news", Provider: "gcp", Model: "default", Usage: Usage{Requests: 1, Cost: 5}},
+	}
+
+	raw, _ := json.Marshal(previous)
+	err = ioutil.WriteFile(dir+"/usage.json", raw, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := openMeter(t, dir)
+	m.Track("news", Budget{Daily: 0.1})
+	if got := m.Exceeded("news"); got != "" {
+		t.Fatalf("exceeded = %q with the reloaded usage, want within budget", got)
+	}
+
+	// The reloaded spend counts towards the budget
+	m.Record("news", gcp, 60)
+	if got := m.Exceeded("news"); got != "daily" {
+		t.Fatalf("exceeded = %q, want daily", got)
+	}
+
+	// Usage is only written periodically and on close
+	raw, _ = ioutil.ReadFile(dir + "/usage.json")
+	var saved []Record
+	json.Unmarshal(raw, &saved)
+	if len(saved) != 2 || saved[0].Requests+saved[1].Requests != 3 {
+		t.Errorf("usage was saved before closing the meter: %+v", saved)
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openMeter(t, dir)
+	defer reopened.Close()
+
+	daily := reopened.Daily("news", "", "")
+	if len(daily) != 1 || daily[0].Requests != 3 || !near(daily[0].Cost, 0.108) {
+		t.Errorf("reloaded usage = %+v, want today's 3 requests costing 0.108 and nothing past the retention", daily)
+	}
+
+	reopened.Track("news", Budget{Daily: 0.1})
+	if got := reopened.Exceeded("news"); got != "daily" {
+		t.Errorf("exceeded = %q after reloading, want daily", got)
+	}
+}
@@ -0,0 +1,177 @@
+package costs
+
+import (
+	"fmt"
+	"math"
+	"server/logging"
+	"server/transcriber/recognizers"
+)
+
+// Actions taken once a channel's budget is exhausted
+const (
+	ActionPause    = "pause"    // No more audio is sent, captions stay empty until the budget resets
+	ActionFallback = "fallback" // Switches to a cheaper model of the same provider
+	ActionLocal    = "local"    // Switches to a local recognizer
+)
+
+// LocalProvider ...
+// Recognizers reached over a plaintext connection are assumed to be local, and priced as such
+const LocalProvider = "local"
+
+// Price ...
+// Per minute of audio, every request is billed in whole increments
+type Price struct {
+	PerMinute         float64            `json:"perMinute"`
+	EnhancedPerMinute float64            `json:"enhancedPerMinute"` // Enhanced models, PerMinute when not set
+	Models            map[string]float64 `json:"models,omitempty"`  // Per minute price of specific models, overriding the above
+	Increment         int                `json:"increment"`         // Seconds
+}
+
+// Config ...
+type Config struct {
+	Path     string           `json:"path"` // Daily usage is kept here across restarts
+	Currency string           `json:"currency"`
+	Prices   map[string]Price `json:"prices"` // By provider, "local" for local recognizers
+}
+
+// DefaultConfig ...
+// Speech-to-Text's list prices, billed per 15 seconds
+func DefaultConfig() Config {
+	return Config{
+		Path:     "_costs",
+		Currency: "USD",
+		Prices: map[string]Price{
+			"gcp": {
+				PerMinute:         0.024,
+				EnhancedPerMinute: 0.036,
+				Increment:         15,
+			},
+			LocalProvider: {
+				Increment: 1,
+			},
+			"fake": {
+				Increment: 1,
+			},
+		},
+	}
+}
+
+// Provider ...
+// The price table entry the recognizer is billed by
+func Provider(recognizer recognizers.Config) string {
+	if recognizer.Insecure {
+		return LocalProvider
+	}
+
+	if recognizer.Provider == "" {
+		return "gcp"
+	}
+
+	return recognizer.Provider
+}
+
+// Model ...
+// The model name usage is reported under
+func Model(recognizer recognizers.Config) string {
+	model := recognizer.Model
+	if model == "" {
+		model = "default"
+	}
+
+	if recognizer.UseEnhanced {
+		model += " (enhanced)"
+	}
+
+	return model
+}
+
+// Cost ...
+// The billed seconds and estimated cost of a single request with the given seconds of audio
+func (c Config) Cost(recognizer recognizers.Config, seconds float64) (float64, float64) {
+	price, ok := c.Prices[Provider(recognizer)]
+	if !ok {
+		logging.New("costs").Warn("No price for provider, counting it as free", "provider", Provider(recognizer))
+	}
+
+	billed := seconds
+	if price.Increment > 0 {
+		increment := float64(price.Increment)
+		billed = math.Ceil(seconds/increment) * increment
+	}
+
+	perMinute := price.PerMinute
+	if recognizer.UseEnhanced && price.EnhancedPerMinute > 0 {
+		perMinute = price.EnhancedPerMinute
+	}
+
+	if model, ok := price.Models[recognizer.Model]; ok {
+		perMinute = model
+	}
+
+	return billed, billed / 60 * perMinute
+}
+
+// Budget ...
+// A channel's spending limits, days and months are in UTC
+type Budget struct {
+	Daily         float64            `json:"daily"`   // No limit when 0
+	Monthly       float64            `json:"monthly"` // No limit when 0
+	Action        string             `json:"action"`  // pause, fallback or local
+	FallbackModel string             `json:"fallbackModel,omitempty"`
+	Local         recognizers.Config `json:"local"` // The channel's languages are used when not set
+}
+
+// Enabled ...
+func (b Budget) Enabled() bool {
+	return b.Daily > 0 || b.Monthly > 0
+}
+
+// Validate ...
+func (b Budget) Validate() error {
+	if b.Daily < 0 || b.Monthly < 0 {
+		return fmt.Errorf("budget: limits can't be negative")
+	}
+
+	switch b.Action {
+	case "", ActionPause:
+	case ActionFallback:
+		if b.FallbackModel == "" {
+			return fmt.Errorf("budget: the fallback action needs a fallbackModel")
+		}
+	case ActionLocal:
+		if b.Local.Endpoint == "" {
+			return fmt.Errorf("budget: the local action needs a local recognizer endpoint")
+		}
+	default:
+		return fmt.Errorf("budget: unknown action %q", b.Action)
+	}
+
+	return nil
+}
+
+// Recognizer ...
+// The recognizer to switch to once the budget is exhausted, false when recognition should pause instead
+func (b Budget) Recognizer(primary recognizers.Config) (recognizers.Config, bool) {
+	switch b.Action {
+	case ActionFallback:
+		fallback := primary
+		fallback.Model = b.FallbackModel
+		fallback.UseEnhanced = false
+		return fallback, true
+
+	case ActionLocal:
+		local := b.Local
+		if local.Provider == "" {
+			local.Provider = primary.Provider
+		}
+
+		if local.LanguageCode == "" {
+			local.LanguageCode = primary.LanguageCode
+			local.AlternativeLanguages = primary.AlternativeLanguages
+		}
+
+		return local, true
+	}
+
+	return recognizers.Config{}, false
+}
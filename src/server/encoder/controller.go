@@ -1,42 +1,505 @@
 package encoder
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"server/encoder/strategies"
+	"server/logging"
+	"server/metrics"
+	"strings"
+	"sync"
+	"time"
 )
 
-// Start ...
-func Start(encoderPath string, outputPath string) {
+// Strategy ...
+type Strategy func(ffmpeg string, input []string, output strategies.Output) *exec.Cmd
 
-	streamSource := "https://live.corusdigitaldev.com/groupd/live/49a91e7f-1023-430f-8d66-561055f3d0f7/live.isml/live-audio_1=96000-video=2499968.m3u8" // Path to video input source
-	streamOutputPath := outputPath + "/%v/playlist.m3u8"
-	segmentFilenamePattern := outputPath + "/%v/%04d.m4s"  // Deliberate template variable for ffmpeg's use.
+var strategyByName = map[string]Strategy{
+	"x264":  strategies.X264,
+	"nvenc": strategies.NVENC,
+}
+
+var (
+	restartsMetric    = metrics.NewCounterVec("vsr_encoder_restarts_total", "Number of times the encoder process was restarted.", "channel")
+	stallsMetric      = metrics.NewCounterVec("vsr_encoder_stalls_total", "Number of times the encoder stopped updating its playlists and was killed.", "channel")
+	upMetric          = metrics.NewGaugeVec("vsr_encoder_up", "Whether the encoder process is running and updating its playlists.", "channel")
+	playlistAgeMetric = metrics.NewGaugeVec("vsr_encoder_playlist_age_seconds", "Seconds since the encoder last updated a playlist.", "channel")
+	fpsMetric         = metrics.NewGaugeVec("vsr_encoder_fps", "Frames per second reported by ffmpeg.", "channel")
+	speedMetric       = metrics.NewGaugeVec("vsr_encoder_speed", "Encoding speed relative to real time reported by ffmpeg.", "channel")
+	bitrateMetric     = metrics.NewGaugeVec("vsr_encoder_bitrate_kbps", "Output bitrate reported by ffmpeg, in kbit/s.", "channel")
+	outTimeMetric     = metrics.NewGaugeVec("vsr_encoder_out_time_seconds", "Output media time of the current ffmpeg process.", "channel")
+	dropFramesMetric  = metrics.NewCounterVec("vsr_encoder_dropped_frames_total", "Frames dropped by ffmpeg.", "channel")
+	dupFramesMetric   = metrics.NewCounterVec("vsr_encoder_duplicated_frames_total", "Frames duplicated by ffmpeg.", "channel")
+	messagesMetric    = metrics.NewCounterVec("vsr_encoder_log_messages_total", "ffmpeg warnings and errors by level and class.", "channel", "level", "class")
+)
+
+// progressLogInterval limits how often progress is logged, every block is still recorded
+const progressLogInterval = 10 * time.Second
+
+// Config ...
+type Config struct {
+	Input       Input        `json:"input"`
+	Strategy    string       `json:"strategy"` // "x264" (default) or "nvenc"
+	Supervision *Supervision `json:"supervision"`
+	ListSize    int          `json:"-"` // Set from the channel's DVR window
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	if c.Strategy != "" {
+		if _, ok := strategyByName[c.Strategy]; !ok {
+			return fmt.Errorf("unknown encoding strategy %q", c.Strategy)
+		}
+	}
+
+	return c.Input.Validate()
+}
+
+// Encoder ...
+// Supervises a single ffmpeg process, restarting it with backoff when it exits or stalls
+type Encoder struct {
+	name        string
+	encoderPath string
+	outputPath  string
+	config      Config
+	supervision Supervision
+	log         *logging.Logger
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	status Status
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// New ...
+func New(name string, encoderPath string, outputPath string, config Config) (*Encoder, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
 
-	mode := "x264"
+	supervision := DefaultSupervision
+	if config.Supervision != nil {
+		supervision = *config.Supervision
+	}
+
+	return &Encoder{
+		name:        name,
+		encoderPath: encoderPath,
+		outputPath:  outputPath,
+		config:      config,
+		supervision: supervision,
+		log:         logging.New("encoder", "channel", name),
+		status: Status{
+			State:     StateStarting,
+			Telemetry: Telemetry{Messages: make(map[string]int)},
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}, nil
+}
+
+// Run ...
+// Blocks until the encoder is stopped, restarting ffmpeg whenever it exits
+func (e *Encoder) Run() {
+	defer close(e.done)
+
+	failures := 0
+
+	for attempt := 0; ; attempt++ {
+		if e.isStopped() {
+			return
+		}
+
+		if attempt > 0 {
+			e.mu.Lock()
+			e.status.Restarts++
+			e.mu.Unlock()
+			restartsMetric.Inc(e.name)
+		}
+
+		startedAt := time.Now()
+		err := e.runOnce(attempt > 0)
 
-	/* Default strategy - no-op */
-	strategy := func(string, string, string, string) *exec.Cmd {
-		return nil
+		if e.isStopped() {
+			return
+		}
+
+		// Finite inputs are not restarted once they have been fully encoded
+		if err == nil && e.config.Input.Finite() {
+			e.log.Info("Encoder reached the end of its input")
+			e.setFinished()
+			return
+		}
+
+		// A process that ran for a while is treated as healthy, resetting the backoff
+		if time.Since(startedAt) > 2*e.seconds(e.supervision.BackoffMax) {
+			failures = 0
+		}
+		failures++
+
+		delay := e.backoff(failures)
+		e.log.Warn("Encoder exited, restarting", "err", err, "delay", delay, "failures", failures)
+
+		now := time.Now()
+		next := now.Add(delay)
+
+		e.mu.Lock()
+		e.cmd = nil
+		e.status.State = StateBackoff
+		e.status.PID = 0
+		e.status.LastExitAt = &now
+		e.status.NextRestartAt = &next
+		upMetric.Set(0, e.name)
+		if err != nil {
+			e.status.LastExitError = err.Error()
+		} else {
+			e.status.LastExitError = ""
+		}
+		e.mu.Unlock()
+
+		select {
+		case <-e.stop:
+			return
+		case <-time.After(delay):
+		}
 	}
+}
 
-	if mode == "nvenc" {
-		strategy = strategies.NVENC
-	} else {
+// runOnce starts ffmpeg and waits for it to exit, killing it when the playlists stop updating
+func (e *Encoder) runOnce(restart bool) error {
+	output := strategies.Output{
+		EndList:         e.config.Input.Finite(),
+		ListSize:        e.config.ListSize,
+		PlaylistPath:    e.outputPath + "/%v/playlist.m3u8",
+		SegmentFilename: e.outputPath + "/%v/%04d.m4s", // Deliberate template variable for ffmpeg's use.
+	}
+
+	// On restart ffmpeg picks up the existing playlists, continuing the media sequence
+	// and marking the first new segment with EXT-X-DISCONTINUITY
+	if restart {
+		output.HLSFlags = append(output.HLSFlags, "append_list")
+	}
+
+	strategy, ok := strategyByName[e.config.Strategy]
+	if !ok {
 		strategy = strategies.X264
 	}
 
-	cmd := strategy(encoderPath, streamSource, segmentFilenamePattern, streamOutputPath)
+	cmd := strategy(e.encoderPath, append(append([]string{}, telemetryArgs...), e.config.Input.Args()...), output)
+
+	progress, progressWriter := io.Pipe()
+	logs, logWriter := io.Pipe()
+	defer progressWriter.Close()
+	defer logWriter.Close()
+
+	cmd.Stdout = progressWriter
+	cmd.Stderr = logWriter
+
+	var previous Progress
+	var lastLogged time.Time
+
+	go parseProgress(progress, func(p Progress) {
+		e.recordProgress(p, previous)
+		previous = p
+
+		if time.Since(lastLogged) >= progressLogInterval {
+			lastLogged = time.Now()
+			e.log.Info("Encoder progress", "frame", p.Frame, "fps", p.FPS, "bitrate_kbps", p.Bitrate, "out_time", p.OutTime,
+				"speed", p.Speed, "dup_frames", p.DupFrames, "drop_frames", p.DropFrames)
+		}
+	})
 
-	//
-	// TODO better logging
-	//
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	go parseLog(logs, e.recordMessage)
 
-	err := cmd.Run()
+	e.mu.Lock()
+	if e.isStoppedLocked() {
+		e.mu.Unlock()
+		return errors.New("encoder stopped")
+	}
+
+	err := cmd.Start()
 	if err != nil {
-		fmt.Println("[main] Could not start encoder, err: ", err)
+		e.mu.Unlock()
+		e.log.Error("Could not start encoder", "err", err)
+		return err
+	}
+
+	now := time.Now()
+	e.cmd = cmd
+	e.status.State = StateStarting
+	e.status.PID = cmd.Process.Pid
+	e.status.StartedAt = &now
+	e.status.NextRestartAt = nil
+	e.mu.Unlock()
+
+	exited := make(chan struct{})
+	go e.watch(cmd, now, exited)
+
+	err = cmd.Wait()
+	close(exited)
+
+	return err
+}
+
+func (e *Encoder) recordProgress(p Progress, previous Progress) {
+	e.mu.Lock()
+	e.status.Telemetry.Progress = &p
+	e.mu.Unlock()
+
+	fpsMetric.Set(p.FPS, e.name)
+	speedMetric.Set(p.Speed, e.name)
+	bitrateMetric.Set(p.Bitrate, e.name)
+	outTimeMetric.Set(p.OutTime, e.name)
+
+	// Counters restart with every ffmpeg process
+	if p.DropFrames > previous.DropFrames {
+		dropFramesMetric.Add(float64(p.DropFrames-previous.DropFrames), e.name)
+	}
+
+	if p.DupFrames > previous.DupFrames {
+		dupFramesMetric.Add(float64(p.DupFrames-previous.DupFrames), e.name)
+	}
+}
+
+// recordMessage logs and counts ffmpeg's warnings and errors, routine lines (stream info, opened segments) are only
+// logged at debug level
+func (e *Encoder) recordMessage(message LogMessage) {
+	if !message.isProblem() {
+		e.log.Component("ffmpeg").Debug(message.Text, "ffmpeg_level", message.Level)
+		return
+	}
+
+	e.log.Component("ffmpeg").Log(message.level(), message.Text, "ffmpeg_level", message.Level, "class", message.Class)
+
+	e.mu.Lock()
+	e.status.Telemetry.Messages[message.Class]++
+	e.status.Telemetry.LastWarning = &message
+	e.mu.Unlock()
+
+	messagesMetric.Inc(e.name, message.Level, message.Class)
+}
+
+// watch checks the playlist freshness every second until the process exits
+func (e *Encoder) watch(cmd *exec.Cmd, startedAt time.Time, exited chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	stallTimeout := e.seconds(e.supervision.StallTimeout)
+
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+
+		lastUpdate := e.lastPlaylistUpdate()
+
+		// Until ffmpeg writes its first playlist, the process start is the reference point
+		reference := startedAt
+		if lastUpdate.After(startedAt) {
+			reference = lastUpdate
+		}
+
+		age := time.Since(reference)
+
+		e.mu.Lock()
+		if e.isStoppedLocked() {
+			e.mu.Unlock()
+			return
+		}
+
+		if lastUpdate.After(startedAt) {
+			e.status.LastUpdateAt = &lastUpdate
+			if e.status.State == StateStarting {
+				e.status.State = StateRunning
+				upMetric.Set(1, e.name)
+			}
+		}
+		e.status.PlaylistAge = age.Seconds()
+		playlistAgeMetric.Set(age.Seconds(), e.name)
+
+		stalled := age > stallTimeout && e.status.State != StateStalled
+		if stalled {
+			e.status.State = StateStalled
+			e.status.Stalls++
+		}
+		e.mu.Unlock()
+
+		if stalled {
+			e.log.Warn("Encoder has not updated its playlists, killing", "age", age.Round(time.Second))
+			stallsMetric.Inc(e.name)
+			upMetric.Set(0, e.name)
+			cmd.Process.Kill()
+		}
+	}
+}
+
+// lastPlaylistUpdate returns the newest modification time across the variant playlists
+func (e *Encoder) lastPlaylistUpdate() time.Time {
+	var newest time.Time
+
+	playlists, _ := filepath.Glob(filepath.Join(e.outputPath, "*", "playlist.m3u8"))
+	for _, playlist := range playlists {
+		info, err := os.Stat(playlist)
+		if err != nil {
+			continue
+		}
+
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+
+	return newest
+}
+
+func (e *Encoder) backoff(failures int) time.Duration {
+	delay := e.seconds(e.supervision.BackoffMin)
+	max := e.seconds(e.supervision.BackoffMax)
+
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		delay = max
+	}
+
+	return delay
+}
+
+func (e *Encoder) seconds(value int) time.Duration {
+	return time.Duration(value) * time.Second
+}
+
+// Shutdown ...
+// Asks ffmpeg to finish the current segment and write its playlists, killing it if it doesn't exit before the context is done.
+// When endList is set the playlists are marked complete with EXT-X-ENDLIST.
+func (e *Encoder) Shutdown(ctx context.Context, endList bool) {
+	e.mu.Lock()
+	if e.markStoppedLocked() && e.cmd != nil && e.cmd.Process != nil {
+		err := e.cmd.Process.Signal(os.Interrupt)
+
+		// Interrupts can't be sent to processes on windows
+		if err != nil || runtime.GOOS == "windows" {
+			e.cmd.Process.Kill()
+		}
+	}
+	e.mu.Unlock()
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		e.log.Warn("Encoder did not exit in time, killing")
+		e.kill()
+		<-e.done
+	}
+
+	if endList {
+		e.endPlaylists()
+	}
+}
+
+// kill the running process, if any, regardless of the stopped state
+func (e *Encoder) kill() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cmd != nil && e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+}
+
+// endPlaylists appends EXT-X-ENDLIST to every variant playlist that doesn't have it yet
+func (e *Encoder) endPlaylists() {
+	playlists, _ := filepath.Glob(filepath.Join(e.outputPath, "*", "playlist.m3u8"))
+	for _, playlist := range playlists {
+		raw, err := ioutil.ReadFile(playlist)
+		if err != nil || strings.Contains(string(raw), "#EXT-X-ENDLIST") {
+			continue
+		}
+
+		if len(raw) > 0 && !strings.HasSuffix(string(raw), "\n") {
+			raw = append(raw, '\n')
+		}
+
+		err = ioutil.WriteFile(playlist, append(raw, []byte("#EXT-X-ENDLIST\n")...), 0644)
+		if err != nil {
+			e.log.Error("Could not end playlist", "playlist", playlist, "err", err)
+		}
+	}
+}
+
+func (e *Encoder) setFinished() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	e.cmd = nil
+	e.status.State = StateFinished
+	e.status.PID = 0
+	e.status.LastExitAt = &now
+
+	e.deleteGaugesLocked()
+}
+
+// markStoppedLocked returns false when the encoder was already stopped
+func (e *Encoder) markStoppedLocked() bool {
+	if e.isStoppedLocked() {
+		return false
+	}
+
+	close(e.stop)
+	e.status.State = StateStopped
+	e.status.PID = 0
+	e.status.NextRestartAt = nil
+
+	e.deleteGaugesLocked()
+	return true
+}
+
+func (e *Encoder) deleteGaugesLocked() {
+	for _, gauge := range []*metrics.GaugeVec{upMetric, playlistAgeMetric, fpsMetric, speedMetric, bitrateMetric, outTimeMetric} {
+		gauge.Delete(e.name)
+	}
+}
+
+// Status ...
+func (e *Encoder) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	status := e.status
+	status.Telemetry.Messages = make(map[string]int, len(e.status.Telemetry.Messages))
+	for class, count := range e.status.Telemetry.Messages {
+		status.Telemetry.Messages[class] = count
+	}
+
+	return status
+}
+
+func (e *Encoder) isStopped() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.isStoppedLocked()
+}
+
+func (e *Encoder) isStoppedLocked() bool {
+	select {
+	case <-e.stop:
+		return true
+	default:
+		return false
 	}
 }
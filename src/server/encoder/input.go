@@ -0,0 +1,193 @@
+package encoder
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceKind ...
+type SourceKind string
+
+// Supported input source kinds, derived from the scheme of the input URI
+const (
+	SourceHLS  SourceKind = "hls"
+	SourceFile SourceKind = "file"
+	SourceUDP  SourceKind = "udp"
+	SourceRTMP SourceKind = "rtmp"
+	SourceSRT  SourceKind = "srt"
+)
+
+// ReconnectPolicy ...
+// Only applies to network pull sources (HLS over http/https)
+type ReconnectPolicy struct {
+	Enabled  bool `json:"enabled"`
+	AtEOF    bool `json:"atEof"`
+	Streamed bool `json:"streamed"`
+	DelayMax int  `json:"delayMax"` // Maximum delay between reconnection attempts, in seconds
+}
+
+// Input ...
+type Input struct {
+	URI       string            `json:"uri"`
+	Listen    bool              `json:"listen"`    // RTMP/SRT only, wait for the contribution feed to connect to us
+	Loop      bool              `json:"loop"`      // Files only, loop the file forever to emulate a live source
+	Realtime  *bool             `json:"realtime"`  // Files only, read at native frame rate (defaults to true)
+	Reconnect ReconnectPolicy   `json:"reconnect"` // HLS only
+	Options   map[string]string `json:"options"`   // Any additional ffmpeg input options, e.g. {"fflags": "+genpts"}
+}
+
+// DefaultReconnectPolicy ...
+var DefaultReconnectPolicy = ReconnectPolicy{
+	Enabled:  true,
+	AtEOF:    true,
+	Streamed: true,
+	DelayMax: 3,
+}
+
+// Kind ...
+func (i Input) Kind() SourceKind {
+	u, err := url.Parse(i.URI)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// No scheme (or a windows drive letter) - treat as a local path
+		return SourceFile
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return SourceHLS
+	case "file":
+		return SourceFile
+	case "udp":
+		return SourceUDP
+	case "rtmp":
+		return SourceRTMP
+	case "srt":
+		return SourceSRT
+	}
+
+	return SourceKind(u.Scheme)
+}
+
+// Finite ...
+// Whether the input is expected to end on its own, i.e. a file that isn't looped
+func (i Input) Finite() bool {
+	return i.Kind() == SourceFile && !i.Loop
+}
+
+// Validate ...
+func (i Input) Validate() error {
+	if i.URI == "" {
+		return errors.New("input uri is required")
+	}
+
+	kind := i.Kind()
+	switch kind {
+	case SourceHLS, SourceFile, SourceUDP, SourceRTMP, SourceSRT:
+	default:
+		return fmt.Errorf("unsupported input scheme %q", kind)
+	}
+
+	if i.Listen && kind != SourceRTMP && kind != SourceSRT {
+		return fmt.Errorf("listen is only supported for rtmp and srt inputs, not %q", kind)
+	}
+
+	if i.Loop && kind != SourceFile {
+		return fmt.Errorf("loop is only supported for file inputs, not %q", kind)
+	}
+
+	return nil
+}
+
+// Args ...
+// Builds the ffmpeg input options for this source, ending with `-i <source>`
+func (i Input) Args() []string {
+	args := make([]string, 0)
+	source := i.URI
+
+	switch i.Kind() {
+	case SourceHLS:
+		if i.Reconnect.Enabled {
+			args = append(args, "-reconnect", "1")
+
+			if i.Reconnect.AtEOF {
+				args = append(args, "-reconnect_at_eof", "1")
+			}
+
+			if i.Reconnect.Streamed {
+				args = append(args, "-reconnect_streamed", "1")
+			}
+
+			if i.Reconnect.DelayMax > 0 {
+				args = append(args, "-reconnect_delay_max", strconv.Itoa(i.Reconnect.DelayMax))
+			}
+		}
+
+	case SourceFile:
+		source = strings.TrimPrefix(source, "file://")
+		source = filepath.FromSlash(source)
+
+		if i.Realtime == nil || *i.Realtime {
+			args = append(args, "-re")
+		}
+
+		if i.Loop {
+			args = append(args, "-stream_loop", "-1")
+		}
+
+	case SourceUDP:
+		// Contribution feeds over UDP are MPEG-TS, don't let a slow reader kill the input
+		source = withQuery(source, map[string]string{
+			"overrun_nonfatal": "1",
+			"fifo_size":        "50000000",
+		})
+		args = append(args, "-f", "mpegts")
+
+	case SourceRTMP:
+		if i.Listen {
+			args = append(args, "-listen", "1")
+		}
+		args = append(args, "-f", "flv")
+
+	case SourceSRT:
+		if i.Listen {
+			source = withQuery(source, map[string]string{"mode": "listener"})
+		}
+		args = append(args, "-f", "mpegts")
+	}
+
+	// Sorting for a stable argument order
+	keys := make([]string, 0, len(i.Options))
+	for key := range i.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		args = append(args, "-"+strings.TrimPrefix(key, "-"), i.Options[key])
+	}
+
+	return append(args, "-i", source)
+}
+
+// withQuery adds the given query parameters to the uri, unless they have been provided already
+func withQuery(uri string, params map[string]string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := u.Query()
+	for key, value := range params {
+		if query.Get(key) == "" {
+			query.Set(key, value)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,128 @@
+package encoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInputArgs(t *testing.T) {
+	realtime := false
+
+	tests := []struct {
+		name  string
+		input Input
+		kind  SourceKind
+		want  []string
+	}{
+		{
+			name:  "hls",
+			input: Input{URI: "https://example.com/live.m3u8", Reconnect: DefaultReconnectPolicy},
+			kind:  SourceHLS,
+			want: []string{"-reconnect", "1", "-reconnect_at_eof", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "3",
+				"-i", "https://example.com/live.m3u8"},
+		},
+		{
+			name:  "hls without reconnecting",
+			input: Input{URI: "http://example.com/live.m3u8", Reconnect: ReconnectPolicy{Enabled: false, AtEOF: true}},
+			kind:  SourceHLS,
+			want:  []string{"-i", "http://example.com/live.m3u8"},
+		},
+		{
+			name:  "file",
+			input: Input{URI: "media/news.mp4"},
+			kind:  SourceFile,
+			want:  []string{"-re", "-i", "media/news.mp4"},
+		},
+		{
+			name:  "looped file url",
+			input: Input{URI: "file:///media/news.mp4", Loop: true},
+			kind:  SourceFile,
+			want:  []string{"-re", "-stream_loop", "-1", "-i", "/media/news.mp4"},
+		},
+		{
+			name:  "file not in realtime",
+			input: Input{URI: "news.mp4", Realtime: &realtime},
+			kind:  SourceFile,
+			want:  []string{"-i", "news.mp4"},
+		},
+		{
+			name:  "udp",
+			input: Input{URI: "udp://239.0.0.1:1234"},
+			kind:  SourceUDP,
+			want:  []string{"-f", "mpegts", "-i", "udp://239.0.0.1:1234?fifo_size=50000000&overrun_nonfatal=1"},
+		},
+		{
+			name:  "udp keeps provided parameters",
+			input: Input{URI: "udp://239.0.0.1:1234?fifo_size=1000"},
+			kind:  SourceUDP,
+			want:  []string{"-f", "mpegts", "-i", "udp://239.0.0.1:1234?fifo_size=1000&overrun_nonfatal=1"},
+		},
+		{
+			name:  "rtmp",
+			input: Input{URI: "rtmp://example.com/live/news"},
+			kind:  SourceRTMP,
+			want:  []string{"-f", "flv", "-i", "rtmp://example.com/live/news"},
+		},
+		{
+			name:  "rtmp listening",
+			input: Input{URI: "rtmp://0.0.0.0:1935/live/news", Listen: true},
+			kind:  SourceRTMP,
+			want:  []string{"-listen", "1", "-f", "flv", "-i", "rtmp://0.0.0.0:1935/live/news"},
+		},
+		{
+			name:  "srt",
+			input: Input{URI: "srt://example.com:9000"},
+			kind:  SourceSRT,
+			want:  []string{"-f", "mpegts", "-i", "srt://example.com:9000"},
+		},
+		{
+			name:  "srt listening",
+			input: Input{URI: "srt://0.0.0.0:9000", Listen: true},
+			kind:  SourceSRT,
+			want:  []string{"-f", "mpegts", "-i", "srt://0.0.0.0:9000?mode=listener"},
+		},
+		{
+			name:  "options sorted after the source's",
+			input: Input{URI: "rtmp://example.com/live/news", Options: map[string]string{"rw_timeout": "5000000", "-fflags": "+genpts"}},
+			kind:  SourceRTMP,
+			want:  []string{"-f", "flv", "-fflags", "+genpts", "-rw_timeout", "5000000", "-i", "rtmp://example.com/live/news"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.input.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			if kind := test.input.Kind(); kind != test.kind {
+				t.Errorf("kind = %s, want %s", kind, test.kind)
+			}
+
+			if got := test.input.Args(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("args = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input Input
+		valid bool
+	}{
+		{"no uri", Input{}, false},
+		{"windows path", Input{URI: `C:\media\news.mp4`}, true},
+		{"unsupported scheme", Input{URI: "ftp://example.com/news.ts"}, false},
+		{"listening udp", Input{URI: "udp://239.0.0.1:1234", Listen: true}, false},
+		{"looped hls", Input{URI: "https://example.com/live.m3u8", Loop: true}, false},
+		{"looped file", Input{URI: "news.mp4", Loop: true}, true},
+	}
+
+	for _, test := range tests {
+		if err := test.input.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: err = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
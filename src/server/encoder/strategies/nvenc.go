@@ -1,17 +1,17 @@
 package strategies
 
-import "os/exec"
+import (
+	"os/exec"
+	"strconv"
+)
 
 // NVENC ...
-func NVENC(ffmpeg, source, segmentFilename, outputPath string) *exec.Cmd {
+func NVENC(ffmpeg string, input []string, output Output) *exec.Cmd {
 
-	cmd := exec.Command(
-		ffmpeg,
+	args := []string{
 		// "-loglevel", "debug",
 		"-ignore_unknown",
 
-		"-i", source,
-
 		"-vcodec", "h264_nvenc",
 		"-preset", "slow",
 		"-profile:v", "high",
@@ -28,10 +28,10 @@ func NVENC(ffmpeg, source, segmentFilename, outputPath string) *exec.Cmd {
 		"-segment_list_flags", "live",
 
 		"-hls_segment_type", "fmp4",
-		"-hls_time", "10",
-		"-hls_list_size", "10",
-		"-hls_flags", "delete_segments+omit_endlist",
-		"-hls_segment_filename", segmentFilename,
+		"-hls_time", strconv.Itoa(SegmentDuration),
+		"-hls_list_size", output.listSize(),
+		"-hls_flags", output.hlsFlags(),
+		"-hls_segment_filename", output.SegmentFilename,
 
 		"-b:v:0", "1500k",
 		"-s:v:0", "896x504",
@@ -48,12 +48,14 @@ func NVENC(ffmpeg, source, segmentFilename, outputPath string) *exec.Cmd {
 		"-master_pl_name", "master.m3u8",
 		"-master_pl_publish_rate", "1",
 		"-hide_banner",
-		"-reconnect_at_eof",
-		"-reconnect_streamed",
-		"-reconnect_delay_max", "3",
 
-		outputPath,
-	)
+		output.PlaylistPath,
+	}
+
+	// Input options must come before any of the output options
+	args = append(append([]string{}, input...), args...)
+
+	cmd := exec.Command(ffmpeg, args...)
 
 	return cmd
 
@@ -0,0 +1,38 @@
+package strategies
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SegmentDuration ...
+// Target duration of the media segments (hls_time), in seconds
+const SegmentDuration = 10
+
+// Output ...
+type Output struct {
+	SegmentFilename string   // ffmpeg segment filename template, e.g. /_tmp/%v/%04d.m4s
+	PlaylistPath    string   // ffmpeg variant playlist template, e.g. /_tmp/%v/playlist.m3u8
+	HLSFlags        []string // Added to the default hls_flags, e.g. append_list
+	EndList         bool     // Let ffmpeg write EXT-X-ENDLIST once the input ends, for finite inputs
+	ListSize        int      // Segments kept in the playlists (and on disk), i.e. the DVR window
+}
+
+// listSize ...
+func (o Output) listSize() string {
+	if o.ListSize <= 0 {
+		return "10"
+	}
+
+	return strconv.Itoa(o.ListSize)
+}
+
+// hlsFlags ...
+func (o Output) hlsFlags() string {
+	flags := []string{"delete_segments", "program_date_time"}
+	if !o.EndList {
+		flags = append(flags, "omit_endlist")
+	}
+
+	return strings.Join(append(flags, o.HLSFlags...), "+")
+}
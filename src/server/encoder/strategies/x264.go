@@ -1,13 +1,14 @@
 package strategies
 
-import "os/exec"
+import (
+	"os/exec"
+	"strconv"
+)
 
 // X264 ...
-func X264(ffmpeg, source, segmentFilename, outputPath string) *exec.Cmd {
+func X264(ffmpeg string, input []string, output Output) *exec.Cmd {
 
-	cmd := exec.Command(
-		ffmpeg,
-		"-i", source,
+	args := []string{
 		"-ignore_unknown",
 		"-acodec", "aac",
 		"-ar", "44100",
@@ -23,10 +24,10 @@ func X264(ffmpeg, source, segmentFilename, outputPath string) *exec.Cmd {
 		"-preset", "veryfast",
 		"-bsf:a", "aac_adtstoasc",
 		"-hls_segment_type", "fmp4",
-		"-hls_time", "10",
-		"-hls_list_size", "10",
-		"-hls_flags", "delete_segments+omit_endlist",
-		"-hls_segment_filename", segmentFilename,
+		"-hls_time", strconv.Itoa(SegmentDuration),
+		"-hls_list_size", output.listSize(),
+		"-hls_flags", output.hlsFlags(),
+		"-hls_segment_filename", output.SegmentFilename,
 
 		"-b:v:0", "1000k",
 		"-s:v:0", "426x240",
@@ -48,12 +49,14 @@ func X264(ffmpeg, source, segmentFilename, outputPath string) *exec.Cmd {
 		"-master_pl_name", "master.m3u8",
 		"-master_pl_publish_rate", "1",
 		"-hide_banner",
-		"-reconnect_at_eof",
-		"-reconnect_streamed",
-		"-reconnect_delay_max", "3",
 
-		outputPath,
-	)
+		output.PlaylistPath,
+	}
+
+	// Input options must come before any of the output options
+	args = append(append([]string{}, input...), args...)
+
+	cmd := exec.Command(ffmpeg, args...)
 
 	return cmd
 
@@ -0,0 +1,193 @@
+package encoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"server/logging"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telemetryArgs makes ffmpeg write machine readable progress to stdout, and prefix its log lines with their level
+var telemetryArgs = []string{
+	"-nostats",
+	"-progress", "pipe:1",
+	"-loglevel", "level+info",
+}
+
+// Progress ...
+// The latest progress block reported by ffmpeg (`-progress`), counters are for the current process only
+type Progress struct {
+	Frame      int64     `json:"frame"`
+	FPS        float64   `json:"fps"`
+	Bitrate    float64   `json:"bitrateKbps"`
+	TotalSize  int64     `json:"totalSize"`
+	OutTime    float64   `json:"outTimeSeconds"`
+	DupFrames  int64     `json:"dupFrames"`
+	DropFrames int64     `json:"dropFrames"`
+	Speed      float64   `json:"speed"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// LogMessage ...
+type LogMessage struct {
+	Level string    `json:"level"`
+	Class string    `json:"class"`
+	Text  string    `json:"text"`
+	At    time.Time `json:"at"`
+}
+
+// Telemetry ...
+type Telemetry struct {
+	Progress    *Progress      `json:"progress,omitempty"`
+	Messages    map[string]int `json:"messages"` // Number of warnings/errors by class, for the encoder's lifetime
+	LastWarning *LogMessage    `json:"lastWarning,omitempty"`
+}
+
+// Classes of ffmpeg log messages worth tracking, first match wins
+var messageClasses = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	{"non_monotonic_dts", regexp.MustCompile(`(?i)non[- ]monoton(ous|ically increasing) dts`)},
+	{"reconnect", regexp.MustCompile(`(?i)will reconnect|reconnect(ing)? at`)},
+	{"http_error", regexp.MustCompile(`(?i)http error|server returned [45]\d\d`)},
+	{"connection", regexp.MustCompile(`(?i)connection (timed out|refused|reset)|i/o error|end of file`)},
+	{"timestamps", regexp.MustCompile(`(?i)past duration .* too large|timestamps are unset|invalid (pts|dts)|discontinuity detected`)},
+	{"decode_error", regexp.MustCompile(`(?i)error while decoding|invalid data found|corrupt`)},
+	{"dropped_input", regexp.MustCompile(`(?i)queue input is backward|thread message queue blocking|buffer (queue )?overflow|circular buffer overrun`)},
+}
+
+var levelPrefix = regexp.MustCompile(`\[(panic|fatal|error|warning|info|verbose|debug|trace)\] ?`)
+
+// parseProgress reads ffmpeg `-progress` key=value blocks, each terminated by a `progress=` line
+func parseProgress(r io.Reader, onBlock func(Progress)) {
+	scanner := bufio.NewScanner(r)
+	current := Progress{}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key, value := parts[0], strings.TrimSpace(parts[1])
+
+		switch key {
+		case "frame":
+			current.Frame = parseInt(value)
+		case "fps":
+			current.FPS = parseFloat(value)
+		case "bitrate":
+			current.Bitrate = parseFloat(strings.TrimSuffix(value, "kbits/s"))
+		case "total_size":
+			current.TotalSize = parseInt(value)
+		case "out_time_us":
+			current.OutTime = float64(parseInt(value)) / 1e6
+		case "dup_frames":
+			current.DupFrames = parseInt(value)
+		case "drop_frames":
+			current.DropFrames = parseInt(value)
+		case "speed":
+			current.Speed = parseFloat(strings.TrimSuffix(value, "x"))
+		case "progress":
+			current.UpdatedAt = time.Now()
+			onBlock(current)
+		}
+	}
+}
+
+// parseLog reads ffmpeg's stderr, classifying every warning and error
+func parseLog(r io.Reader, onMessage func(LogMessage)) {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		onMessage(classify(line))
+	}
+}
+
+func classify(line string) LogMessage {
+	message := LogMessage{
+		Level: "info",
+		Class: "other",
+		Text:  strings.TrimSpace(line),
+		At:    time.Now(),
+	}
+
+	match := levelPrefix.FindStringSubmatch(line)
+	if match != nil {
+		message.Level = match[1]
+		message.Text = strings.TrimSpace(strings.Replace(line, match[0], "", 1))
+	}
+
+	for _, c := range messageClasses {
+		if c.pattern.MatchString(message.Text) {
+			message.Class = c.class
+			break
+		}
+	}
+
+	return message
+}
+
+// level maps ffmpeg's log level onto ours, classified problems ffmpeg reports at a lower level are warnings
+func (m LogMessage) level() logging.Level {
+	switch m.Level {
+	case "panic", "fatal", "error":
+		return logging.Error
+	case "warning":
+		return logging.Warn
+	}
+
+	if m.Class != "other" {
+		return logging.Warn
+	}
+
+	return logging.Debug
+}
+
+// isProblem ...
+func (m LogMessage) isProblem() bool {
+	switch m.Level {
+	case "panic", "fatal", "error", "warning":
+		return true
+	}
+
+	// Reconnects are logged at info level by the http protocol
+	return m.Class != "other"
+}
+
+// String ...
+// logfmt representation of a progress block
+func (p Progress) String() string {
+	return fmt.Sprintf("frame=%d fps=%.2f bitrate_kbps=%.1f out_time=%.3f speed=%.3f dup_frames=%d drop_frames=%d",
+		p.Frame, p.FPS, p.Bitrate, p.OutTime, p.Speed, p.DupFrames, p.DropFrames)
+}
+
+func parseInt(value string) int64 {
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0 // ffmpeg reports N/A until the value is known
+	}
+
+	return result
+}
+
+func parseFloat(value string) float64 {
+	result, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+
+	return result
+}
@@ -0,0 +1,136 @@
+package encoder
+
+import (
+	"server/logging"
+	"strings"
+	"testing"
+)
+
+func TestParseProgress(t *testing.T) {
+	output := `frame=120
+fps=25.00
+stream_0_0_q=28.0
+bitrate=1834.2kbits/s
+total_size=1048576
+out_time_us=4800000
+out_time=00:00:04.800000
+dup_frames=1
+drop_frames=0
+speed=1.01x
+progress=continue
+frame=245
+fps=24.90
+bitrate=N/A
+total_size=N/A
+out_time_us=9800000
+dup_frames=1
+drop_frames=3
+speed= 0.98x
+progress=end
+frame=300
+`
+
+	blocks := make([]Progress, 0)
+	parseProgress(strings.NewReader(output), func(p Progress) {
+		blocks = append(blocks, p)
+	})
+
+	// The trailing lines without a progress line aren't a block yet
+	if len(blocks) != 2 {
+		t.Fatalf("%d blocks, want 2", len(blocks))
+	}
+
+	tests := []struct {
+		got, want Progress
+	}{
+		{blocks[0], Progress{Frame: 120, FPS: 25, Bitrate: 1834.2, TotalSize: 1048576, OutTime: 4.8, DupFrames: 1, Speed: 1.01}},
+		{blocks[1], Progress{Frame: 245, FPS: 24.9, OutTime: 9.8, DupFrames: 1, DropFrames: 3, Speed: 0.98}},
+	}
+
+	for i, test := range tests {
+		if test.got.UpdatedAt.IsZero() {
+			t.Errorf("block %d has no update time", i)
+		}
+
+		test.got.UpdatedAt = test.want.UpdatedAt
+		if test.got != test.want {
+			t.Errorf("block %d = %+v, want %+v", i, test.got, test.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		line    string
+		level   string
+		class   string
+		text    string
+		logged  logging.Level
+		problem bool
+	}{
+		{
+			line:  "[mpegts @ 0x55d] [warning] Non-monotonous DTS in output stream 0:1; previous: 100, current: 90",
+			level: "warning", class: "non_monotonic_dts", text: "[mpegts @ 0x55d] Non-monotonous DTS in output stream 0:1; previous: 100, current: 90",
+			logged: logging.Warn, problem: true,
+		},
+		{
+			line:  "[http @ 0x55d] [info] Will reconnect at 1234 in 1 second(s), error=End of file.",
+			level: "info", class: "reconnect", text: "[http @ 0x55d] Will reconnect at 1234 in 1 second(s), error=End of file.",
+			logged: logging.Warn, problem: true,
+		},
+		{
+			line:  "[https @ 0x55d] [error] HTTP error 404 Not Found",
+			level: "error", class: "http_error", text: "[https @ 0x55d] HTTP error 404 Not Found",
+			logged: logging.Error, problem: true,
+		},
+		{
+			line:  "[tcp @ 0x55d] [error] Connection refused",
+			level: "error", class: "connection", text: "[tcp @ 0x55d] Connection refused",
+			logged: logging.Error, problem: true,
+		},
+		{
+			line:  "[fatal] Invalid data found when processing input",
+			level: "fatal", class: "decode_error", text: "Invalid data found when processing input",
+			logged: logging.Error, problem: true,
+		},
+		{
+			line:  "[warning] Thread message queue blocking; consider raising the thread_queue_size option",
+			level: "warning", class: "dropped_input", text: "Thread message queue blocking; consider raising the thread_queue_size option",
+			logged: logging.Warn, problem: true,
+		},
+		{
+			line:  "[hls @ 0x55d] [info] Opening 'segment-12.ts' for writing",
+			level: "info", class: "other", text: "[hls @ 0x55d] Opening 'segment-12.ts' for writing",
+			logged: logging.Debug, problem: false,
+		},
+		{
+			line:  "  Stream #0:0: Video: h264 ",
+			level: "info", class: "other", text: "Stream #0:0: Video: h264",
+			logged: logging.Debug, problem: false,
+		},
+	}
+
+	for _, test := range tests {
+		message := classify(test.line)
+		if message.Level != test.level || message.Class != test.class || message.Text != test.text {
+			t.Errorf("classify(%q) = %s %s %q, want %s %s %q", test.line, message.Level, message.Class, message.Text, test.level, test.class, test.text)
+		}
+
+		if message.level() != test.logged || message.isProblem() != test.problem {
+			t.Errorf("%q logged at %s, problem %v, want %s, %v", test.line, message.level(), message.isProblem(), test.logged, test.problem)
+		}
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	output := "[info] Input #0, hls\n\n   \n[warning] Discontinuity detected\n[error] Connection timed out\n"
+
+	classes := make([]string, 0)
+	parseLog(strings.NewReader(output), func(m LogMessage) {
+		classes = append(classes, m.Class)
+	})
+
+	if strings.Join(classes, " ") != "other timestamps connection" {
+		t.Errorf("classes = %v, want other, timestamps and connection, blank lines skipped", classes)
+	}
+}
@@ -0,0 +1,45 @@
+package encoder
+
+import "time"
+
+// State ...
+type State string
+
+// Encoder process states
+const (
+	StateStarting State = "starting" // ffmpeg launched, waiting on the first playlist update
+	StateRunning  State = "running"
+	StateStalled  State = "stalled" // playlists stopped updating, the process is being killed
+	StateBackoff  State = "backoff" // waiting before the next restart
+	StateStopped  State = "stopped"
+	StateFinished State = "finished" // a finite input reached its end, the playlists are complete
+)
+
+// Supervision ...
+type Supervision struct {
+	StallTimeout int `json:"stallTimeout"` // Seconds without a playlist update before ffmpeg is considered stalled and restarted
+	BackoffMin   int `json:"backoffMin"`   // Seconds to wait before the first restart, doubled for every consecutive failure
+	BackoffMax   int `json:"backoffMax"`   // Upper limit for the restart delay, in seconds
+}
+
+// DefaultSupervision ...
+var DefaultSupervision = Supervision{
+	StallTimeout: 30,
+	BackoffMin:   1,
+	BackoffMax:   30,
+}
+
+// Status ...
+type Status struct {
+	State         State      `json:"state"`
+	PID           int        `json:"pid,omitempty"`
+	Restarts      int        `json:"restarts"`
+	Stalls        int        `json:"stalls"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	LastUpdateAt  *time.Time `json:"lastPlaylistUpdateAt,omitempty"`
+	PlaylistAge   float64    `json:"playlistAgeSeconds"`
+	LastExitAt    *time.Time `json:"lastExitAt,omitempty"`
+	LastExitError string     `json:"lastExitError,omitempty"`
+	NextRestartAt *time.Time `json:"nextRestartAt,omitempty"`
+	Telemetry     Telemetry  `json:"telemetry"`
+}
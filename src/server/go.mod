@@ -5,5 +5,9 @@ go 1.15
 require (
 	cloud.google.com/go v0.37.4
 	github.com/fsnotify/fsnotify v1.4.7 // indirect
+	github.com/golang/protobuf v1.2.0
+	go.opencensus.io v0.20.1
+	google.golang.org/api v0.3.1
 	google.golang.org/genproto v0.0.0-20190404172233-64821d5d2107
+	google.golang.org/grpc v1.19.0
 )
@@ -0,0 +1,237 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"server/api"
+	"server/channels"
+	"server/encoder"
+	"server/transcriber"
+	"server/transcriber/recognizers/failover"
+	"time"
+)
+
+// Paths
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+	StatusPath    = "/status"
+)
+
+// Config ...
+type Config struct {
+	MaxCaptionLag float64 `json:"maxCaptionLag"` // Seconds a segment may wait for its captions before the channel isn't ready
+	MaxSegmentAge float64 `json:"maxSegmentAge"` // Seconds without a new segment from a running encoder before the channel isn't ready
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		MaxCaptionLag: 30,
+		MaxSegmentAge: 30,
+	}
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	if c.MaxCaptionLag <= 0 || c.MaxSegmentAge <= 0 {
+		return fmt.Errorf("health thresholds must be positive, got maxCaptionLag %v and maxSegmentAge %v", c.MaxCaptionLag, c.MaxSegmentAge)
+	}
+
+	return nil
+}
+
+// Report ...
+type Report struct {
+	Ready    bool            `json:"ready"`
+	Time     time.Time       `json:"time"`
+	Channels []ChannelReport `json:"channels"`
+}
+
+// ChannelReport ...
+type ChannelReport struct {
+	Name     string   `json:"name"`
+	Ready    bool     `json:"ready"`
+	Reasons  []string `json:"reasons,omitempty"` // Why the channel isn't ready
+	Running  bool     `json:"running"`
+	Encoder  Encoder  `json:"encoder"`
+	Captions Captions `json:"captions"`
+	// Health of the recognizers of a failover chain, in order
+	Recognizers []failover.Status `json:"recognizers,omitempty"`
+	// The segments of the live window and where each one is in the pipeline
+	Window []transcriber.WindowSegment `json:"window"`
+}
+
+// Encoder ...
+type Encoder struct {
+	State    encoder.State `json:"state"`
+	PID      int           `json:"pid,omitempty"`
+	Restarts int           `json:"restarts"`
+}
+
+// Captions ...
+type Captions struct {
+	NewestSegmentAge    *float64 `json:"newestSegmentAge"`    // Seconds, nil before the first segment
+	NewestTranscriptAge *float64 `json:"newestTranscriptAge"` // Seconds, nil before the first captions
+	Lag                 float64  `json:"lag"`                 // Seconds the oldest segment without captions has been waiting
+	QueueDepth          int      `json:"queueDepth"`          // Listed segments without captions yet
+	RecognizerAvailable bool     `json:"recognizerAvailable"`
+	Paused              bool     `json:"paused"`   // The channel's recognition budget is exhausted
+	Degraded            []string `json:"degraded"` // Latency degradation modes applied to catch up with the live edge
+}
+
+// Checker ...
+// Tells whether the channels are producing captions
+type Checker struct {
+	config  Config
+	manager *channels.Manager
+}
+
+// New ...
+func New(config Config, manager *channels.Manager) *Checker {
+	return &Checker{
+		config:  config,
+		manager: manager,
+	}
+}
+
+// Report ...
+// Every channel's report, or only the named one's
+func (c *Checker) Report(channel string) Report {
+	report := Report{
+		Ready:    true,
+		Time:     time.Now().UTC(),
+		Channels: make([]ChannelReport, 0),
+	}
+
+	for _, status := range c.manager.List() {
+		if channel != "" && status.Name != channel {
+			continue
+		}
+
+		r := c.check(status)
+		report.Ready = report.Ready && r.Ready
+		report.Channels = append(report.Channels, r)
+	}
+
+	return report
+}
+
+func (c *Checker) check(status channels.Status) ChannelReport {
+	report := ChannelReport{
+		Name:    status.Name,
+		Running: status.Running,
+		Encoder: Encoder{
+			State:    status.Encoder.State,
+			PID:      status.Encoder.PID,
+			Restarts: status.Encoder.Restarts,
+		},
+		Recognizers: status.Recognizers,
+		Window:      []transcriber.WindowSegment{},
+	}
+
+	if progress := status.Transcription; progress != nil {
+		report.Captions = Captions{
+			NewestSegmentAge:    progress.NewestSegmentAge,
+			NewestTranscriptAge: progress.NewestTranscriptAge,
+			Lag:                 progress.CaptionLag,
+			QueueDepth:          progress.Pending,
+			RecognizerAvailable: progress.RecognizerAvailable,
+			Paused:              progress.Paused,
+			Degraded:            progress.Degraded,
+		}
+		report.Window = progress.Window
+	}
+
+	if !status.Running {
+		report.Reasons = append(report.Reasons, "channel is stopped")
+	}
+
+	switch status.Encoder.State {
+	case encoder.StateRunning:
+		age := report.Captions.NewestSegmentAge
+		if age != nil && *age > c.config.MaxSegmentAge {
+			report.Reasons = append(report.Reasons, fmt.Sprintf("no new segment for %.0fs", *age))
+		}
+	case encoder.StateFinished:
+	default:
+		report.Reasons = append(report.Reasons, fmt.Sprintf("encoder is %s", status.Encoder.State))
+	}
+
+	if status.Running {
+		switch {
+		case report.Captions.Paused:
+			report.Reasons = append(report.Reasons, "recognition is paused, the budget is exhausted")
+		case !report.Captions.RecognizerAvailable:
+			report.Reasons = append(report.Reasons, "recognizer is unavailable")
+		}
+	}
+
+	if report.Captions.Lag > c.config.MaxCaptionLag {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("captions are %.0fs behind", report.Captions.Lag))
+	}
+
+	report.Ready = len(report.Reasons) == 0
+	return report
+}
+
+// LivenessHandler ...
+//
+// GET /healthz  - 200 as long as the server is serving requests
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// ReadinessHandler ...
+//
+// GET /readyz[?channel=<name>]  - 200 when every channel, or the named one, is producing captions in time, 503 otherwise
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		channel := r.URL.Query().Get("channel")
+		if channel != "" && c.manager.Get(channel) == nil {
+			api.WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channel))
+			return
+		}
+
+		report := c.Report(channel)
+
+		type readiness struct {
+			Name    string   `json:"name"`
+			Ready   bool     `json:"ready"`
+			Reasons []string `json:"reasons,omitempty"`
+		}
+
+		body := struct {
+			Ready    bool        `json:"ready"`
+			Channels []readiness `json:"channels"`
+		}{Ready: report.Ready, Channels: make([]readiness, 0, len(report.Channels))}
+
+		for _, r := range report.Channels {
+			body.Channels = append(body.Channels, readiness{Name: r.Name, Ready: r.Ready, Reasons: r.Reasons})
+		}
+
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, status, body)
+	})
+}
+
+// StatusHandler ...
+//
+// GET /status[?channel=<name>]  - the full report of every channel, or the named one
+func (c *Checker) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Report(r.URL.Query().Get("channel")))
+	})
+}
+
+// writeJSON keeps probes and dashboards from being served a cached report
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Cache-Control", "no-cache")
+	api.WriteJSON(w, status, body)
+}
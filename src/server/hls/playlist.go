@@ -0,0 +1,113 @@
+package hls
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment ...
+type Segment struct {
+	URI             string
+	Sequence        int
+	Duration        float64
+	Discontinuity   bool
+	ProgramDateTime *time.Time
+}
+
+// MediaPlaylist ...
+type MediaPlaylist struct {
+	TargetDuration int
+	MediaSequence  int
+	Map            string // URI of the EXT-X-MAP init segment
+	Segments       []Segment
+	EndList        bool
+}
+
+// ParseMediaPlaylist ...
+// Only the tags needed to follow a live playlist are understood, anything else is ignored
+func ParseMediaPlaylist(r io.Reader) (MediaPlaylist, error) {
+	playlist := MediaPlaylist{Segments: make([]Segment, 0)}
+	scanner := bufio.NewScanner(r)
+
+	next := Segment{}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			playlist.TargetDuration, _ = strconv.Atoi(value(line))
+
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			playlist.MediaSequence, _ = strconv.Atoi(value(line))
+
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			playlist.Map = attribute(value(line), "URI")
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			duration := strings.SplitN(value(line), ",", 2)[0]
+			next.Duration, _ = strconv.ParseFloat(duration, 64)
+
+		case line == "#EXT-X-DISCONTINUITY":
+			next.Discontinuity = true
+
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			at, err := time.Parse(time.RFC3339Nano, value(line))
+			if err != nil {
+				// ffmpeg writes the offset without a colon, e.g. +0000
+				at, err = time.Parse("2006-01-02T15:04:05.999999999-0700", value(line))
+			}
+			if err == nil {
+				next.ProgramDateTime = &at
+			}
+
+		case line == "#EXT-X-ENDLIST":
+			playlist.EndList = true
+
+		case strings.HasPrefix(line, "#"):
+			continue
+
+		default:
+			next.URI = line
+			next.Sequence = playlist.MediaSequence + len(playlist.Segments)
+			playlist.Segments = append(playlist.Segments, next)
+			next = Segment{}
+		}
+	}
+
+	return playlist, scanner.Err()
+}
+
+// ReadMediaPlaylist ...
+func ReadMediaPlaylist(path string) (MediaPlaylist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return MediaPlaylist{}, err
+	}
+	defer file.Close()
+
+	return ParseMediaPlaylist(file)
+}
+
+func value(line string) string {
+	return strings.SplitN(line, ":", 2)[1]
+}
+
+// attribute reads a single attribute from an attribute list, e.g. URI="init.mp4"
+func attribute(list string, name string) string {
+	for _, pair := range strings.Split(list, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == name {
+			return strings.Trim(parts[1], `"`)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/api"
+)
+
+// APIPrefix ...
+const APIPrefix = "/api/logging"
+
+// APIHandler ...
+//
+// GET /api/logging  - the levels and format in effect
+// PUT /api/logging  - changes them, body is a Config, the level and format are kept when not set
+func APIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			api.WriteJSON(w, http.StatusOK, Current())
+
+		case http.MethodPut:
+			config := Current()
+			config.Components = nil
+
+			err := json.NewDecoder(r.Body).Decode(&config)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid logging config: %v", err))
+				return
+			}
+
+			err = Configure(config)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			New("logging").Info("Logging reconfigured", "level", config.Level, "format", config.Format)
+			api.WriteJSON(w, http.StatusOK, Current())
+
+		default:
+			api.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level ...
+type Level int
+
+// Levels, in increasing severity
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+
+	return levelNames[l]
+}
+
+// ParseLevel ...
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+
+	if strings.EqualFold(name, "warning") {
+		return Warn, nil
+	}
+
+	return Info, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+}
+
+// Formats
+const (
+	Logfmt = "logfmt"
+	JSON   = "json"
+)
+
+// Config ...
+type Config struct {
+	Level  string `json:"level"`  // debug, info, warn or error
+	Format string `json:"format"` // logfmt or json
+	// Levels overriding the default for some components, e.g. {"recognizers": "debug"}
+	Components map[string]string `json:"components,omitempty"`
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Level:  "info",
+		Format: Logfmt,
+	}
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	_, err := ParseLevel(c.Level)
+	if err != nil {
+		return err
+	}
+
+	if c.Format != Logfmt && c.Format != JSON {
+		return fmt.Errorf("unknown log format %q, expected %s or %s", c.Format, Logfmt, JSON)
+	}
+
+	for component, level := range c.Components {
+		_, err := ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("component %s: %v", component, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mu         sync.RWMutex
+	level                = Info
+	components           = make(map[string]Level)
+	format               = Logfmt
+	output     io.Writer = os.Stdout
+
+	writeMu sync.Mutex
+)
+
+// Configure ...
+// Applies the levels and format, taking effect immediately for every logger
+func Configure(config Config) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	defaultLevel, _ := ParseLevel(config.Level)
+	overrides := make(map[string]Level, len(config.Components))
+	for component, name := range config.Components {
+		overrides[component], _ = ParseLevel(name)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	level = defaultLevel
+	components = overrides
+	format = config.Format
+	return nil
+}
+
+// Current ...
+// The levels and format in effect
+func Current() Config {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	config := Config{
+		Level:      level.String(),
+		Format:     format,
+		Components: make(map[string]string, len(components)),
+	}
+
+	for component, l := range components {
+		config.Components[component] = l.String()
+	}
+
+	return config
+}
+
+// SetOutput ...
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	output = w
+}
+
+// NewID ...
+// A random identifier correlating the log lines of e.g. one segment
+func NewID() string {
+	raw := make([]byte, 6)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(raw)
+}
+
+// Logger ...
+// Writes leveled lines for a component, each carrying the logger's fields
+type Logger struct {
+	component string
+	fields    []interface{} // Alternating keys and values
+}
+
+// New ...
+func New(component string, keyValues ...interface{}) *Logger {
+	return &Logger{component: component, fields: keyValues}
+}
+
+// With ...
+// A logger adding the given keys and values to every line
+func (l *Logger) With(keyValues ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyValues))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyValues...)
+
+	return &Logger{component: l.component, fields: fields}
+}
+
+// Component ...
+// The same logger, fields included, writing for another component
+func (l *Logger) Component(component string) *Logger {
+	return &Logger{component: component, fields: l.fields}
+}
+
+// Enabled ...
+func (l *Logger) Enabled(at Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	min, ok := components[l.component]
+	if !ok {
+		min = level
+	}
+
+	return at >= min
+}
+
+// Debug ...
+func (l *Logger) Debug(msg string, keyValues ...interface{}) {
+	l.Log(Debug, msg, keyValues...)
+}
+
+// Info ...
+func (l *Logger) Info(msg string, keyValues ...interface{}) {
+	l.Log(Info, msg, keyValues...)
+}
+
+// Warn ...
+func (l *Logger) Warn(msg string, keyValues ...interface{}) {
+	l.Log(Warn, msg, keyValues...)
+}
+
+// Error ...
+func (l *Logger) Error(msg string, keyValues ...interface{}) {
+	l.Log(Error, msg, keyValues...)
+}
+
+// Log ...
+func (l *Logger) Log(at Level, msg string, keyValues ...interface{}) {
+	if !l.Enabled(at) {
+		return
+	}
+
+	fields := []interface{}{
+		"time", time.Now().UTC().Format(time.RFC3339Nano),
+		"level", at.String(),
+		"component", l.component,
+		"msg", msg,
+	}
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyValues...)
+
+	mu.RLock()
+	asJSON, w := format == JSON, output
+	mu.RUnlock()
+
+	var line []byte
+	if asJSON {
+		line = encodeJSON(fields)
+	} else {
+		line = encodeLogfmt(fields)
+	}
+
+	// Lines from concurrent segments are never interleaved
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	w.Write(line)
+}
+
+func encodeLogfmt(fields []interface{}) []byte {
+	var b bytes.Buffer
+
+	for i := 0; i < len(fields); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(key(fields, i))
+		b.WriteByte('=')
+
+		value := text(fields, i+1)
+		if value == "" || strings.ContainsAny(value, " =\"\t\n\\") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(value)
+	}
+
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+func encodeJSON(fields []interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+
+	for i := 0; i < len(fields); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(key(fields, i))
+		b.Write(name)
+		b.WriteByte(':')
+
+		var value interface{}
+		if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+
+		// Values JSON can't represent as is are written as text
+		switch value.(type) {
+		case error, fmt.Stringer:
+			value = text(fields, i+1)
+		}
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			raw, _ = json.Marshal(text(fields, i+1))
+		}
+		b.Write(raw)
+	}
+
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+func key(fields []interface{}, i int) string {
+	name, ok := fields[i].(string)
+	if !ok {
+		return fmt.Sprint(fields[i])
+	}
+
+	return name
+}
+
+func text(fields []interface{}, i int) string {
+	if i >= len(fields) {
+		return ""
+	}
+
+	switch value := fields[i].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case error:
+		return value.Error()
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+type contextKey struct{}
+
+// NewContext ...
+// Carries the logger, and its fields, to whatever the context is passed to
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext ...
+// The context's logger writing for the given component, a logger without fields when there is none
+func FromContext(ctx context.Context, component string) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+			return l.Component(component)
+		}
+	}
+
+	return New(component)
+}
@@ -7,22 +7,68 @@ package main
 //
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
-	"server/encoder"
-	"server/transcriber"
+	"os/signal"
+	"server/alerts"
+	"server/channels"
+	"server/config"
+	"server/costs"
+	"server/health"
+	"server/logging"
+	"server/metrics"
+	"server/store"
+	"server/tracing"
+	"server/transcriber/recognizers/cache"
+	"syscall"
+	"time"
 )
 
 var ffmpegPath = os.Getenv("FFMPEG_PATH")
+var configPath = os.Getenv("VSR_CONFIG")
 const temporaryOutputDirName = "_tmp"
 
+// Synchronous recognition requests are capped at one minute of audio
+const vodMaxRequestDuration = 60 * time.Second
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "vod" {
+		os.Exit(runVOD(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "webhook-receiver" {
+		os.Exit(runReceiver(os.Args[2:]))
+	}
+
 	wd, err := os.Getwd()
 	if err != nil {
 		fmt.Println("[main] Error Getwd(), err: ", err)
 		panic(err)
 	}
 
+	cfg := config.Default()
+	if configPath != "" {
+		cfg, err = config.Load(configPath)
+		if err != nil {
+			fmt.Println("[main] Error loading configuration, err: ", err)
+			panic(err)
+		}
+	}
+
+	err = logging.Configure(cfg.Logging)
+	if err != nil {
+		fmt.Println("[main] Invalid logging configuration, err: ", err)
+		panic(err)
+	}
+
+	exporter, err := tracing.Start(cfg.Tracing)
+	if err != nil {
+		fmt.Println("[main] Could not start tracing, err: ", err)
+		panic(err)
+	}
+
 	var temporaryOutputDirPath = fmt.Sprintf("%s/%s", wd, temporaryOutputDirName)
 
 	err = os.RemoveAll(fmt.Sprintf("%s/%s", "./", temporaryOutputDirName))
@@ -30,14 +76,106 @@ func main() {
 		fmt.Println("[main] Could not remove pre-existing temporary directory: ", err)
 	}
 
-	go transcriber.Start(
-		ffmpegPath,
-		fmt.Sprintf("%s/%s", temporaryOutputDirPath, "text"),  // Transcriber will output to /_tmp/text
-		fmt.Sprintf("%s/%s", temporaryOutputDirPath, "0"),     // Transcriber will reference media segments that will exist in /_tmp/0
-	)
+	history, err := store.Open(cfg.HistoryPath)
+	if err != nil {
+		fmt.Println("[main] Could not open the transcript history, err: ", err)
+		panic(err)
+	}
+	defer history.Close()
+
+	alerter, err := alerts.New(cfg.Alerts)
+	if err != nil {
+		fmt.Println("[main] Could not start alerting, err: ", err)
+		panic(err)
+	}
+
+	history.Subscribe(alerter.Transcript)
+
+	meter, err := costs.Open(cfg.Costs)
+	if err != nil {
+		fmt.Println("[main] Could not open the recognition usage, err: ", err)
+		panic(err)
+	}
+
+	var recognitions *cache.Cache
+	if cfg.Cache.Enabled {
+		recognitions, err = cache.Open(cfg.Cache)
+		if err != nil {
+			fmt.Println("[main] Could not open the recognition cache, err: ", err)
+			panic(err)
+		}
+	}
+
+	// Each channel will output to /_tmp/<channel name>
+	manager := channels.NewManager(ffmpegPath, temporaryOutputDirPath, history, meter, recognitions,
+		time.Duration(cfg.Shutdown.Timeout)*time.Second)
+
+	for _, channel := range cfg.Channels {
+		err = manager.Add(channel)
+		if err != nil {
+			fmt.Println("[main] Could not add channel, err: ", err)
+		}
+	}
+
+	checker := health.New(cfg.Health, manager)
+
+	mux := http.NewServeMux()
+	mux.Handle(health.LivenessPath, checker.LivenessHandler())
+	mux.Handle(health.ReadinessPath, checker.ReadinessHandler())
+	mux.Handle(health.StatusPath, checker.StatusHandler())
+	mux.Handle(channels.APIPrefix, manager.APIHandler())
+	mux.Handle(channels.APIPrefix+"/", manager.APIHandler())
+	mux.Handle(channels.SearchPath, manager.SearchHandler())
+	mux.Handle(alerts.APIPrefix+"/", alerter.APIHandler())
+	mux.Handle(costs.APIPrefix, meter.APIHandler())
+	mux.Handle(costs.APIPrefix+"/", meter.APIHandler())
+	mux.Handle(logging.APIPrefix, logging.APIHandler())
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.Handle("/", manager)
+
+	server := &http.Server{Addr: cfg.HTTPAddr, Handler: mux}
+
+	go func() {
+		fmt.Println("[main] listening on: ", cfg.HTTPAddr)
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			fmt.Println("[main] HTTP server stopped, err: ", err)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	sig := <-signals
+	fmt.Printf("[main] Received %v, shutting down (send again to exit immediately) \n", sig)
+
+	go func() {
+		<-signals
+		fmt.Println("[main] Exiting immediately")
+		os.Exit(1)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Shutdown.Timeout)*time.Second)
+	defer cancel()
+
+	// Keep serving while the channels drain so players can fetch the final segments and transcripts
+	manager.Shutdown(ctx, cfg.Shutdown.KeepWorkspace)
+	alerter.Shutdown(ctx)
+
+	err = meter.Close()
+	if err != nil {
+		fmt.Println("[main] Could not save the recognition usage, err: ", err)
+	}
+
+	// Sending the spans of the final segments
+	if exporter != nil {
+		exporter.Shutdown(ctx)
+	}
+
+	err = server.Shutdown(ctx)
+	if err != nil {
+		fmt.Println("[main] Could not shut down the HTTP server cleanly, err: ", err)
+	}
 
-	encoder.Start(
-		ffmpegPath,
-		temporaryOutputDirPath,  // Encoder will output to /_tmp
-	)
+	fmt.Println("[main] Shut down")
 }
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LatencyBuckets ...
+// Upper bounds in seconds, from a fast recognition to a segment falling far behind
+var LatencyBuckets = []float64{0.25, 0.5, 1, 2, 3, 5, 8, 10, 15, 20, 30, 60}
+
+// RatioBuckets ...
+// Upper bounds for values between 0 and 1, e.g. confidences
+var RatioBuckets = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// HistogramVec ...
+// Counts observations in cumulative buckets, one histogram per combination of label values
+type HistogramVec struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+type histogram struct {
+	labels []Label
+	counts []uint64 // Per bucket, not cumulative, the last one is +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec ...
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    sorted,
+		values:     make(map[string]*histogram),
+	}
+
+	Default.Register(h)
+	return h
+}
+
+// Describe ...
+func (h *HistogramVec) Describe() (string, string, string) {
+	return h.name, h.help, "histogram"
+}
+
+// Observe ...
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(h.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", h.name, len(h.labelNames), len(labelValues)))
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := h.values[key]
+	if !ok {
+		labels := make([]Label, len(labelValues))
+		for i, value := range labelValues {
+			labels[i] = Label{Name: h.labelNames[i], Value: value}
+		}
+
+		s = &histogram{labels: labels, counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = s
+	}
+
+	s.counts[sort.SearchFloat64s(h.buckets, value)]++
+	s.sum += value
+	s.count++
+}
+
+// Delete ...
+// Removes the histogram for the given label values, e.g. when a channel is removed
+func (h *HistogramVec) Delete(labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.values, strings.Join(labelValues, "\xff"))
+}
+
+// Samples ...
+func (h *HistogramVec) Samples() []Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]Sample, 0, len(keys)*(len(h.buckets)+3))
+	for _, key := range keys {
+		s := h.values[key]
+
+		var cumulative uint64
+		for i, count := range s.counts {
+			cumulative += count
+
+			bound := math.Inf(1)
+			if i < len(h.buckets) {
+				bound = h.buckets[i]
+			}
+
+			labels := append(append([]Label{}, s.labels...), Label{Name: "le", Value: formatBound(bound)})
+			result = append(result, Sample{Suffix: "_bucket", Labels: labels, Value: float64(cumulative)})
+		}
+
+		result = append(result,
+			Sample{Suffix: "_sum", Labels: s.labels, Value: s.sum},
+			Sample{Suffix: "_count", Labels: s.labels, Value: float64(s.count)},
+		)
+	}
+
+	return result
+}
+
+func formatBound(bound float64) string {
+	if math.IsInf(bound, 1) {
+		return "+Inf"
+	}
+
+	return formatValue(bound)
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default ...
+// The registry served on /metrics
+var Default = NewRegistry()
+
+// Collector ...
+type Collector interface {
+	Describe() (name string, help string, kind string)
+	Samples() []Sample
+}
+
+// Sample ...
+type Sample struct {
+	Suffix string // e.g. "_bucket", empty for plain counters and gauges
+	Labels []Label
+	Value  float64
+}
+
+// Label ...
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Registry ...
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry ...
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+	}
+}
+
+// Register ...
+// Panics on duplicate names, registration happens at package init time
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name, _, _ := c.Describe()
+	if _, exists := r.collectors[name]; exists {
+		panic(fmt.Sprintf("metrics: duplicate registration of %s", name))
+	}
+
+	r.collectors[name] = c
+}
+
+// WriteText ...
+// Writes every registered metric in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := r.collectors
+	r.mu.RUnlock()
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, help, kind := collectors[name].Describe()
+
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+		if err != nil {
+			return err
+		}
+
+		for _, sample := range collectors[name].Samples() {
+			_, err = fmt.Fprintf(w, "%s%s%s %s\n", name, sample.Suffix, formatLabels(sample.Labels), formatValue(sample.Value))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Handler ...
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		err := r.WriteText(w)
+		if err != nil {
+			fmt.Println("[Handler] Could not write metrics: ", err)
+		}
+	})
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%q", label.Name, label.Value))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
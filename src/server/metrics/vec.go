@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// vec holds one value per combination of label values
+type vec struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]*series
+}
+
+type series struct {
+	labels []Label
+	value  float64
+}
+
+func newVec(name, help, kind string, labelNames []string) *vec {
+	return &vec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		values:     make(map[string]*series),
+	}
+}
+
+// Describe ...
+func (v *vec) Describe() (string, string, string) {
+	return v.name, v.help, v.kind
+}
+
+// Samples ...
+func (v *vec) Samples() []Sample {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]Sample, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, Sample{Labels: v.values[key].labels, Value: v.values[key].value})
+	}
+
+	return result
+}
+
+func (v *vec) series(labelValues []string) *series {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.values[key]
+	if !ok {
+		labels := make([]Label, len(labelValues))
+		for i, value := range labelValues {
+			labels[i] = Label{Name: v.labelNames[i], Value: value}
+		}
+
+		s = &series{labels: labels}
+		v.values[key] = s
+	}
+
+	return s
+}
+
+// Delete ...
+// Removes the series for the given label values, e.g. when a channel is removed
+func (v *vec) Delete(labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.values, strings.Join(labelValues, "\xff"))
+}
+
+// CounterVec ...
+type CounterVec struct {
+	*vec
+}
+
+// NewCounterVec ...
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labelNames)}
+	Default.Register(c)
+	return c
+}
+
+// Add ...
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.series(labelValues).value += delta
+}
+
+// Inc ...
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// GaugeVec ...
+type GaugeVec struct {
+	*vec
+}
+
+// NewGaugeVec ...
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labelNames)}
+	Default.Register(g)
+	return g
+}
+
+// Set ...
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.series(labelValues).value = value
+}
+
+// Add ...
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.series(labelValues).value += delta
+}
@@ -1,6 +1,7 @@
 #!/bin/bash
 export GOOGLE_APPLICATION_CREDENTIALS="/Users/mcunningham/Credentials/live-transcription-9e1584a26299.json"
 export FFMPEG_PATH="/Users/mcunningham/Binaries/ffmpeg-osx_4.3.1"
+# export VSR_CONFIG="$(pwd)/src/server/config.json"
 
 # Removing old built binary if it exists
 if [[ -f "./bin/vsr_x64" ]]; then
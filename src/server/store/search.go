@@ -0,0 +1,306 @@
+package store
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"server/transcriber/recognizers"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSearchLimit ...
+const DefaultSearchLimit = 100
+
+// DefaultSearchContext ...
+// Words of context on either side of a match
+const DefaultSearchContext = 8
+
+// SearchQuery ...
+type SearchQuery struct {
+	Phrase   string   // One or more words, matched consecutively regardless of case and punctuation
+	Channels []string // All channels when empty
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+	Context  int
+}
+
+// Match ...
+// A single occurrence of the searched phrase
+type Match struct {
+	Channel    string     `json:"channel"`
+	Segment    string     `json:"segment"`
+	MediaTime  float64    `json:"mediaTime"` // Absolute media time of the first matched word, in seconds
+	WallClock  *time.Time `json:"wallClock,omitempty"`
+	Text       string     `json:"text"`
+	Context    string     `json:"context"`
+	Confidence float32    `json:"confidence"`
+}
+
+// newer orders matches by wall clock, the only time comparable across channels. Matches without one can't be placed
+// against it and come after all the others, per channel by media time.
+func (m Match) newer(other Match) bool {
+	switch {
+	case m.WallClock != nil && other.WallClock != nil:
+		return m.WallClock.After(*other.WallClock)
+	case m.WallClock != nil || other.WallClock != nil:
+		return m.WallClock != nil
+	case m.Channel != other.Channel:
+		return m.Channel < other.Channel
+	}
+
+	return m.MediaTime > other.MediaTime
+}
+
+// posting ...
+// An occurrence of a term, positions are counted across the whole channel so phrases can span segments
+type posting struct {
+	entry    int // Index into the channel's entries, in arrival order
+	word     int // Index into the entry's words
+	position int
+}
+
+func terms(phrase string) []string {
+	result := make([]string, 0)
+	for _, word := range strings.Fields(phrase) {
+		term := recognizers.Normalize(word)
+		if term != "" {
+			result = append(result, term)
+		}
+	}
+
+	return result
+}
+
+func (l *channelLog) indexWords(entry Entry, loc location) {
+	l.entries = append(l.entries, loc)
+
+	for i, word := range entry.Words {
+		term := recognizers.Normalize(word.Word)
+		if term == "" {
+			continue
+		}
+
+		l.terms[term] = append(l.terms[term], posting{
+			entry:    len(l.entries) - 1,
+			word:     i,
+			position: l.position,
+		})
+		l.position++
+	}
+}
+
+// Search ...
+// The most recent occurrences of the phrase, newest first
+func (s *Store) Search(query SearchQuery) ([]Match, error) {
+	phrase := terms(query.Phrase)
+	if len(phrase) == 0 {
+		return []Match{}, nil
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultSearchLimit
+	}
+
+	if query.Context <= 0 {
+		query.Context = DefaultSearchContext
+	}
+
+	channels := query.Channels
+	if len(channels) == 0 {
+		var err error
+		channels, err = s.Channels()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := make([]Match, 0)
+	for _, channel := range channels {
+		log, err := s.open(channel)
+		if err == ErrNoHistory {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := log.search(channel, phrase, query)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, found...)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].newer(matches[j])
+	})
+
+	if len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+
+	return matches, nil
+}
+
+// Channels ...
+// Every channel with a history, including removed ones
+func (s *Store) Channels() ([]string, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]string, 0)
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".jsonl" {
+			channels = append(channels, strings.TrimSuffix(file.Name(), ".jsonl"))
+		}
+	}
+
+	return channels, nil
+}
+
+func (l *channelLog) search(channel string, phrase []string, query SearchQuery) ([]Match, error) {
+	// Entries are read at most once per search
+	cache := make(map[int]Entry)
+	entry := func(i int) (Entry, error) {
+		if e, ok := cache[i]; ok {
+			return e, nil
+		}
+
+		entries, err := l.read([]location{l.entries[i]})
+		if err != nil {
+			return Entry{}, err
+		}
+
+		cache[i] = entries[0]
+		return entries[0], nil
+	}
+
+	matches := make([]Match, 0)
+	for _, first := range l.terms[phrase[0]] {
+		if !l.inRange(first.entry, query) || !l.follows(first.position, phrase[1:]) {
+			continue
+		}
+
+		e, err := entry(first.entry)
+		if err != nil {
+			return nil, err
+		}
+
+		word := e.Words[first.word]
+		match := Match{
+			Channel:    channel,
+			Segment:    e.Segment,
+			MediaTime:  e.MediaStart + word.Start.Duration().Seconds(),
+			Confidence: e.Confidence,
+		}
+
+		if e.WallClock != nil {
+			clock := e.WallClock.Add(word.Start.Duration())
+			if !query.Since.IsZero() && clock.Before(query.Since) || !query.Until.IsZero() && !clock.Before(query.Until) {
+				continue
+			}
+
+			match.WallClock = &clock
+		}
+
+		text, context, err := l.context(first, len(phrase), query.Context, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		match.Text = text
+		match.Context = context
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// inRange cheaply rules out entries well outside the wall-clock filter, words are checked exactly once read
+func (l *channelLog) inRange(entry int, query SearchQuery) bool {
+	if query.Since.IsZero() && query.Until.IsZero() {
+		return true
+	}
+
+	loc := l.entries[entry]
+	if loc.wallClock.IsZero() {
+		return false
+	}
+
+	end := loc.wallClock.Add(time.Duration((loc.mediaEnd - loc.mediaStart) * float64(time.Second)))
+	if !query.Since.IsZero() && end.Before(query.Since) {
+		return false
+	}
+
+	return query.Until.IsZero() || loc.wallClock.Before(query.Until)
+}
+
+// follows checks the rest of the phrase occurs right after the given position
+func (l *channelLog) follows(position int, rest []string) bool {
+	for i, term := range rest {
+		want := position + i + 1
+		postings := l.terms[term]
+
+		j := sort.Search(len(postings), func(j int) bool {
+			return postings[j].position >= want
+		})
+		if j == len(postings) || postings[j].position != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// context returns the matched words and the words around them, which may belong to neighbouring segments
+func (l *channelLog) context(first posting, length int, around int, entry func(int) (Entry, error)) (string, string, error) {
+	words := make([]string, 0)
+	start := 0
+
+	for i := first.entry - 1; i <= first.entry+1; i++ {
+		if i < 0 || i >= len(l.entries) {
+			continue
+		}
+
+		e, err := entry(i)
+		if err != nil {
+			return "", "", err
+		}
+
+		if i == first.entry {
+			start = len(words) + first.word
+		}
+
+		for _, w := range e.Words {
+			words = append(words, w.Word)
+		}
+	}
+
+	// Punctuation-only words were not indexed, so they don't count towards the phrase
+	end := start
+	for matched := 0; end < len(words) && matched < length; end++ {
+		if recognizers.Normalize(words[end]) != "" {
+			matched++
+		}
+	}
+
+	from := start - around
+	if from < 0 {
+		from = 0
+	}
+
+	to := end + around
+	if to > len(words) {
+		to = len(words)
+	}
+
+	return strings.Join(words[start:end], " "), strings.Join(words[from:to], " "), nil
+}
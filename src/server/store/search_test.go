@@ -0,0 +1,179 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"server/transcriber/recognizers"
+	"strings"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// testEntry is the sequence-th 4 second segment of the channel, its words half a second apart. Segments are dated
+// from epoch unless undated.
+func testEntry(channel string, sequence int, undated bool, text string) Entry {
+	entry := Entry{
+		Channel:    channel,
+		Segment:    channel + "-" + string(rune('a'+sequence)) + ".ts",
+		Sequence:   sequence,
+		MediaStart: float64(sequence * 4),
+		Duration:   4,
+		Words:      make([]recognizers.TimedWord, 0),
+	}
+
+	if !undated {
+		clock := epoch.Add(time.Duration(sequence*4) * time.Second)
+		entry.WallClock = &clock
+	}
+
+	for i, word := range strings.Fields(text) {
+		start := time.Duration(i) * 500 * time.Millisecond
+		entry.Words = append(entry.Words, recognizers.TimedWord{
+			Start: recognizers.FromDuration(start),
+			End:   recognizers.FromDuration(start + 400*time.Millisecond),
+			Word:  word,
+		})
+	}
+
+	return entry
+}
+
+func openStore(t *testing.T, entries ...Entry) *Store {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+
+	for _, entry := range entries {
+		err = s.Append(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return s
+}
+
+func TestSearch(t *testing.T) {
+	s := openStore(t,
+		testEntry("news", 0, false, "Good morning, the weather today"),
+		testEntry("news", 1, false, "is sunny. In other news"),
+		testEntry("news", 2, false, "the weather tomorrow is rainy"),
+		testEntry("sports", 1, false, "the weather held for the match"),
+		testEntry("sports", 2, false, "what a goal — weather permitting"),
+		testEntry("archive", 0, true, "weather report from the archive"),
+		testEntry("archive", 1, true, "more weather"),
+	)
+
+	tests := []struct {
+		name    string
+		query   SearchQuery
+		matches []string // Channel and wall-clock or media seconds of each match, in order
+		texts   []string
+	}{
+		{
+			name:    "newest first, undated last",
+			query:   SearchQuery{Phrase: "weather"},
+			matches: []string{"sports@10:00:10", "news@10:00:08.5", "sports@10:00:04.5", "news@10:00:01.5", "archive@4.5", "archive@0"},
+		},
+		{
+			name:    "limited to the newest",
+			query:   SearchQuery{Phrase: "weather", Limit: 2},
+			matches: []string{"sports@10:00:10", "news@10:00:08.5"},
+		},
+		{
+			name:    "phrase across punctuation and case",
+			query:   SearchQuery{Phrase: "THE Weather"},
+			matches: []string{"news@10:00:08", "sports@10:00:04", "news@10:00:01"},
+			texts:   []string{"the weather", "the weather", "the weather"},
+		},
+		{
+			name:    "phrase spanning segments",
+			query:   SearchQuery{Phrase: "weather today is sunny"},
+			matches: []string{"news@10:00:01.5"},
+			texts:   []string{"weather today is sunny."},
+		},
+		{
+			name:    "words that aren't consecutive",
+			query:   SearchQuery{Phrase: "weather sunny"},
+			matches: []string{},
+		},
+		{
+			name:    "punctuation-only words are skipped",
+			query:   SearchQuery{Phrase: "goal weather"},
+			matches: []string{"sports@10:00:09"},
+			texts:   []string{"goal — weather"},
+		},
+		{
+			name:    "channels",
+			query:   SearchQuery{Phrase: "weather", Channels: []string{"archive", "missing"}},
+			matches: []string{"archive@4.5", "archive@0"},
+		},
+		{
+			name:    "wall-clock range",
+			query:   SearchQuery{Phrase: "weather", Since: epoch.Add(2 * time.Second), Until: epoch.Add(8 * time.Second)},
+			matches: []string{"sports@10:00:04.5"},
+		},
+		{
+			name:    "no terms",
+			query:   SearchQuery{Phrase: " , "},
+			matches: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, err := s.Search(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0)
+			texts := make([]string, 0)
+			for _, m := range matches {
+				if m.WallClock != nil {
+					got = append(got, m.Channel+"@"+m.WallClock.Format("15:04:05.999"))
+				} else {
+					got = append(got, m.Channel+"@"+strings.TrimSuffix(time.Duration(m.MediaTime*float64(time.Second)).String(), "s"))
+				}
+
+				texts = append(texts, m.Text)
+			}
+
+			if strings.Join(got, " ") != strings.Join(test.matches, " ") {
+				t.Errorf("matches = %v, want %v", got, test.matches)
+			}
+
+			if test.texts != nil && strings.Join(texts, "|") != strings.Join(test.texts, "|") {
+				t.Errorf("texts = %q, want %q", texts, test.texts)
+			}
+		})
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	s := openStore(t,
+		testEntry("news", 0, false, "one two three four"),
+		testEntry("news", 1, false, "five six seven eight"),
+	)
+
+	matches, err := s.Search(SearchQuery{Phrase: "five", Context: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 1 || matches[0].Context != "three four five six seven" || matches[0].Segment != "news-b.ts" {
+		t.Errorf("matches = %+v, want five in news-b.ts with two words of context", matches)
+	}
+}
@@ -0,0 +1,390 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"server/logging"
+	"server/transcriber/recognizers"
+	"server/transcriber/vad"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry ...
+// The transcript of a single media segment
+type Entry struct {
+	Channel    string                  `json:"channel"`
+	Segment    string                  `json:"segment"`    // Media segment filename
+	Sequence   int                     `json:"sequence"`   // Media sequence number
+	MediaStart float64                 `json:"mediaStart"` // Absolute media time of the segment start, in seconds
+	Duration   float64                 `json:"duration"`
+	WallClock  *time.Time              `json:"wallClock,omitempty"` // Program date time of the segment start
+	Confidence float32                 `json:"confidence"`
+	Language   string                  `json:"language,omitempty"`
+	Speaker    string                  `json:"speaker,omitempty"`    // Speaker of most of the words, when diarized
+	Words      []recognizers.TimedWord `json:"words"`                // Relative to the segment start, as published
+	Unfiltered []recognizers.TimedWord `json:"unfiltered,omitempty"` // As recognized, only kept when the content filter changed the words
+	Speech     *vad.Decision           `json:"speech,omitempty"`     // Voice activity detection, no words were recognized without speech
+	Paused     bool                    `json:"paused,omitempty"`     // Not recognized, the channel's recognition budget was exhausted
+	Skipped    bool                    `json:"skipped,omitempty"`    // Not recognized, too far behind the live edge
+	Provider   string                  `json:"provider,omitempty"`   // Recognizers that produced the words, '+'-joined for ensembles
+	// Share of aligned words the recognizers of an ensemble didn't agree on
+	Disagreement *float64 `json:"disagreement,omitempty"`
+	Cached       bool     `json:"cached,omitempty"` // Reused from the recognition of identical audio, not billed
+	// The segment's ID in the logs, from discovery to publication
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// Recognized ...
+// The words as the recognizer returned them, before any filtering
+func (e Entry) Recognized() []recognizers.TimedWord {
+	if e.Unfiltered != nil {
+		return e.Unfiltered
+	}
+
+	return e.Words
+}
+
+// MediaEnd ...
+func (e Entry) MediaEnd() float64 {
+	return e.MediaStart + e.Duration
+}
+
+// AbsoluteWords ...
+// The entry's words placed on the channel's media timeline
+func (e Entry) AbsoluteWords() []recognizers.TimedWord {
+	offset := time.Duration(e.MediaStart * float64(time.Second))
+
+	words := make([]recognizers.TimedWord, 0, len(e.Words))
+	for _, word := range e.Words {
+		words = append(words, word.Offset(offset))
+	}
+
+	return words
+}
+
+// ErrNoHistory ...
+// The channel has never stored a transcript
+var ErrNoHistory = errors.New("channel has no transcript history")
+
+// Store ...
+// Retains the full transcript history of every channel, independently of the live media window.
+// Entries are appended to one JSON-lines file per channel, with in-memory indexes by media time, wall-clock time
+// and word pointing into the file, so only the entries a query needs are read back.
+type Store struct {
+	dir string
+
+	mu          sync.Mutex
+	channels    map[string]*channelLog
+	subscribers []func(Entry)
+}
+
+type channelLog struct {
+	file *os.File
+	size int64
+
+	byMedia []location // Sorted by media start
+	byWall  []location // Sorted by wall-clock start, only dated entries
+	longest float64    // Duration of the longest entry, in seconds
+
+	entries  []location // In arrival order
+	terms    map[string][]posting
+	position int
+}
+
+type location struct {
+	mediaStart float64
+	mediaEnd   float64
+	wallClock  time.Time
+	offset     int64
+	size       int
+}
+
+// Open ...
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		dir:      dir,
+		channels: make(map[string]*channelLog),
+	}, nil
+}
+
+// Append ...
+func (s *Store) Append(entry Entry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	subscribers, err := s.write(entry, append(raw, '\n'))
+	if err != nil {
+		return err
+	}
+
+	for _, subscriber := range subscribers {
+		subscriber(entry)
+	}
+
+	return nil
+}
+
+// write appends the raw entry to the channel's log and indexes it, returning the subscribers to notify
+func (s *Store) write(entry Entry, raw []byte) ([]func(Entry), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, err := s.create(entry.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = log.file.WriteAt(raw, log.size)
+	if err != nil {
+		return nil, err
+	}
+
+	log.index(entry, log.size, len(raw))
+	log.size += int64(len(raw))
+
+	return s.subscribers, nil
+}
+
+// Subscribe ...
+// Calls the subscriber with every entry once it's stored, subscribers must not block
+func (s *Store) Subscribe(subscriber func(Entry)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers = append(s.subscribers, subscriber)
+}
+
+// Range ...
+// Entries overlapping the media time range [from, to), in seconds
+func (s *Store) Range(channel string, from float64, to float64) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, err := s.open(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	first := sort.Search(len(log.byMedia), func(i int) bool {
+		return log.byMedia[i].mediaEnd > from
+	})
+
+	locations := make([]location, 0)
+	for _, l := range log.byMedia[first:] {
+		if l.mediaStart >= to {
+			break
+		}
+
+		locations = append(locations, l)
+	}
+
+	return log.read(locations)
+}
+
+// RangeWallClock ...
+// Entries overlapping the wall-clock range [since, until)
+func (s *Store) RangeWallClock(channel string, since time.Time, until time.Time) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, err := s.open(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries are indexed by their start, those started up to the longest duration earlier may still overlap
+	earliest := since.Add(-time.Duration(log.longest * float64(time.Second)))
+	first := sort.Search(len(log.byWall), func(i int) bool {
+		return log.byWall[i].wallClock.After(earliest)
+	})
+
+	locations := make([]location, 0)
+	for _, l := range log.byWall[first:] {
+		if !l.wallClock.Before(until) {
+			break
+		}
+
+		if l.wallEnd().After(since) {
+			locations = append(locations, l)
+		}
+	}
+
+	return log.read(locations)
+}
+
+// MediaEnd ...
+// The end of the channel's media timeline, so a restarted channel continues where it left off, 0 without a history
+func (s *Store) MediaEnd(channel string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, err := s.open(channel)
+	if err == ErrNoHistory {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	end := 0.0
+	for _, l := range log.byMedia {
+		if l.mediaEnd > end {
+			end = l.mediaEnd
+		}
+	}
+
+	return end, nil
+}
+
+// Close ...
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result error
+	for channel, log := range s.channels {
+		err := log.file.Close()
+		if err != nil {
+			result = err
+		}
+
+		delete(s.channels, channel)
+	}
+
+	return result
+}
+
+// open returns the channel's log for reading, ErrNoHistory when it has none. Must be called with the lock held.
+func (s *Store) open(channel string) (*channelLog, error) {
+	log, err := s.load(channel, os.O_RDWR)
+	if os.IsNotExist(err) {
+		return nil, ErrNoHistory
+	}
+
+	return log, err
+}
+
+// create returns the channel's log for appending, creating it on the first entry. Must be called with the lock held.
+func (s *Store) create(channel string) (*channelLog, error) {
+	return s.load(channel, os.O_CREATE|os.O_RDWR)
+}
+
+// load returns the channel's log, building its index from the file on first use
+func (s *Store) load(channel string, flag int) (*channelLog, error) {
+	log, ok := s.channels[channel]
+	if ok {
+		return log, nil
+	}
+
+	file, err := os.OpenFile(filepath.Join(s.dir, channel+".jsonl"), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	log = &channelLog{
+		file:  file,
+		terms: make(map[string][]posting),
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A partially written last line, e.g. after a crash, is overwritten by the next append
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		var entry Entry
+		if json.Unmarshal(line, &entry) == nil {
+			log.index(entry, log.size, len(line))
+		} else {
+			logging.New("store", "channel", channel).Warn("Skipping unreadable entry", "offset", log.size)
+		}
+
+		log.size += int64(len(line))
+	}
+
+	s.channels[channel] = log
+	return log, nil
+}
+
+func (l *channelLog) index(entry Entry, offset int64, size int) {
+	loc := location{
+		mediaStart: entry.MediaStart,
+		mediaEnd:   entry.MediaEnd(),
+		offset:     offset,
+		size:       size,
+	}
+
+	if entry.WallClock != nil {
+		loc.wallClock = *entry.WallClock
+	}
+
+	l.indexWords(entry, loc)
+
+	if entry.Duration > l.longest {
+		l.longest = entry.Duration
+	}
+
+	// Segments almost always arrive in order, the insertion is at the end
+	i := sort.Search(len(l.byMedia), func(i int) bool {
+		return l.byMedia[i].mediaStart > loc.mediaStart
+	})
+	l.byMedia = append(l.byMedia, location{})
+	copy(l.byMedia[i+1:], l.byMedia[i:])
+	l.byMedia[i] = loc
+
+	if entry.WallClock == nil {
+		return
+	}
+
+	i = sort.Search(len(l.byWall), func(i int) bool {
+		return l.byWall[i].wallClock.After(loc.wallClock)
+	})
+	l.byWall = append(l.byWall, location{})
+	copy(l.byWall[i+1:], l.byWall[i:])
+	l.byWall[i] = loc
+}
+
+func (l location) wallEnd() time.Time {
+	return l.wallClock.Add(time.Duration((l.mediaEnd - l.mediaStart) * float64(time.Second)))
+}
+
+func (l *channelLog) read(locations []location) ([]Entry, error) {
+	entries := make([]Entry, 0, len(locations))
+
+	for _, loc := range locations {
+		raw := make([]byte, loc.size)
+		_, err := l.file.ReadAt(raw, loc.offset)
+		if err != nil {
+			return nil, err
+		}
+
+		var entry Entry
+		err = json.Unmarshal(raw, &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
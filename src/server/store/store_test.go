@@ -0,0 +1,125 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	long := testEntry("news", 4, false, "a long segment")
+	long.Duration = 12
+	undated := testEntry("news", 7, true, "undated")
+
+	// Appended out of order, as after a restart
+	s := openStore(t,
+		testEntry("news", 0, false, "zero"),
+		testEntry("news", 2, false, "two"),
+		testEntry("news", 1, false, "one"),
+		testEntry("news", 3, false, "three"),
+		long,
+		undated,
+		testEntry("sports", 0, false, "other channel"),
+	)
+
+	media := []struct {
+		name     string
+		from, to float64
+		want     []int
+	}{
+		{"single segment", 0, 4, []int{0}},
+		{"across a boundary", 3, 5, []int{0, 1}},
+		{"ends are exclusive", 4, 8, []int{1}},
+		{"within a long segment", 20, 24, []int{4}},
+		{"overlapping the start", 26, 30, []int{4, 7}},
+		{"past the end", 40, 50, []int{}},
+	}
+
+	wall := []struct {
+		name         string
+		since, until float64 // Seconds from epoch
+		want         []int
+	}{
+		{"single segment", 0, 4, []int{0}},
+		{"within a segment", 5, 6, []int{1}},
+		{"started before", 26, 27, []int{4}},
+		{"undated are left out", 27, 40, []int{4}},
+		{"before the first", -10, 0, []int{}},
+		{"after the last", 28, 40, []int{}},
+	}
+
+	sequences := func(entries []Entry) []int {
+		result := make([]int, 0)
+		for _, entry := range entries {
+			if entry.Channel != "news" {
+				t.Errorf("entry of channel %s", entry.Channel)
+			}
+			result = append(result, entry.Sequence)
+		}
+		return result
+	}
+
+	// The indexes built on append and those rebuilt from the file once reopened
+	for _, state := range []string{"appended", "reopened"} {
+		if state == "reopened" {
+			s.Close()
+		}
+
+		for _, test := range media {
+			t.Run(state+"/media/"+test.name, func(t *testing.T) {
+				entries, err := s.Range("news", test.from, test.to)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := sequences(entries); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("Range(%v, %v) = %v, want %v", test.from, test.to, got, test.want)
+				}
+			})
+		}
+
+		for _, test := range wall {
+			t.Run(state+"/wall clock/"+test.name, func(t *testing.T) {
+				since := epoch.Add(seconds(test.since))
+				until := epoch.Add(seconds(test.until))
+
+				entries, err := s.RangeWallClock("news", since, until)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := sequences(entries); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("RangeWallClock(%v, %v) = %v, want %v", test.since, test.until, got, test.want)
+				}
+			})
+		}
+
+		end, err := s.MediaEnd("news")
+		if err != nil || end != 32 {
+			t.Errorf("%s: media end = %v, %v, want 32", state, end, err)
+		}
+	}
+}
+
+func TestNoHistory(t *testing.T) {
+	s := openStore(t)
+
+	_, err := s.Range("news", 0, 10)
+	if err != ErrNoHistory {
+		t.Errorf("Range = %v, want ErrNoHistory", err)
+	}
+
+	_, err = s.RangeWallClock("news", epoch, epoch.Add(time.Minute))
+	if err != ErrNoHistory {
+		t.Errorf("RangeWallClock = %v, want ErrNoHistory", err)
+	}
+
+	end, err := s.MediaEnd("news")
+	if err != nil || end != 0 {
+		t.Errorf("media end = %v, %v, want 0", end, err)
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,63 @@
+package subtitles
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// GroupID ...
+const GroupID = "subs"
+
+// AddRenditions ...
+// Lists the subtitle renditions in a master playlist written by ffmpeg and attaches them to every variant
+func AddRenditions(master []byte, renditions []Rendition) []byte {
+	if len(renditions) == 0 {
+		return master
+	}
+
+	var out bytes.Buffer
+	added := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(master))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			if !added {
+				for _, rendition := range renditions {
+					fmt.Fprintln(&out, media(rendition))
+				}
+
+				added = true
+			}
+
+			line += fmt.Sprintf(`,SUBTITLES="%s"`, GroupID)
+		}
+
+		fmt.Fprintln(&out, line)
+	}
+
+	return out.Bytes()
+}
+
+func media(rendition Rendition) string {
+	yes := func(b bool) string {
+		if b {
+			return "YES"
+		}
+
+		return "NO"
+	}
+
+	name := rendition.Language
+	if rendition.Translated {
+		name += " (translated)"
+	}
+
+	return fmt.Sprintf(
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="%s",NAME="%s",LANGUAGE="%s",DEFAULT=%s,AUTOSELECT=YES,FORCED=NO,URI="%s"`,
+		GroupID, name, rendition.Language, yes(rendition.Default), rendition.URI,
+	)
+}
@@ -0,0 +1,152 @@
+package subtitles
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/captions"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Segment ...
+// A media segment the subtitles are aligned with
+type Segment struct {
+	Sequence      int
+	Duration      float64
+	Discontinuity bool
+	Start         float64 // Media time of the segment start since the encoder (re)started, in seconds
+}
+
+// timestampMap ...
+// Cues are relative to the segment start, placed on the media's 90kHz timeline
+func (s Segment) timestampMap() string {
+	return fmt.Sprintf("MPEGTS:%d,LOCAL:00:00:00.000", int64(s.Start*90000+0.5))
+}
+
+// livePlaylist ...
+// A sliding window of WebVTT segments, numbered like the media segments they belong to
+type livePlaylist struct {
+	dir    string
+	window int
+
+	mu                    sync.Mutex
+	segments              []Segment
+	discontinuitySequence int
+}
+
+func newLivePlaylist(dir string, window int) (*livePlaylist, error) {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	return &livePlaylist{dir: dir, window: window}, nil
+}
+
+// write publishes the segment's cues, replacing them if the segment was published before
+func (p *livePlaylist) write(segment Segment, cues []captions.Cue) error {
+	var vtt bytes.Buffer
+	err := captions.WriteVTTSegment(&vtt, cues, segment.timestampMap())
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(p.dir, uri(segment.Sequence)), vtt.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := sort.Search(len(p.segments), func(i int) bool {
+		return p.segments[i].Sequence >= segment.Sequence
+	})
+
+	if i < len(p.segments) && p.segments[i].Sequence == segment.Sequence {
+		return nil
+	}
+
+	if i == 0 && len(p.segments) >= p.window {
+		// Already outside of the window
+		return os.Remove(filepath.Join(p.dir, uri(segment.Sequence)))
+	}
+
+	if i == len(p.segments) && i > 0 {
+		// Segments that were never transcribed, e.g. deleted before the transcriber caught up, stay empty
+		for sequence := p.segments[i-1].Sequence + 1; sequence < segment.Sequence; sequence++ {
+			gap := Segment{Sequence: sequence, Duration: segment.Duration}
+
+			err = ioutil.WriteFile(filepath.Join(p.dir, uri(sequence)), []byte("WEBVTT\n\n"), 0644)
+			if err != nil {
+				return err
+			}
+
+			p.segments = append(p.segments, gap)
+		}
+
+		i = len(p.segments)
+	}
+
+	p.segments = append(p.segments, Segment{})
+	copy(p.segments[i+1:], p.segments[i:])
+	p.segments[i] = segment
+
+	for len(p.segments) > p.window {
+		if p.segments[0].Discontinuity {
+			p.discontinuitySequence++
+		}
+
+		os.Remove(filepath.Join(p.dir, uri(p.segments[0].Sequence)))
+		p.segments = p.segments[1:]
+	}
+
+	return p.writePlaylist(false)
+}
+
+// end marks the playlist as complete
+func (p *livePlaylist) end() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.writePlaylist(true)
+}
+
+// writePlaylist must be called with the lock held
+func (p *livePlaylist) writePlaylist(ended bool) error {
+	if len(p.segments) == 0 {
+		return nil
+	}
+
+	segments := make([]captions.Segment, 0, len(p.segments))
+	for _, segment := range p.segments {
+		segments = append(segments, captions.Segment{
+			URI:           uri(segment.Sequence),
+			Duration:      time.Duration(segment.Duration * float64(time.Second)),
+			Discontinuity: segment.Discontinuity,
+		})
+	}
+
+	var playlist bytes.Buffer
+	err := captions.WritePlaylist(&playlist, segments, p.segments[0].Sequence, p.discontinuitySequence, ended)
+	if err != nil {
+		return err
+	}
+
+	// Written next to the playlist and renamed, players never see a partial playlist
+	path := filepath.Join(p.dir, "playlist.m3u8")
+	err = ioutil.WriteFile(path+".tmp", playlist.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+func uri(sequence int) string {
+	return fmt.Sprintf("%d.vtt", sequence)
+}
@@ -0,0 +1,186 @@
+package subtitles
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"server/captions"
+	"server/logging"
+	"server/metrics"
+	"server/transcriber/recognizers"
+	"server/transcriber/translators"
+)
+
+// DirName ...
+// Subtitle renditions are published under <channel output>/subtitles/<language>/
+const DirName = "subtitles"
+
+var translationErrorsTotal = metrics.NewCounterVec(
+	"vsr_translation_errors_total",
+	"Subtitle segments published empty because translation failed, by channel and language",
+	"channel", "language",
+)
+
+// Rendition ...
+type Rendition struct {
+	Language   string `json:"language"`
+	URI        string `json:"uri"` // Relative to the master playlist
+	Default    bool   `json:"default"`
+	Translated bool   `json:"translated"`
+}
+
+// Config ...
+type Config struct {
+	Channel     string
+	OutputPath  string   // The channel's output directory, next to the master playlist
+	Window      int      // Segments kept in each playlist, matching the media playlists
+	Languages   []string // Languages that may be spoken on the channel, the main one first
+	Captions    captions.Style
+	Translation translators.Config
+	Translator  translators.Adapter // Only needed when translating
+}
+
+// Publisher ...
+// Publishes live WebVTT subtitle renditions aligned with the media segments: one per spoken language,
+// holding the segments detected in that language, and one per translation, holding every segment
+type Publisher struct {
+	config   Config
+	glossary *translators.Glossary
+
+	spoken       map[string]*livePlaylist
+	translations map[string]*livePlaylist
+}
+
+// New ...
+func New(config Config) (*Publisher, error) {
+	if config.Translation.Enabled() && config.Translator == nil {
+		return nil, fmt.Errorf("translation into %v requires a translator", config.Translation.Languages)
+	}
+
+	if len(config.Languages) == 0 || config.Languages[0] == "" {
+		config.Languages = []string{"und"}
+	}
+
+	p := &Publisher{
+		config:       config,
+		glossary:     translators.NewGlossary(config.Translation.Glossary),
+		spoken:       make(map[string]*livePlaylist),
+		translations: make(map[string]*livePlaylist),
+	}
+
+	var err error
+	for _, language := range config.Languages {
+		p.spoken[language], err = newLivePlaylist(p.dir(language), config.Window)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, language := range config.Translation.Languages {
+		if _, ok := p.spoken[language]; ok {
+			continue
+		}
+
+		p.translations[language], err = newLivePlaylist(p.dir(language), config.Window)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Publish ...
+// Publishes the segment's words, relative to the segment start, in the rendition of the language they were spoken in
+// and translated into every other language. Every rendition gets every segment, empty if needed, so the subtitle
+// playlists stay aligned with the media.
+func (p *Publisher) Publish(ctx context.Context, segment Segment, words []recognizers.TimedWord, language string) error {
+	cues := captions.Build(words, captions.DefaultOptions)
+
+	if _, ok := p.spoken[language]; !ok {
+		language = p.config.Languages[0]
+	}
+
+	for spoken, playlist := range p.spoken {
+		var published []captions.Cue
+		if spoken == language {
+			published = cues
+		}
+
+		err := playlist.write(segment, p.config.Captions.LabelVTT(published))
+		if err != nil {
+			return err
+		}
+	}
+
+	for target, playlist := range p.translations {
+		translated := cues
+
+		if len(cues) > 0 && recognizers.BaseLanguage(target) != recognizers.BaseLanguage(language) {
+			var err error
+			translated, err = p.glossary.Translate(ctx, p.config.Translator, cues, language, target)
+			if err != nil {
+				logging.FromContext(ctx, "subtitles").Warn("Could not translate segment", "sequence", segment.Sequence,
+					"language", target, "err", err)
+				translationErrorsTotal.Inc(p.config.Channel, target)
+				translated = nil
+			}
+		}
+
+		err := playlist.write(segment, p.config.Captions.LabelVTT(translated))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// End ...
+// Marks every playlist as complete, when the channel's output is kept after shutting down
+func (p *Publisher) End() error {
+	var err error
+	for _, playlists := range []map[string]*livePlaylist{p.spoken, p.translations} {
+		for _, playlist := range playlists {
+			if e := playlist.end(); e != nil {
+				err = e
+			}
+		}
+	}
+
+	return err
+}
+
+// Renditions ...
+// The spoken languages first, the main one is the default
+func (p *Publisher) Renditions() []Rendition {
+	renditions := make([]Rendition, 0, len(p.spoken)+len(p.translations))
+
+	for i, language := range p.config.Languages {
+		renditions = append(renditions, Rendition{
+			Language: language,
+			URI:      p.uri(language),
+			Default:  i == 0,
+		})
+	}
+
+	for _, language := range p.config.Translation.Languages {
+		if _, ok := p.translations[language]; ok {
+			renditions = append(renditions, Rendition{
+				Language:   language,
+				URI:        p.uri(language),
+				Translated: true,
+			})
+		}
+	}
+
+	return renditions
+}
+
+func (p *Publisher) dir(language string) string {
+	return filepath.Join(p.config.OutputPath, DirName, language)
+}
+
+func (p *Publisher) uri(language string) string {
+	return fmt.Sprintf("%s/%s/playlist.m3u8", DirName, language)
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Config ...
+// Spans are sent to a local collector in the Zipkin v2 JSON format, which Jaeger and the OpenTelemetry collector accept
+type Config struct {
+	Enabled       bool    `json:"enabled"`
+	Endpoint      string  `json:"endpoint"`      // e.g. Jaeger's Zipkin endpoint, http://localhost:9411/api/v2/spans
+	ServiceName   string  `json:"serviceName"`   // Service the spans are reported under
+	SampleRate    float64 `json:"sampleRate"`    // Share of segments traced, between 0 and 1
+	BatchSize     int     `json:"batchSize"`     // Spans sent per request
+	FlushInterval float64 `json:"flushInterval"` // Seconds between requests when batches don't fill up
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Enabled:       false,
+		Endpoint:      "http://localhost:9411/api/v2/spans",
+		ServiceName:   "vsr",
+		SampleRate:    1,
+		BatchSize:     100,
+		FlushInterval: 5,
+	}
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("invalid tracing endpoint %q, expected an http(s) URL", c.Endpoint)
+	}
+
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("invalid tracing sample rate %v, expected a value between 0 and 1", c.SampleRate)
+	}
+
+	if c.BatchSize <= 0 || c.FlushInterval <= 0 {
+		return errors.New("tracing batch size and flush interval must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/logging"
+	"server/metrics"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+var spansTotal = metrics.NewCounterVec(
+	"vsr_trace_spans_total",
+	"Spans handed to the collector by result (exported, failed, dropped when the queue was full)",
+	"result",
+)
+
+// queueBatches bounds the spans waiting to be sent while the collector is unreachable, in batches
+const queueBatches = 10
+
+// Exporter ...
+// Batches sampled spans and sends them to the collector in the background
+type Exporter struct {
+	config Config
+	client *http.Client
+	log    *logging.Logger
+
+	mu      sync.Mutex
+	pending []*trace.SpanData
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Start ...
+// Registers the exporter and samples the configured share of traces, nothing is traced when disabled
+func Start(config Config) (*Exporter, error) {
+	if !config.Enabled {
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+		return nil, nil
+	}
+
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Exporter{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		log:    logging.New("tracing", "endpoint", config.Endpoint),
+		flush:  make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(config.SampleRate)})
+	trace.RegisterExporter(e)
+
+	go e.run()
+	return e, nil
+}
+
+// ExportSpan ...
+func (e *Exporter) ExportSpan(s *trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.pending) >= queueBatches*e.config.BatchSize {
+		spansTotal.Inc("dropped")
+		return
+	}
+
+	e.pending = append(e.pending, s)
+	if len(e.pending) >= e.config.BatchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Shutdown ...
+// Stops exporting and sends the spans still waiting, giving up when the context is done
+func (e *Exporter) Shutdown(ctx context.Context) {
+	trace.UnregisterExporter(e)
+	close(e.stop)
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		e.log.Warn("Could not send the remaining spans in time")
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(time.Duration(e.config.FlushInterval * float64(time.Second)))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			e.send()
+			return
+		case <-e.flush:
+		case <-ticker.C:
+		}
+
+		e.send()
+	}
+}
+
+// send posts the waiting spans in batches, a batch the collector didn't accept is dropped
+func (e *Exporter) send() {
+	for {
+		e.mu.Lock()
+		size := len(e.pending)
+		if size > e.config.BatchSize {
+			size = e.config.BatchSize
+		}
+		batch := e.pending[:size]
+		e.pending = e.pending[size:]
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		err := e.post(batch)
+		if err != nil {
+			e.log.Warn("Could not send spans", "spans", len(batch), "err", err)
+			spansTotal.Add(float64(len(batch)), "failed")
+			return
+		}
+
+		spansTotal.Add(float64(len(batch)), "exported")
+	}
+}
+
+func (e *Exporter) post(batch []*trace.SpanData) error {
+	spans := make([]zipkinSpan, 0, len(batch))
+	for _, s := range batch {
+		spans = append(spans, toZipkin(e.config.ServiceName, s))
+	}
+
+	raw, err := json.Marshal(spans)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.config.Endpoint, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+
+	return nil
+}
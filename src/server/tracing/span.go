@@ -0,0 +1,15 @@
+package tracing
+
+import (
+	"go.opencensus.io/trace"
+)
+
+// End ...
+// Ends the span, marking it failed with the error if there is one
+func End(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+
+	span.End()
+}
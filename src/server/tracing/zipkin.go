@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.opencensus.io/trace"
+)
+
+// zipkinSpan ...
+// https://zipkin.io/zipkin-api/#/default/post_spans
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId,omitempty"`
+	Name          string             `json:"name"`
+	Kind          string             `json:"kind,omitempty"`
+	Timestamp     int64              `json:"timestamp"` // Microseconds since the epoch
+	Duration      int64              `json:"duration"`  // Microseconds
+	LocalEndpoint zipkinEndpoint     `json:"localEndpoint"`
+	Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// toZipkin converts an opencensus span, its status becomes the error tag collectors flag failed spans with
+func toZipkin(service string, s *trace.SpanData) zipkinSpan {
+	span := zipkinSpan{
+		TraceID:       s.TraceID.String(),
+		ID:            s.SpanID.String(),
+		Name:          s.Name,
+		Timestamp:     s.StartTime.UnixNano() / 1e3,
+		Duration:      s.EndTime.Sub(s.StartTime).Nanoseconds() / 1e3,
+		LocalEndpoint: zipkinEndpoint{ServiceName: service},
+		Tags:          make(map[string]string, len(s.Attributes)+2),
+	}
+
+	if s.ParentSpanID != (trace.SpanID{}) {
+		span.ParentID = s.ParentSpanID.String()
+	}
+
+	switch s.SpanKind {
+	case trace.SpanKindClient:
+		span.Kind = "CLIENT"
+	case trace.SpanKindServer:
+		span.Kind = "SERVER"
+	}
+
+	// Durations under a microsecond would be reported as missing
+	if span.Duration < 1 {
+		span.Duration = 1
+	}
+
+	for key, value := range s.Attributes {
+		switch v := value.(type) {
+		case string:
+			span.Tags[key] = v
+		case bool:
+			span.Tags[key] = strconv.FormatBool(v)
+		case int64:
+			span.Tags[key] = strconv.FormatInt(v, 10)
+		case float64:
+			span.Tags[key] = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			span.Tags[key] = fmt.Sprint(v)
+		}
+	}
+
+	if s.Code != trace.StatusCodeOK {
+		span.Tags["error"] = s.Message
+		if s.Message == "" {
+			span.Tags["error"] = fmt.Sprintf("status code %d", s.Code)
+		}
+	}
+
+	for _, annotation := range s.Annotations {
+		span.Annotations = append(span.Annotations, zipkinAnnotation{
+			Timestamp: annotation.Time.UnixNano() / 1e3,
+			Value:     annotation.Message,
+		})
+	}
+
+	return span
+}
@@ -0,0 +1,96 @@
+package transcriber
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"server/metrics"
+	"server/transcriber/vad"
+	"strconv"
+	"time"
+)
+
+// opusOutput are the ffmpeg output arguments for the ogg opus audio sent for recognition
+var opusOutput = []string{
+	"-fflags", "+bitexact", // Identical audio gives identical bytes, without a random stream serial, so it can be cached
+	"-f", "opus", // Providing a format hint since ffmpeg cannot detect the format through conventional means (e.g. filename extension sniffing)
+	"-acodec", "libopus",
+	"-b:a", "64k",
+	"-ar", "16000",
+	"-ac", "1",
+	"pipe:1",
+}
+
+var (
+	vadSegmentsTotal = metrics.NewCounterVec(
+		"vsr_vad_segments_total",
+		"Segments classified by voice activity detection, by channel and decision (speech, trimmed or skipped)",
+		"channel", "decision",
+	)
+	vadSkippedSeconds = metrics.NewCounterVec(
+		"vsr_vad_skipped_seconds_total",
+		"Seconds of audio not sent for recognition because they held no speech",
+		"channel",
+	)
+)
+
+// extractAudio converts the segment to the audio sent for recognition. With voice activity detection, segments without
+// speech aren't sent at all and the non-speech around it is trimmed, the offset is where the sent audio starts.
+func (t *Transcriber) extractAudio(blob []byte, duration float64) ([]byte, time.Duration, *vad.Decision, error) {
+	if !t.vad.Enabled {
+		/* Extracting the audio stream from mp4 and converting to ogg */
+		audio, err := t.runEncoder(blob, append([]string{"-i", "pipe:0", "-vn"}, opusOutput...)...)
+		return audio, 0, nil, err
+	}
+
+	pcm, err := t.runEncoder(blob,
+		"-i", "pipe:0",
+		"-vn",
+		"-f", "s16le",
+		"-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(vad.SampleRate),
+		"-ac", "1",
+		"pipe:1",
+	)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	decision := vad.Detect(t.vad, pcm)
+	if !decision.Speech {
+		vadSegmentsTotal.Inc(t.channel, "skipped")
+		vadSkippedSeconds.Add(duration, t.channel)
+		return nil, 0, &decision, nil
+	}
+
+	speech, offset := pcm, time.Duration(0)
+	if decision.Trimmed(float64(len(pcm)/2) / vad.SampleRate) {
+		speech, offset = vad.Trim(decision, pcm)
+		vadSegmentsTotal.Inc(t.channel, "trimmed")
+		vadSkippedSeconds.Add(float64((len(pcm)-len(speech))/2)/vad.SampleRate, t.channel)
+	} else {
+		vadSegmentsTotal.Inc(t.channel, "speech")
+	}
+
+	input := []string{"-f", "s16le", "-ar", strconv.Itoa(vad.SampleRate), "-ac", "1", "-i", "pipe:0"}
+	audio, err := t.runEncoder(speech, append(input, opusOutput...)...)
+
+	return audio, offset, &decision, err
+}
+
+// runEncoder pipes the input through ffmpeg
+func (t *Transcriber) runEncoder(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(t.ctx, t.encoderPath, args...)
+
+	var outb bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &outb
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg: %v", err)
+	}
+
+	return outb.Bytes(), nil
+}
@@ -1,132 +1,423 @@
 package transcriber
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"server/hls"
+	"server/logging"
+	"server/metrics"
+	"server/store"
+	"server/subtitles"
+	"server/tracing"
+	"server/transcriber/filters"
 	"server/transcriber/recognizers"
-	"server/transcriber/recognizers/gcp"
+	"server/transcriber/recognizers/cache"
+	"server/transcriber/recognizers/failover"
+	"server/transcriber/slo"
 	"server/transcriber/utils"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opencensus.io/trace"
 )
 
-var state = State{}
+var detectedTotal = metrics.NewCounterVec(
+	"vsr_segment_languages_total",
+	"Transcribed segments by channel and detected spoken language",
+	"channel", "language",
+)
 
-// Start ...
-func Start(encoderPath string, outputPath string, segmentsPath string) {
+// New ...
+func New(config Config) (*Transcriber, error) {
+	recognizer, err := NewRecognizer(config.Recognizer, config.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := filters.New(config.Channel, config.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var fallback recognizers.Adapter
+	fallbackWith, ok := config.Budget.Recognizer(config.Recognizer)
+	if ok {
+		fallback, err = NewRecognizer(fallbackWith, config.Channel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fast recognizers.Adapter
+	fastWith, ok := config.Latency.Recognizer(config.Recognizer)
+	if ok {
+		fast, err = NewRecognizer(fastWith, config.Channel)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	state.encoderPath = encoderPath
-	state.outputPath = outputPath
-	state.segmentsPath = segmentsPath
+	if config.Cache != nil {
+		recognizer = cache.Wrap(config.Cache, config.Recognizer, recognizer)
+		if fallback != nil {
+			fallback = cache.Wrap(config.Cache, fallbackWith, fallback)
+		}
+		if fast != nil {
+			fast = cache.Wrap(config.Cache, fastWith, fast)
+		}
+	}
 
-	state.recognizer = &gcp.Adapter{}
-	state.processing = false
-	state.pruning = false
+	if config.Meter != nil {
+		config.Meter.Track(config.Channel, config.Budget)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &Transcriber{
+		ctx:          ctx,
+		cancel:       cancel,
+		encoderPath:  config.EncoderPath,
+		outputPath:   config.OutputPath,
+		segmentsPath: config.SegmentsPath,
+		channel:      config.Channel,
+		log:          logging.New("transcriber", "channel", config.Channel),
+		store:        config.Store,
+		recognition:  config.Recognizer,
+		filter:       filter,
+		subtitles:    config.Subtitles,
+		vad:          config.VAD,
+		mediaEnd:     -1,
+		periodStart:  -1,
+		recognizer:   recognizer,
+		meter:        config.Meter,
+		fallback:     fallback,
+		fallbackWith: fallbackWith,
+		latency:      config.Latency,
+		degradation:  slo.New(config.Latency),
+		fast:         fast,
+		fastWith:     fastWith,
+		processing:   false,
+		pruning:      false,
+	}
 
-	state.playlistInfo = PlaylistInfo{
+	// Continuing the channel's media timeline from its history, so it stays unique across restarts
+	if t.store != nil {
+		end, err := t.store.MediaEnd(t.channel)
+		if err != nil {
+			return nil, err
+		}
+
+		if end > 0 {
+			t.mediaEnd = end
+		}
+	}
+
+	t.playlistInfo = PlaylistInfo{
 		Init: SegmentInfo{
 			Filename: "",
 		},
 		Segments: make(map[string]SegmentInfo),
 	}
 
+	return t, nil
+}
+
+// Start ...
+func (t *Transcriber) Start() error {
 	/* Creating the sub-dir for outputting transcription results */
-	err := os.MkdirAll(state.outputPath, 0777)
+	err := os.MkdirAll(t.outputPath, 0777)
 	if err != nil {
-		fmt.Printf("[Start] Could not create transcription output sub-dir in temporary output directory: %v", err)
-		return
+		t.log.Error("Could not create transcription output sub-dir in temporary output directory", "err", err)
+		return err
+	}
+
+	t.recognizer.Init()
+	if t.fallback != nil {
+		t.fallback.Init()
+	}
+	if t.fast != nil {
+		t.fast.Init()
 	}
 
-	utils.SetInterval(processNewSegments, 1000, true)
-	utils.SetInterval(pruneOldTranscripts, 1000, true)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.intervals = append(t.intervals,
+		utils.SetInterval(t.processNewSegments, 1000, true),
+		utils.SetInterval(t.pruneOldTranscripts, 1000, true),
+	)
+
+	return nil
 }
 
-func processNewSegments() {
-	if state.processing {
-		fmt.Println("[processSegments] still processing...")
+// Shutdown ...
+// Stops picking up new segments and waits for in-flight transcriptions to finish and be written,
+// abandoning them if the context is done first
+func (t *Transcriber) Shutdown(ctx context.Context) {
+	t.stopIntervals()
+
+	drained := make(chan struct{})
+	go func() {
+		t.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		t.log.Warn("In-flight transcriptions did not finish in time, abandoning")
+		t.cancel()
+		<-drained
+	}
+
+	t.cancel()
+}
+
+func (t *Transcriber) stopIntervals() {
+	t.mu.Lock()
+	intervals := t.intervals
+	t.intervals = nil
+	t.stopping = true
+	t.mu.Unlock()
+
+	for _, clear := range intervals {
+		clear <- true
+	}
+
+	segmentsPending.Delete(t.channel)
+	degradationLevel.Delete(t.channel)
+}
+
+func (t *Transcriber) isStopping() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stopping
+}
+
+func (t *Transcriber) processNewSegments() {
+	if !t.begin(&t.processing) {
+		t.log.Debug("Still processing segments")
 		return
 	}
 
-	state.processing = true
+	defer t.end(&t.processing)
 
-	files, err := ioutil.ReadDir(state.segmentsPath)
+	// Only segments listed in the playlist are complete, ffmpeg is still writing to the newest file
+	playlist, err := hls.ReadMediaPlaylist(fmt.Sprintf("%s/%s", t.segmentsPath, "playlist.m3u8"))
 	if err != nil {
-		fmt.Println("[processSegments] could not read segment list")
-		state.processing = false
+		t.log.Debug("Could not read segment playlist", "err", err)
 		return
 	}
 
-	for _, fileInfo := range files {
-		filename := fileInfo.Name()
+	// Handle the init segment if it hasn't been handled yet
+	if t.initFilename() == "" && playlist.Map != "" {
+		t.log.Info("Storing init filename", "init", playlist.Map)
+		t.mu.Lock()
+		t.playlistInfo.Init.Filename = playlist.Map
+		t.mu.Unlock()
+	}
 
-		// Handle the init segment if it hasn't been handled yet
-		if state.playlistInfo.Init.Filename == "" && strings.Contains(filename, "init") {
-			fmt.Println("[processSegments] storing init filename: ", filename)
-			state.playlistInfo.Init.Filename = filename
-			continue
-		}
+	listed := t.timeSegments(playlist)
+
+	t.mu.Lock()
+	previous := t.listed
+	t.listed = listed
+	t.mu.Unlock()
 
-		_, segmentKnown := state.playlistInfo.Segments[filename]
+	t.countMissed(previous, listed)
+
+	queue := make([]SegmentInfo, 0)
+	for _, segment := range listed {
+		known, segmentKnown := t.segment(segment.Filename)
 		if segmentKnown {
 			// If it's not in an errored state, continue iterating
 			// This effectively allows us to "retry" a failed transcription for a specific segment
-			if state.playlistInfo.Segments[filename].State != "errored" {
+			if known.State != "errored" {
 				continue
 			}
+		} else {
+			segmentsDiscovered.Inc(t.channel)
+			t.segmentLog(segment).Debug("Segment discovered", "sequence", segment.Sequence, "duration", segment.Duration,
+				"media_start", segment.MediaStart)
 		}
 
-		// TODO use WebVTT - no json condition needed
-		isAudio := strings.Contains(filename, ".m4s") && !strings.Contains(filename, ".json")
-		if isAudio {
-			fmt.Printf("[processSegments] processing audio file: %s \n", filename)
+		queue = append(queue, segment)
+	}
+
+	segmentsPending.Set(float64(len(queue)), t.channel)
+	remaining := int64(len(queue))
+
+	// Segments are transcribed one at a time, unless the concurrency mode is applied to catch up with the live edge
+	slots := make(chan struct{}, t.concurrency())
+	var wg sync.WaitGroup
+
+	for _, segment := range queue {
+		// Only the segments already being transcribed are finished when stopping
+		if t.isStopping() {
+			break
+		}
+
+		if !t.degradation.Active(slo.Concurrency) {
+			wg.Wait()
+		}
+
+		lag := t.measureLag(&segment)
+
+		slots <- struct{}{}
+		wg.Add(1)
 
-			segment := SegmentInfo{
-				Filename: filename,
-				State:    "processing",
+		go func(segment SegmentInfo) {
+			defer wg.Done()
+
+			t.transcribe(segment, lag)
+
+			<-slots
+			segmentsPending.Set(float64(atomic.AddInt64(&remaining, -1)), t.channel)
+		}(segment)
+	}
+
+	wg.Wait()
+}
+
+// transcribe runs the segment through the pipeline, or publishes it without captions when too far behind the live edge
+func (t *Transcriber) transcribe(segment SegmentInfo, lag float64) {
+	log := t.segmentLog(segment)
+	log.Debug("Processing segment", "lag", lag)
+
+	segment.State = "processing"
+	t.setSegment(segment)
+
+	// Each segment is its own trace, with a span per step of the pipeline
+	ctx, span := trace.StartSpan(t.ctx, "vsr.segment")
+	span.AddAttributes(
+		trace.StringAttribute("channel", t.channel),
+		trace.StringAttribute("segment", segment.ID),
+		trace.StringAttribute("file", segment.Filename),
+		trace.Int64Attribute("sequence", int64(segment.Sequence)),
+		trace.Float64Attribute("duration", segment.Duration),
+		trace.Float64Attribute("lag", lag),
+	)
+	ctx = logging.NewContext(ctx, log)
+
+	var err error
+	if t.degradation.Active(slo.Skip) && lag > t.latency.Target {
+		log.Warn("Too far behind the live edge, publishing the segment without captions", "lag", lag)
+		segment.Skipped = true
+		segmentsSkipped.Inc(t.channel)
+		err = t.record(ctx, segment, recognizers.Response{Words: make([]recognizers.TimedWord, 0)})
+	} else {
+		err = t.processAudio(ctx, segment)
+	}
+
+	if err != nil {
+		log.Error("Segment failed, retrying while it's listed", "err", err)
+		segment.State = "errored"
+		segmentsFailed.Inc(t.channel)
+		t.countFailure(true)
+
+		// Keeping the subtitle playlists aligned with the media until the retry succeeds
+		t.publish(ctx, segment, nil)
+	} else {
+		segment.State = "processed"
+		segmentsProcessed.Inc(t.channel)
+		t.countFailure(false)
+	}
+
+	tracing.End(span, err)
+
+	t.setSegment(segment)
+
+	log.Debug("Segment done", "state", segment.State)
+}
+
+// timeSegments places the playlist's segments on the channel's media timeline, continuing from the segments seen before
+func (t *Transcriber) timeSegments(playlist hls.MediaPlaylist) []SegmentInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]SegmentInfo, 0, len(playlist.Segments))
+	cursor := -1.0
+	var clock *time.Time
+
+	for _, segment := range playlist.Segments {
+		info, known := t.playlistInfo.Segments[segment.URI]
+		if !known {
+			info = SegmentInfo{
+				ID:        logging.NewID(),
+				Filename:  segment.URI,
+				Sequence:  segment.Sequence,
+				Duration:  segment.Duration,
+				WallClock: segment.ProgramDateTime,
 			}
 
-			state.playlistInfo.Segments[filename] = segment
+			switch {
+			case cursor >= 0:
+				info.MediaStart = cursor
+			case t.mediaEnd >= 0:
+				info.MediaStart = t.mediaEnd
+			default:
+				// The very first segment, anything before it has been deleted already
+				info.MediaStart = float64(segment.Sequence * playlist.TargetDuration)
+			}
 
-			filepath := fmt.Sprintf("%s/%s", state.segmentsPath, filename)
-			err := processAudio(filepath)
-			if err != nil {
-				segment.State = "processed"
-			} else {
-				segment.State = "errored"
+			// ffmpeg only dates some of the segments, the rest follow on from the previous one
+			if info.WallClock == nil && clock != nil {
+				info.WallClock = clock
 			}
 
-			fmt.Printf("[processSegments] processed audio file: %s \n", filename)
-		} else {
-			// fmt.Println("[processSegments] ignoring non-audio file: ", filename)
+			// The restarted encoder's timestamps begin at zero again
+			if segment.Discontinuity || t.periodStart < 0 {
+				info.Discontinuity = segment.Discontinuity
+				t.periodStart = info.MediaStart
+			}
+
+			info.PeriodStart = t.periodStart
 		}
+
+		if info.WallClock != nil {
+			next := info.WallClock.Add(time.Duration(info.Duration * float64(time.Second)))
+			clock = &next
+		}
+
+		cursor = info.MediaStart + info.Duration
+		if cursor > t.mediaEnd {
+			t.mediaEnd = cursor
+		}
+
+		result = append(result, info)
 	}
 
-	state.processing = false
+	return result
 }
 
-func pruneOldTranscripts() {
-	if state.pruning {
-		fmt.Println("[pruneOldTranscripts] still pruning...")
+func (t *Transcriber) pruneOldTranscripts() {
+	if !t.begin(&t.pruning) {
+		t.log.Debug("Still pruning transcripts")
 		return
 	}
 
-	state.pruning = true
+	defer t.end(&t.pruning)
 
-	files, err := ioutil.ReadDir(state.segmentsPath)
+	files, err := ioutil.ReadDir(t.segmentsPath)
 	if err != nil {
-		fmt.Println("[pruneOldTranscripts] could not read segment list")
-		state.pruning = false
+		t.log.Warn("Could not read segment list", "err", err)
 		return
 	}
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	/* Scanning through the known segments - if a known segment doesn't exist in the latest files list, prune it */
 	toDelete := make([]string, 0)
 
-	for filename := range state.playlistInfo.Segments {
+	for filename := range t.playlistInfo.Segments {
 		found := false
 
 		for _, fileInfo := range files {
@@ -141,8 +432,8 @@ func pruneOldTranscripts() {
 			continue
 		}
 
-		transcriptPath := fmt.Sprintf("%s/%s", state.outputPath, fmt.Sprintf("%s.json", filename))
-		fmt.Println("[pruneOldTranscripts] removing: ", transcriptPath)
+		transcriptPath := fmt.Sprintf("%s/%s", t.outputPath, fmt.Sprintf("%s.json", filename))
+		t.log.Debug("Removing transcript", "segment", t.playlistInfo.Segments[filename].ID, "file", filename)
 
 		/* Removing the file */
 		os.Remove(transcriptPath)
@@ -152,84 +443,369 @@ func pruneOldTranscripts() {
 	}
 
 	for _, filename := range toDelete {
-		delete(state.playlistInfo.Segments, filename)
+		delete(t.playlistInfo.Segments, filename)
+	}
+}
+
+// begin marks a periodic task as running, returning false if it's already in progress or the transcriber is stopping
+func (t *Transcriber) begin(running *bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if *running || t.stopping {
+		return false
 	}
 
-	state.pruning = false
+	*running = true
+	t.inFlight.Add(1)
+	return true
 }
 
-func processAudio(segmentPath string) error {
-	fmt.Println("[processAudio] for: ", segmentPath)
-	_, segmentFilename := filepath.Split(segmentPath)
+func (t *Transcriber) end(running *bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	// Reading the segment into memory
-	mdat, err := ioutil.ReadFile(segmentPath)
+	*running = false
+	t.inFlight.Done()
+}
+
+func (t *Transcriber) initFilename() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.playlistInfo.Init.Filename
+}
+
+func (t *Transcriber) segment(filename string) (SegmentInfo, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	segment, ok := t.playlistInfo.Segments[filename]
+	return segment, ok
+}
+
+func (t *Transcriber) setSegment(segment SegmentInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.playlistInfo.Segments[segment.Filename] = segment
+}
+
+func (t *Transcriber) processAudio(ctx context.Context, segment SegmentInfo) error {
+	// Recognizers log with the segment's correlation ID too
+	log := t.segmentLog(segment)
+	ctx = logging.NewContext(ctx, log)
+
+	_, span := trace.StartSpan(ctx, "vsr.read")
+	blob, err := t.readSegment(&segment)
+	tracing.End(span, err)
 	if err != nil {
-		fmt.Println("[processAudio] failed to read file: ", err)
+		log.Error("Could not read segment", "err", err)
 		return err
 	}
 
-	// Reading the init segment into memory
-	initSegmentPath := fmt.Sprintf("%s/%s", state.segmentsPath, state.playlistInfo.Init.Filename)
-	init, err := ioutil.ReadFile(initSegmentPath)
+	_, span = trace.StartSpan(ctx, "vsr.extract_audio")
+	audio, offset, speech, err := t.extractAudio(blob, segment.Duration)
+	span.AddAttributes(trace.Int64Attribute("bytes", int64(len(audio))))
+	if speech != nil {
+		span.AddAttributes(trace.BoolAttribute("speech", speech.Speech), trace.Float64Attribute("speech_ratio", speech.Ratio))
+	}
+	tracing.End(span, err)
 	if err != nil {
-		fmt.Println("[processAudio] failed to read init segment: ", err)
+		log.Error("Could not extract audio stream", "err", err)
 		return err
 	}
 
-	blob := append(init, mdat...)
+	log.Debug("Audio extracted", "bytes", len(audio), "offset", offset)
+
+	segment.Speech = speech
+
+	// Nothing is sent for recognition without speech, the segment's captions stay empty
+	if speech != nil && !speech.Speech {
+		log.Info("No speech detected, skipping recognition", "speech_ratio", speech.Ratio)
+		return t.record(ctx, segment, recognizers.Response{Words: make([]recognizers.TimedWord, 0)})
+	}
+
+	recognizer, recognition := t.currentRecognizer()
+	if recognizer == nil {
+		log.Warn("Budget exhausted, skipping recognition")
+		segment.Paused = true
+		return t.record(ctx, segment, recognizers.Response{Words: make([]recognizers.TimedWord, 0)})
+	}
+
+	// The recognition RPC's own span is a child of this one, the gcp client traces its calls with ocgrpc
+	recognizeCtx, span := trace.StartSpan(ctx, "vsr.recognize")
+	span.AddAttributes(trace.StringAttribute("recognizer", recognition.ID()))
 
-	/* Extracting the audio stream from mp4 and converting to ogg */
-	cmd := exec.Command(
-		state.encoderPath,
-		"-i", "pipe:0",
-		"-f", "opus",  // Providing a format hint since ffmpeg cannot detect the format through conventional means (e.g. filename extension sniffing)
-		"-vn",
-		"-acodec", "libopus",
-		"-b:a", "64k",
-		"-ar", "16000",
-		"-ac", "1",
-		"pipe:1",
+	started := time.Now()
+	resp, err := recognizer.Input(recognizeCtx, audio)
+	span.AddAttributes(
+		trace.StringAttribute("provider", resp.Provider),
+		trace.BoolAttribute("cached", resp.Cached),
+		trace.Int64Attribute("words", int64(len(resp.Words))),
 	)
+	tracing.End(span, err)
+	if err != nil {
+		recognitionSeconds.Observe(time.Since(started).Seconds(), t.channel, recognition.ID(), "error")
+		log.Error("Could not transcribe audio", "recognizer", recognition.ID(), "err", err)
+		return err
+	}
+
+	// Failover chains and ensembles tag the recognizers that answered, which are also the ones billed
+	if resp.Provider == "" {
+		resp.Provider = recognition.ID()
+	}
+
+	if !resp.Cached {
+		recognitionSeconds.Observe(time.Since(started).Seconds(), t.channel, resp.Provider, "ok")
+	}
+
+	if t.meter != nil && !resp.Cached {
+		seconds := segment.Duration
+		if speech != nil {
+			seconds = speech.End - speech.Start
+		}
+
+		for _, provider := range strings.Split(resp.Provider, recognizers.ProviderSeparator) {
+			billed, ok := recognition.Member(provider)
+			if !ok {
+				billed = recognition
+			}
+
+			t.meter.Record(t.channel, billed, seconds)
+		}
+	}
+
+	// Word times are relative to the audio sent, which may have been trimmed
+	if offset > 0 {
+		for i, word := range resp.Words {
+			resp.Words[i] = word.Offset(offset)
+		}
+	}
+
+	log.Info("Transcribed segment", "provider", resp.Provider, "words", len(resp.Words), "confidence", resp.Confidence,
+		"cached", resp.Cached, "seconds", time.Since(started).Seconds())
+
+	// Words are routed to the subtitles of the language they were spoken in
+	segment.Language = t.recognition.MatchLanguage(resp.Language)
+	resp.Language = segment.Language
+	detectedTotal.Inc(t.channel, segment.Language)
+
+	return t.record(ctx, segment, resp)
+}
+
+// readSegment reads the init segment followed by the media segment, noting when the media segment was written
+func (t *Transcriber) readSegment(segment *SegmentInfo) ([]byte, error) {
+	segmentPath := fmt.Sprintf("%s/%s", t.segmentsPath, segment.Filename)
+
+	mdat, err := ioutil.ReadFile(segmentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The caption delay is measured from the moment ffmpeg finished writing the segment
+	if info, err := os.Stat(segmentPath); err == nil {
+		segment.Written = info.ModTime()
+	}
+
+	initSegmentPath := fmt.Sprintf("%s/%s", t.segmentsPath, t.initFilename())
+	init, err := ioutil.ReadFile(initSegmentPath)
+	if err != nil {
+		return nil, fmt.Errorf("init segment: %v", err)
+	}
+
+	return append(init, mdat...), nil
+}
+
+// Recognizers ...
+// The health of the channel's failover chain, empty without one
+func (t *Transcriber) Recognizers() []failover.Status {
+	recognizer := t.recognizer
+	if cached, ok := recognizer.(*cache.Adapter); ok {
+		recognizer = cached.Unwrap()
+	}
+
+	if chain, ok := recognizer.(*failover.Adapter); ok {
+		return chain.Status()
+	}
+
+	return []failover.Status{}
+}
+
+// currentRecognizer is the channel's recognizer while within budget, then the fallback, nil when recognition is paused
+// The fast-model degradation mode only applies within budget
+func (t *Transcriber) currentRecognizer() (recognizers.Adapter, recognizers.Config) {
+	if t.meter != nil && t.meter.Exceeded(t.channel) != "" {
+		return t.fallback, t.fallbackWith
+	}
+
+	if t.fast != nil && t.degradation.Active(slo.FastModel) {
+		return t.fast, t.fastWith
+	}
+
+	return t.recognizer, t.recognition
+}
+
+// segmentLog logs with the segment's correlation ID, which follows it from discovery to publication
+func (t *Transcriber) segmentLog(segment SegmentInfo) *logging.Logger {
+	return t.log.With("segment", segment.ID, "file", segment.Filename)
+}
 
-	var outb bytes.Buffer
+// record filters, writes, stores and publishes the segment's transcript
+func (t *Transcriber) record(ctx context.Context, segment SegmentInfo, resp recognizers.Response) error {
+	// Only filtered words are ever published, the history keeps the original ones for compliance review
+	published := resp
+	var unfiltered []recognizers.TimedWord
+
+	words, filtered := t.filter.Apply(resp.Words, segment.Language)
+	if filtered > 0 {
+		t.segmentLog(segment).Info("Filtered words", "filtered", filtered)
+		published.Words = words
+		unfiltered = resp.Words
+	}
 
-	cmd.Stdin = bytes.NewReader(blob)
-	cmd.Stdout = &outb
+	_, span := trace.StartSpan(ctx, "vsr.write")
+	err := t.writeTranscriptionForSegment(published, segment)
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
 
-	err = cmd.Run()
+	err = t.publish(ctx, segment, published.Words)
 	if err != nil {
-		fmt.Printf("[processAudio] Could not extract audio stream, err: %v \n", err)
 		return err
 	}
 
-	resp, err := state.recognizer.Input(outb.Bytes())
+	// Stored last, alert rules are evaluated as entries are stored and a retried segment must not fire them twice
+	_, span = trace.StartSpan(ctx, "vsr.store")
+	err = t.storeTranscription(published, unfiltered, segment)
+	tracing.End(span, err)
 	if err != nil {
-		fmt.Println("[processAudio] Error transcribing audio data: ", err)
-	} else {
-		fmt.Println("[processAudio] Successfully transcribed audio for segment: ", segmentPath)
-		writeTranscriptionForSegment(resp, fmt.Sprintf("%s/%s", state.outputPath, segmentFilename))
+		return err
+	}
+
+	t.mu.Lock()
+	t.published = time.Now()
+	t.mu.Unlock()
+
+	if !segment.Written.IsZero() {
+		delay := time.Since(segment.Written).Seconds()
+		captionDelay.Observe(delay, t.channel)
+
+		if t.latency.Enabled {
+			switch {
+			case segment.Skipped:
+				captionSLO.Inc(t.channel, "skipped")
+			case delay <= t.latency.Target:
+				captionSLO.Inc(t.channel, "met")
+			default:
+				captionSLO.Inc(t.channel, "missed")
+			}
+		}
+	}
+
+	if len(resp.Words) > 0 {
+		confidence.Observe(float64(resp.Confidence), t.channel)
 	}
 
 	return nil
 }
 
-func writeTranscriptionForSegment(data recognizers.Response, path string) error {
-	fmt.Println("[writeTranscriptionForSegment] Transcription received: ", data)
+// publish writes the segment's captions to the live subtitle renditions
+func (t *Transcriber) publish(ctx context.Context, segment SegmentInfo, words []recognizers.TimedWord) error {
+	if t.subtitles == nil {
+		return nil
+	}
 
-	filepath := fmt.Sprintf("%v.json", path)
+	ctx, span := trace.StartSpan(ctx, "vsr.build_captions")
+	span.AddAttributes(trace.Int64Attribute("words", int64(len(words))))
+
+	err := t.subtitles.Publish(ctx, subtitles.Segment{
+		Sequence:      segment.Sequence,
+		Duration:      segment.Duration,
+		Discontinuity: segment.Discontinuity,
+		Start:         segment.MediaStart - segment.PeriodStart,
+	}, words, segment.Language)
+	if err != nil {
+		t.segmentLog(segment).Error("Could not publish subtitles", "err", err)
+	}
+
+	tracing.End(span, err)
+	return err
+}
+
+func (t *Transcriber) writeTranscriptionForSegment(data recognizers.Response, segment SegmentInfo) error {
+	log := t.segmentLog(segment)
+
+	filepath := fmt.Sprintf("%s/%s.json", t.outputPath, segment.Filename)
 	raw, err := json.Marshal(data)
 	if err != nil {
-		fmt.Println("[writeTranscriptionForSegment] Could not convert speech response to byte array: ", err)
+		log.Error("Could not convert speech response to byte array", "err", err)
 		return err
 	}
 
-	fmt.Printf("[writeTranscriptionForSegment] Writing transcription to %s", filepath)
+	log.Debug("Writing transcription", "path", filepath, "transcript", string(raw))
 	err = ioutil.WriteFile(filepath, raw, 0644)
 	if err != nil {
-		fmt.Printf("[writeTranscriptionForSegment] Could not write to path %v, error was %v \n", filepath, err)
+		log.Error("Could not write transcription", "path", filepath, "err", err)
 		return err
 	}
 
 	return nil
 }
+
+// storeTranscription adds the transcript to the channel's history, which outlives the live window
+func (t *Transcriber) storeTranscription(data recognizers.Response, unfiltered []recognizers.TimedWord, segment SegmentInfo) error {
+	if t.store == nil {
+		return nil
+	}
+
+	err := t.store.Append(store.Entry{
+		Channel:       t.channel,
+		Segment:       segment.Filename,
+		CorrelationID: segment.ID,
+		Sequence:      segment.Sequence,
+		MediaStart:    segment.MediaStart,
+		Duration:      segment.Duration,
+		WallClock:     segment.WallClock,
+		Confidence:    data.Confidence,
+		Language:      segment.Language,
+		Speaker:       mainSpeaker(data.Words),
+		Speech:        segment.Speech,
+		Paused:        segment.Paused,
+		Skipped:       segment.Skipped,
+		Provider:      data.Provider,
+		Disagreement:  data.Disagreement,
+		Cached:        data.Cached,
+		Words:         data.Words,
+		Unfiltered:    unfiltered,
+	})
+	if err != nil {
+		t.segmentLog(segment).Error("Could not store transcription", "err", err)
+	}
+
+	return err
+}
+
+// mainSpeaker is the speaker of most of the words, empty without diarization
+func mainSpeaker(words []recognizers.TimedWord) string {
+	counts := make(map[int]int)
+	speaker := 0
+
+	for _, word := range words {
+		if word.Speaker == 0 {
+			continue
+		}
+
+		counts[word.Speaker]++
+		if counts[word.Speaker] > counts[speaker] {
+			speaker = word.Speaker
+		}
+	}
+
+	if speaker == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(speaker)
+}
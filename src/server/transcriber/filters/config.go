@@ -0,0 +1,72 @@
+package filters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions applied to a filtered word
+const (
+	Mask    = "mask"    // Keeps the first letter, e.g. "d***"
+	Replace = "replace" // Substitutes the replacement text
+	Drop    = "drop"    // Removes the word from the captions entirely
+)
+
+// AnyLanguage ...
+// The list key applying to every language
+const AnyLanguage = "*"
+
+// Config ...
+// Words are matched one by one regardless of case and surrounding punctuation, a trailing '*' matches any ending,
+// phrases aren't supported. Lists are keyed by language, "en-US" also uses the "en" and "*" lists.
+type Config struct {
+	Action      string              `json:"action"`      // mask, replace or drop, filtering is disabled without any words or files
+	Replacement string              `json:"replacement"` // Used by the replace action
+	Words       map[string][]string `json:"words"`
+	Files       map[string][]string `json:"files"` // Text files with one word per line, '#' starts a comment, reloaded when changed
+	Allow       []string            `json:"allow"` // Never filtered in any language, e.g. place names a wildcard would otherwise catch
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Action:      Mask,
+		Replacement: "[bleep]",
+	}
+}
+
+// Enabled ...
+func (c Config) Enabled() bool {
+	return len(c.Words) > 0 || len(c.Files) > 0
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	switch c.Action {
+	case Mask, Replace, Drop:
+	default:
+		return fmt.Errorf("unknown filter action %q", c.Action)
+	}
+
+	for language, words := range c.Words {
+		for _, word := range words {
+			if !singleWord(word) {
+				return fmt.Errorf("filter: %q in the %s words is not a single word, phrases aren't supported", word, language)
+			}
+		}
+	}
+
+	for _, word := range c.Allow {
+		if !singleWord(word) {
+			return fmt.Errorf("filter: allowed %q is not a single word, phrases aren't supported", word)
+		}
+	}
+
+	return nil
+}
+
+// singleWord ...
+// Captions are filtered word by word, an entry with spaces would never match
+func singleWord(entry string) bool {
+	return len(strings.Fields(entry)) <= 1
+}
@@ -0,0 +1,280 @@
+package filters
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"server/logging"
+	"server/metrics"
+	"server/transcriber/recognizers"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var filteredTotal = metrics.NewCounterVec(
+	"vsr_filtered_words_total",
+	"Words filtered out of published captions, by channel and action",
+	"channel", "action",
+)
+
+// Filter ...
+// Masks, replaces or drops listed words before a transcript is published
+type Filter struct {
+	config  Config
+	channel string
+	allow   *list
+
+	mu    sync.Mutex
+	lists map[string]*list // By language
+	files map[string]time.Time
+}
+
+// list ...
+type list struct {
+	words    map[string]bool
+	prefixes []string
+}
+
+func newList() *list {
+	return &list{words: make(map[string]bool)}
+}
+
+func (l *list) add(word string) {
+	word = strings.TrimSpace(word)
+	if strings.HasSuffix(word, "*") {
+		if prefix := recognizers.Normalize(strings.TrimSuffix(word, "*")); prefix != "" {
+			l.prefixes = append(l.prefixes, prefix)
+		}
+
+		return
+	}
+
+	if term := recognizers.Normalize(word); term != "" {
+		l.words[term] = true
+	}
+}
+
+func (l *list) contains(term string) bool {
+	if l.words[term] {
+		return true
+	}
+
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(term, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// New ...
+func New(channel string, config Config) (*Filter, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{
+		config:  config,
+		channel: channel,
+		allow:   newList(),
+	}
+
+	for _, word := range config.Allow {
+		f.allow.add(word)
+	}
+
+	err = f.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Apply ...
+// Returns the words as they may be published and how many were filtered
+func (f *Filter) Apply(words []recognizers.TimedWord, language string) ([]recognizers.TimedWord, int) {
+	if !f.config.Enabled() {
+		return words, 0
+	}
+
+	f.reload()
+
+	f.mu.Lock()
+	lists := f.languageLists(language)
+	f.mu.Unlock()
+
+	result := make([]recognizers.TimedWord, 0, len(words))
+	filtered := 0
+
+	for _, word := range words {
+		term := recognizers.Normalize(word.Word)
+		if term == "" || f.allow.contains(term) || !anyContains(lists, term) {
+			result = append(result, word)
+			continue
+		}
+
+		filtered++
+
+		switch f.config.Action {
+		case Mask:
+			word.Word = mask(word.Word)
+		case Replace:
+			word.Word = f.config.Replacement
+		case Drop:
+			continue
+		}
+
+		result = append(result, word)
+	}
+
+	if filtered > 0 {
+		filteredTotal.Add(float64(filtered), f.channel, f.config.Action)
+	}
+
+	return result, filtered
+}
+
+// languageLists returns the lists for the language, its base language and any language. Must be called with the lock held.
+func (f *Filter) languageLists(language string) []*list {
+	keys := []string{AnyLanguage}
+	if language != "" {
+		keys = append(keys, language)
+		if i := strings.IndexAny(language, "-_"); i > 0 {
+			keys = append(keys, language[:i])
+		}
+	}
+
+	result := make([]*list, 0, len(keys))
+	for _, key := range keys {
+		if l, ok := f.lists[key]; ok {
+			result = append(result, l)
+		}
+	}
+
+	return result
+}
+
+func anyContains(lists []*list, term string) bool {
+	for _, l := range lists {
+		if l.contains(term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mask keeps the first letter and any surrounding punctuation, e.g. "Damn," becomes "D***,"
+func mask(word string) string {
+	start := strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+	end := strings.LastIndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+	if start < 0 {
+		return word
+	}
+
+	_, size := utf8.DecodeRuneInString(word[start:])
+	_, lastSize := utf8.DecodeRuneInString(word[end:])
+	hidden := utf8.RuneCountInString(word[start+size : end+lastSize])
+
+	return word[:start+size] + strings.Repeat("*", hidden) + word[end+lastSize:]
+}
+
+// reload rebuilds the lists when a list file has changed since it was last read
+func (f *Filter) reload() {
+	changed := false
+
+	f.mu.Lock()
+	for _, paths := range f.config.Files {
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err == nil && !info.ModTime().Equal(f.files[path]) {
+				changed = true
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	err := f.load()
+	if err != nil {
+		// Keeping the previous lists rather than publishing unfiltered captions
+		logging.New("transcriber", "channel", f.channel).Error("Could not reload filter lists, keeping the previous ones", "err", err)
+	}
+}
+
+func (f *Filter) load() error {
+	lists := make(map[string]*list)
+	files := make(map[string]time.Time)
+
+	get := func(language string) *list {
+		if _, ok := lists[language]; !ok {
+			lists[language] = newList()
+		}
+
+		return lists[language]
+	}
+
+	for language, words := range f.config.Words {
+		for _, word := range words {
+			get(language).add(word)
+		}
+	}
+
+	for language, paths := range f.config.Files {
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+
+			err = readList(path, get(language))
+			if err != nil {
+				return err
+			}
+
+			files[path] = info.ModTime()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lists = lists
+	f.files = files
+	return nil
+}
+
+func readList(path string, l *list) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		if !singleWord(line) {
+			return fmt.Errorf("%s:%d: %q is not a single word, phrases aren't supported", path, n, strings.TrimSpace(line))
+		}
+
+		if strings.TrimSpace(line) != "" {
+			l.add(line)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,177 @@
+package filters
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/transcriber/recognizers"
+	"strings"
+	"testing"
+	"time"
+)
+
+func timedWords(text string) []recognizers.TimedWord {
+	words := make([]recognizers.TimedWord, 0)
+	for i, word := range strings.Fields(text) {
+		words = append(words, recognizers.TimedWord{
+			Start: recognizers.FromDuration(time.Duration(i) * time.Second),
+			End:   recognizers.FromDuration(time.Duration(i)*time.Second + 800*time.Millisecond),
+			Word:  word,
+		})
+	}
+
+	return words
+}
+
+func text(words []recognizers.TimedWord) string {
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		result = append(result, word.Word)
+	}
+
+	return strings.Join(result, " ")
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"damn", "d***"},
+		{"Damn,", "D***,"},
+		{"\"heck!\"", "\"h***!\""},
+		{"héllo.", "h****."},
+		{"a", "a"},
+		{"!!!", "!!!"},
+	}
+
+	for _, test := range tests {
+		if got := mask(test.word); got != test.want {
+			t.Errorf("mask(%q) = %q, want %q", test.word, got, test.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	words := map[string][]string{
+		AnyLanguage: {"damn"},
+		"en":        {"heck*"},
+		"fr":        {"merde"},
+	}
+	allow := []string{"Heckington"}
+	spoken := "Damn, the heckin Heckington merde"
+
+	tests := []struct {
+		name     string
+		config   Config
+		language string
+		want     string
+		filtered int
+	}{
+		{"disabled", DefaultConfig(), "en-US", spoken, 0},
+		{"base language and any language", Config{Action: Mask, Words: words, Allow: allow}, "en-US", "D***, the h***** Heckington merde", 2},
+		{"other language", Config{Action: Mask, Words: words, Allow: allow}, "fr-FR", "D***, the heckin Heckington m****", 2},
+		{"unknown language", Config{Action: Mask, Words: words, Allow: allow}, "", "D***, the heckin Heckington merde", 1},
+		{"allowed words aren't filtered", Config{Action: Mask, Words: words}, "en", "D***, the h***** H********* merde", 3},
+		{"replace", Config{Action: Replace, Replacement: "[bleep]", Words: words, Allow: allow}, "en-US", "[bleep] the [bleep] Heckington merde", 2},
+		{"drop", Config{Action: Drop, Words: words, Allow: allow}, "en-US", "the Heckington merde", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := New("news", test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, filtered := f.Apply(timedWords(spoken), test.language)
+			if text(result) != test.want || filtered != test.filtered {
+				t.Errorf("Apply = %q, %d filtered, want %q, %d filtered", text(result), filtered, test.want, test.filtered)
+			}
+
+			// Published words keep their timing
+			for _, word := range result {
+				if word.End.Duration()-word.Start.Duration() != 800*time.Millisecond {
+					t.Errorf("%q retimed to %v-%v", word.Word, word.Start.Duration(), word.End.Duration())
+				}
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "words.txt")
+	err = ioutil.WriteFile(path, []byte("# Mild\ndarn # and more\n\n  drat\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := New("news", Config{Action: Mask, Files: map[string][]string{"en": {path}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, _ := f.Apply(timedWords("darn it drat"), "en-GB"); text(result) != "d*** it d***" {
+		t.Errorf("Apply = %q, want the listed words masked", text(result))
+	}
+
+	// Changed lists are picked up by the next transcript
+	err = ioutil.WriteFile(path, []byte("it\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	if result, _ := f.Apply(timedWords("darn it drat"), "en-GB"); text(result) != "darn i* drat" {
+		t.Errorf("Apply = %q after reloading, want only the new word masked", text(result))
+	}
+
+	// An invalid list keeps the previous one
+	err = ioutil.WriteFile(path, []byte("two words\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	later = later.Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	if result, _ := f.Apply(timedWords("darn it drat"), "en-GB"); text(result) != "darn i* drat" {
+		t.Errorf("Apply = %q after an invalid reload, want the previous list", text(result))
+	}
+
+	_, err = New("news", Config{Action: Mask, Files: map[string][]string{"en": {path}}})
+	if err == nil || !strings.Contains(err.Error(), "words.txt:1") {
+		t.Errorf("err = %v, want the invalid line reported", err)
+	}
+
+	_, err = New("news", Config{Action: Mask, Files: map[string][]string{"en": {filepath.Join(dir, "missing.txt")}}})
+	if !os.IsNotExist(err) {
+		t.Errorf("err = %v, want the missing list reported", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"default", DefaultConfig(), true},
+		{"unknown action", Config{Action: "blur"}, false},
+		{"phrase", Config{Action: Mask, Words: map[string][]string{"en": {"oh my"}}}, false},
+		{"allowed phrase", Config{Action: Mask, Allow: []string{"New York"}}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.config.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: err = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package transcriber
+
+import (
+	"server/transcriber/slo"
+	"time"
+)
+
+// measureLag is how long ago the segment was at the live edge of the playlist, applying or reverting a degradation
+// mode when it's too far behind or caught up
+func (t *Transcriber) measureLag(segment *SegmentInfo) float64 {
+	written, err := t.written(segment.Filename)
+	if err != nil {
+		// Already deleted, reading it will fail
+		return 0
+	}
+
+	segment.Written = written
+	lag := time.Since(written).Seconds()
+	segmentLag.Observe(lag, t.channel)
+
+	transition, ok := t.degradation.Observe(time.Now(), lag)
+	if !ok {
+		return lag
+	}
+
+	degradationLevel.Set(float64(t.degradation.Level()), t.channel)
+
+	if transition.Degraded {
+		degradationTransitions.Inc(t.channel, transition.Mode, "applied")
+		t.log.Warn("Captions are behind the live edge, degrading", "mode", transition.Mode, "lag", lag,
+			"target", t.latency.Target, "modes", t.degradation.Modes())
+	} else {
+		degradationTransitions.Inc(t.channel, transition.Mode, "reverted")
+		t.log.Info("Captions caught up with the live edge, reverting", "mode", transition.Mode, "lag", lag,
+			"modes", t.degradation.Modes())
+	}
+
+	return lag
+}
+
+// concurrency is how many segments may be transcribed at once, while the concurrency mode is applied
+func (t *Transcriber) concurrency() int {
+	if !t.latency.Uses(slo.Concurrency) {
+		return 1
+	}
+
+	return t.latency.Concurrency
+}
+
+// countMissed reports the segments that slid out of the live window before their captions were published
+func (t *Transcriber) countMissed(previous []SegmentInfo, listed []SegmentInfo) {
+	current := make(map[string]bool, len(listed))
+	for _, segment := range listed {
+		current[segment.Filename] = true
+	}
+
+	for _, segment := range previous {
+		if current[segment.Filename] {
+			continue
+		}
+
+		state := "pending"
+		if known, ok := t.segment(segment.Filename); ok {
+			state = known.State
+		}
+
+		if state == "processed" {
+			continue
+		}
+
+		segmentsMissed.Inc(t.channel)
+		t.segmentLog(segment).Warn("Segment left the live window without captions", "state", state)
+	}
+}
@@ -0,0 +1,75 @@
+package transcriber
+
+import "server/metrics"
+
+var (
+	segmentsDiscovered = metrics.NewCounterVec(
+		"vsr_segments_discovered_total",
+		"Media segments found in the channel's playlist",
+		"channel",
+	)
+	segmentsProcessed = metrics.NewCounterVec(
+		"vsr_segments_processed_total",
+		"Media segments transcribed and published",
+		"channel",
+	)
+	segmentsFailed = metrics.NewCounterVec(
+		"vsr_segments_failed_total",
+		"Media segment transcriptions that failed, they're retried while the segment is listed",
+		"channel",
+	)
+	segmentsPending = metrics.NewGaugeVec(
+		"vsr_segments_pending",
+		"Listed media segments waiting to be transcribed",
+		"channel",
+	)
+	recognitionSeconds = metrics.NewHistogramVec(
+		"vsr_recognition_duration_seconds",
+		"Time taken by the recognizer per segment, by channel, provider and result (ok, error), cache hits excluded",
+		metrics.LatencyBuckets,
+		"channel", "provider", "result",
+	)
+	captionDelay = metrics.NewHistogramVec(
+		"vsr_caption_delay_seconds",
+		"Time from the media segment being written to its captions being published",
+		metrics.LatencyBuckets,
+		"channel",
+	)
+	confidence = metrics.NewHistogramVec(
+		"vsr_transcript_confidence",
+		"Confidence of the transcribed segments with words, by channel",
+		metrics.RatioBuckets,
+		"channel",
+	)
+	segmentLag = metrics.NewHistogramVec(
+		"vsr_segment_lag_seconds",
+		"Time from the media segment being written, at the live edge, to its transcription starting",
+		metrics.LatencyBuckets,
+		"channel",
+	)
+	segmentsMissed = metrics.NewCounterVec(
+		"vsr_segments_missed_total",
+		"Media segments that left the live window without captions",
+		"channel",
+	)
+	segmentsSkipped = metrics.NewCounterVec(
+		"vsr_segments_skipped_total",
+		"Media segments published without captions to catch up with the live edge",
+		"channel",
+	)
+	captionSLO = metrics.NewCounterVec(
+		"vsr_caption_slo_segments_total",
+		"Segments whose captions were published within the channel's latency target or not, by result (met, missed, skipped)",
+		"channel", "result",
+	)
+	degradationLevel = metrics.NewGaugeVec(
+		"vsr_degradation_level",
+		"Latency degradation modes applied to the channel",
+		"channel",
+	)
+	degradationTransitions = metrics.NewCounterVec(
+		"vsr_degradation_transitions_total",
+		"Latency degradation modes applied and reverted, by mode and direction (applied, reverted)",
+		"channel", "mode", "direction",
+	)
+)
@@ -0,0 +1,150 @@
+package transcriber
+
+import (
+	"fmt"
+	"os"
+	"server/transcriber/recognizers/failover"
+	"time"
+)
+
+// unavailableAfter is how many consecutive segments must fail before the recognizer is reported unavailable
+const unavailableAfter = 3
+
+// Progress ...
+// How far the transcription is behind the live window
+type Progress struct {
+	Pending int `json:"pending"` // Listed segments without captions yet, including the one being transcribed and failed ones
+	// Seconds since the newest listed segment was written, nil before the first one
+	NewestSegmentAge *float64 `json:"newestSegmentAge"`
+	// Seconds since captions were last published, nil before the first ones
+	NewestTranscriptAge *float64 `json:"newestTranscriptAge"`
+	// Seconds the oldest untranscribed segment has been waiting for its captions, 0 when caught up
+	CaptionLag          float64         `json:"captionLag"`
+	RecognizerAvailable bool            `json:"recognizerAvailable"`
+	Paused              bool            `json:"paused"`   // Recognition is paused, the channel's budget is exhausted
+	Failures            int             `json:"failures"` // Consecutive segments that failed
+	Degraded            []string        `json:"degraded"` // Latency degradation modes applied, in order
+	Window              []WindowSegment `json:"window"`
+}
+
+// WindowSegment ...
+// A segment of the live window and where it is in the pipeline
+type WindowSegment struct {
+	ID         string  `json:"id"` // Correlation ID in the logs
+	Filename   string  `json:"filename"`
+	Sequence   int     `json:"sequence"`
+	MediaStart float64 `json:"mediaStart"`
+	Duration   float64 `json:"duration"`
+	State      string  `json:"state"` // pending, processing, processed or errored
+	Language   string  `json:"language,omitempty"`
+}
+
+// Progress ...
+func (t *Transcriber) Progress() Progress {
+	recognizer, _ := t.currentRecognizer()
+	chain := t.Recognizers()
+
+	t.mu.Lock()
+	listed := t.listed
+	published := t.published
+	failures := t.failures
+
+	window := make([]WindowSegment, 0, len(listed))
+	for _, segment := range listed {
+		state := "pending"
+		if known, ok := t.playlistInfo.Segments[segment.Filename]; ok {
+			segment = known
+			state = known.State
+		}
+
+		window = append(window, WindowSegment{
+			ID:         segment.ID,
+			Filename:   segment.Filename,
+			Sequence:   segment.Sequence,
+			MediaStart: segment.MediaStart,
+			Duration:   segment.Duration,
+			State:      state,
+			Language:   segment.Language,
+		})
+	}
+	t.mu.Unlock()
+
+	now := time.Now()
+	progress := Progress{
+		Paused:              recognizer == nil,
+		RecognizerAvailable: recognizer != nil && failures < unavailableAfter && chainAvailable(chain),
+		Failures:            failures,
+		Degraded:            t.degradation.Modes(),
+		Window:              window,
+	}
+
+	if !published.IsZero() {
+		age := now.Sub(published).Seconds()
+		progress.NewestTranscriptAge = &age
+	}
+
+	if len(window) > 0 {
+		written, err := t.written(window[len(window)-1].Filename)
+		if err == nil {
+			age := now.Sub(written).Seconds()
+			progress.NewestSegmentAge = &age
+		}
+	}
+
+	oldest := ""
+	for _, segment := range window {
+		if segment.State == "processed" {
+			continue
+		}
+
+		progress.Pending++
+		if oldest == "" {
+			oldest = segment.Filename
+		}
+	}
+
+	if oldest != "" {
+		written, err := t.written(oldest)
+		if err == nil {
+			progress.CaptionLag = now.Sub(written).Seconds()
+		}
+	}
+
+	return progress
+}
+
+// written is when ffmpeg finished writing the segment
+func (t *Transcriber) written(filename string) (time.Time, error) {
+	info, err := os.Stat(fmt.Sprintf("%s/%s", t.segmentsPath, filename))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
+
+func (t *Transcriber) countFailure(failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if failed {
+		t.failures++
+	} else {
+		t.failures = 0
+	}
+}
+
+// chainAvailable is true without a failover chain, or when one of its circuits isn't open
+func chainAvailable(chain []failover.Status) bool {
+	if len(chain) == 0 {
+		return true
+	}
+
+	for _, status := range chain {
+		if status.State != failover.StateOpen {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package transcriber
+
+import (
+	"fmt"
+	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/ensemble"
+	"server/transcriber/recognizers/failover"
+	"server/transcriber/recognizers/fake"
+	"server/transcriber/recognizers/gcp"
+)
+
+// NewRecognizer ...
+// Builds the recognizer adapter for the configured provider, a failover chain when failover recognizers are listed or
+// an ensemble when ensemble recognizers are. The channel labels their metrics.
+func NewRecognizer(config recognizers.Config, channel string) (recognizers.Adapter, error) {
+	switch {
+	case len(config.Failover) > 0 && len(config.Ensemble) > 0:
+		return nil, fmt.Errorf("a recognizer can't have both failover and ensemble recognizers")
+
+	case len(config.Failover) > 0:
+		adapters, err := newAdapters(config.Chain())
+		if err != nil {
+			return nil, err
+		}
+
+		members := make([]failover.Member, 0, len(adapters))
+		for i, member := range config.Chain() {
+			members = append(members, failover.Member{Name: member.ID(), Adapter: adapters[i]})
+		}
+
+		return failover.New(channel, members, config.Breaker), nil
+
+	case len(config.Ensemble) > 0:
+		adapters, err := newAdapters(config.Voters())
+		if err != nil {
+			return nil, err
+		}
+
+		members := make([]ensemble.Member, 0, len(adapters))
+		for i, member := range config.Voters() {
+			members = append(members, ensemble.Member{Name: member.ID(), Adapter: adapters[i], Weight: member.VoteWeight()})
+		}
+
+		wait := config.EnsembleWait
+		if wait <= 0 {
+			wait = recognizers.DefaultEnsembleWait
+		}
+
+		return ensemble.New(channel, members, recognizers.Seconds(wait)), nil
+	}
+
+	return newAdapter(config)
+}
+
+// newAdapters builds the adapters of a failover chain or ensemble, whose recognizers must have different names
+func newAdapters(configs []recognizers.Config) ([]recognizers.Adapter, error) {
+	adapters := make([]recognizers.Adapter, 0, len(configs))
+	names := make(map[string]bool)
+
+	for _, config := range configs {
+		if names[config.ID()] {
+			return nil, fmt.Errorf("recognizer %s is listed twice, give each a different name", config.ID())
+		}
+
+		names[config.ID()] = true
+
+		adapter, err := newAdapter(config)
+		if err != nil {
+			return nil, fmt.Errorf("recognizer %s: %v", config.ID(), err)
+		}
+
+		adapters = append(adapters, adapter)
+	}
+
+	return adapters, nil
+}
+
+func newAdapter(config recognizers.Config) (recognizers.Adapter, error) {
+	switch config.Provider {
+	case "", "gcp":
+		return &gcp.Adapter{Config: config}, nil
+	case "fake":
+		return fake.New(config)
+	}
+
+	return nil, fmt.Errorf("unknown recognizer provider %q", config.Provider)
+}
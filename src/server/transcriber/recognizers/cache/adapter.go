@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"server/transcriber/recognizers"
+
+	"go.opencensus.io/trace"
+)
+
+// Adapter ...
+// Serves the recognition of audio already recognized with the same settings from the cache
+type Adapter struct {
+	cache   *Cache
+	config  recognizers.Config
+	adapter recognizers.Adapter
+}
+
+// Wrap ...
+func Wrap(cache *Cache, config recognizers.Config, adapter recognizers.Adapter) *Adapter {
+	return &Adapter{
+		cache:   cache,
+		config:  config,
+		adapter: adapter,
+	}
+}
+
+// Unwrap ...
+// The adapter recognizing cache misses
+func (a *Adapter) Unwrap() recognizers.Adapter {
+	return a.adapter
+}
+
+// Init ...
+func (a *Adapter) Init() {
+	a.adapter.Init()
+}
+
+// Input ...
+func (a *Adapter) Input(ctx context.Context, audio []byte) (recognizers.Response, error) {
+	resp, err := a.cache.Recognize(ctx, Key(audio, a.config), func(ctx context.Context) (recognizers.Response, error) {
+		return a.adapter.Input(ctx, audio)
+	})
+
+	trace.FromContext(ctx).AddAttributes(trace.BoolAttribute("cache_hit", resp.Cached))
+	return resp, err
+}
@@ -0,0 +1,417 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/logging"
+	"server/metrics"
+	"server/transcriber/recognizers"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	requestsTotal = metrics.NewCounterVec(
+		"vsr_recognition_cache_requests_total",
+		"Recognitions looked up in the cache, by result (hit, miss, shared with an identical request in flight)",
+		"result",
+	)
+	evictionsTotal = metrics.NewCounterVec(
+		"vsr_recognition_cache_evictions_total",
+		"Cached recognitions removed, by reason (expired, size)",
+		"reason",
+	)
+	sizeBytes = metrics.NewGaugeVec(
+		"vsr_recognition_cache_bytes",
+		"Size of the cached recognitions on disk",
+	)
+)
+
+// Config ...
+type Config struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+	MaxSize int64  `json:"maxSize"` // Megabytes, the least recently used recognitions are removed beyond it
+	TTL     int    `json:"ttl"`     // Hours a recognition is kept
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Enabled: true,
+		Path:    "_cache",
+		MaxSize: 1024,
+		TTL:     30 * 24,
+	}
+}
+
+// settings ...
+// The parts of a recognizer's config that change what it recognizes. Names, endpoints, failover and breakers don't,
+// so channels recognizing the same audio with the same model share their recognitions.
+type settings struct {
+	Provider             string     `json:"provider"`
+	LanguageCode         string     `json:"languageCode"`
+	AlternativeLanguages []string   `json:"alternativeLanguages"`
+	Model                string     `json:"model"`
+	UseEnhanced          bool       `json:"useEnhanced"`
+	Punctuation          bool       `json:"punctuation"`
+	Diarization          bool       `json:"diarization"`
+	MinSpeakers          int        `json:"minSpeakers"`
+	MaxSpeakers          int        `json:"maxSpeakers"`
+	Weight               float64    `json:"weight,omitempty"`
+	Ensemble             []settings `json:"ensemble,omitempty"` // The merged words depend on every recognizer's
+}
+
+func settingsOf(config recognizers.Config) settings {
+	s := settings{
+		Provider:             config.Provider,
+		LanguageCode:         config.LanguageCode,
+		AlternativeLanguages: config.AlternativeLanguages,
+		Model:                config.Model,
+		UseEnhanced:          config.UseEnhanced,
+		Punctuation:          config.Punctuation,
+		Diarization:          config.Diarization,
+		MinSpeakers:          config.MinSpeakers,
+		MaxSpeakers:          config.MaxSpeakers,
+		Weight:               config.Weight,
+	}
+
+	for _, member := range config.Ensemble {
+		s.Ensemble = append(s.Ensemble, settingsOf(member))
+	}
+
+	return s
+}
+
+// Key ...
+// Identifies the recognition of the audio with the given settings
+func Key(audio []byte, config recognizers.Config) string {
+	settings, _ := json.Marshal(settingsOf(config))
+
+	h := sha256.New()
+	h.Write(settings)
+	h.Write([]byte{0})
+	h.Write(audio)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type entry struct {
+	size     int64
+	created  time.Time
+	lastUsed time.Time
+}
+
+// call is a recognition in flight, identical requests wait for it instead of reaching the provider. It runs under
+// its own context, canceled once every request waiting for it is gone.
+type call struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+	done    chan struct{}
+	resp    recognizers.Response
+	err     error
+}
+
+// detached keeps the values of a context, its logger and trace span, without its deadline and cancellation
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+// Cache ...
+// Recognitions on disk keyed by a hash of their audio and settings, shared by every channel
+type Cache struct {
+	config Config
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	size     int64
+	inFlight map[string]*call
+}
+
+// Open ...
+// Indexes the recognitions cached by previous runs
+func Open(config Config) (*Cache, error) {
+	err := os.MkdirAll(config.Path, 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		config:   config,
+		entries:  make(map[string]*entry),
+		inFlight: make(map[string]*call),
+	}
+
+	err = filepath.Walk(config.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+
+		// Without any record of their use, recognitions are evicted oldest first
+		key := strings.TrimSuffix(info.Name(), ".json")
+		c.entries[key] = &entry{size: info.Size(), created: info.ModTime(), lastUsed: info.ModTime()}
+		c.size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	removed := c.evict(time.Now())
+	c.mu.Unlock()
+
+	c.unlink(removed)
+	return c, nil
+}
+
+// Recognize ...
+// Returns the cached recognition for the key, otherwise recognizes and caches it. Concurrent requests for the same key
+// share a single recognition, which is only canceled once all of them are.
+func (c *Cache) Recognize(ctx context.Context, key string,
+	recognize func(ctx context.Context) (recognizers.Response, error)) (recognizers.Response, error) {
+	var pending *call
+	var shared bool
+
+	for {
+		resp, ok := c.get(key, time.Now())
+		if ok {
+			requestsTotal.Inc("hit")
+			resp.Cached = true
+			return resp, nil
+		}
+
+		c.mu.Lock()
+		pending, shared = c.inFlight[key]
+		if _, cached := c.entries[key]; shared || !cached {
+			break
+		}
+
+		// Cached by a recognition that finished in the meantime
+		c.mu.Unlock()
+	}
+
+	if !shared {
+		pending = &call{done: make(chan struct{})}
+		pending.ctx, pending.cancel = context.WithCancel(detached{ctx})
+		c.inFlight[key] = pending
+		go c.run(key, pending, recognize)
+	}
+
+	pending.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-pending.done:
+	case <-ctx.Done():
+		c.leave(key, pending)
+		return recognizers.Response{}, ctx.Err()
+	}
+
+	if !shared {
+		requestsTotal.Inc("miss")
+		return pending.resp, pending.err
+	}
+
+	requestsTotal.Inc("shared")
+	resp := pending.resp
+	resp.Cached = pending.err == nil
+	return resp, pending.err
+}
+
+// run recognizes and caches the audio of a call in flight
+func (c *Cache) run(key string, pending *call, recognize func(ctx context.Context) (recognizers.Response, error)) {
+	defer pending.cancel()
+
+	pending.resp, pending.err = recognize(pending.ctx)
+	if pending.err == nil {
+		err := c.put(key, pending.resp, time.Now())
+		if err != nil {
+			logging.FromContext(pending.ctx, "recognizers").Warn("Could not cache recognition", "key", key, "err", err)
+		}
+	}
+
+	c.mu.Lock()
+	if c.inFlight[key] == pending {
+		delete(c.inFlight, key)
+	}
+	c.mu.Unlock()
+
+	close(pending.done)
+}
+
+// leave stops waiting for a call in flight, the last request to leave cancels it
+func (c *Cache) leave(key string, pending *call) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending.waiters--
+	if pending.waiters > 0 {
+		return
+	}
+
+	// Later requests start over rather than share a canceled recognition
+	if c.inFlight[key] == pending {
+		delete(c.inFlight, key)
+	}
+
+	pending.cancel()
+}
+
+func (c *Cache) get(key string, now time.Time) (recognizers.Response, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && c.expired(e, now) {
+		removed := c.remove(key, "expired")
+		c.mu.Unlock()
+
+		c.unlink([]string{removed})
+		return recognizers.Response{}, false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return recognizers.Response{}, false
+	}
+
+	raw, err := ioutil.ReadFile(c.path(key))
+	if err == nil {
+		var resp recognizers.Response
+		err = json.Unmarshal(raw, &resp)
+		if err == nil {
+			c.mu.Lock()
+			e.lastUsed = now
+			c.mu.Unlock()
+			return resp, true
+		}
+	}
+
+	// Otherwise evicted while it was read
+	if !os.IsNotExist(err) {
+		logging.New("recognizers").Warn("Dropping unreadable cached recognition", "key", key, "err", err)
+	}
+
+	c.mu.Lock()
+	var removed []string
+	if c.entries[key] == e {
+		removed = append(removed, c.remove(key, "expired"))
+	}
+	c.mu.Unlock()
+
+	c.unlink(removed)
+	return recognizers.Response{}, false
+}
+
+func (c *Cache) put(key string, resp recognizers.Response, now time.Time) error {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key)
+	err = os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, raw, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if previous, ok := c.entries[key]; ok {
+		c.size -= previous.size
+	}
+
+	c.entries[key] = &entry{size: int64(len(raw)), created: now, lastUsed: now}
+	c.size += int64(len(raw))
+	removed := c.evict(now)
+	c.mu.Unlock()
+
+	c.unlink(removed)
+	return nil
+}
+
+// evict must be called with the lock held, it removes expired recognitions then the least recently used ones beyond
+// the maximum size from the index, returning the files to unlink
+func (c *Cache) evict(now time.Time) []string {
+	var removed []string
+
+	for key, e := range c.entries {
+		if c.expired(e, now) {
+			removed = append(removed, c.remove(key, "expired"))
+		}
+	}
+
+	if c.config.MaxSize > 0 && c.size > c.config.MaxSize*1024*1024 {
+		keys := make([]string, 0, len(c.entries))
+		for key := range c.entries {
+			keys = append(keys, key)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			return c.entries[keys[i]].lastUsed.Before(c.entries[keys[j]].lastUsed)
+		})
+
+		for _, key := range keys {
+			if c.size <= c.config.MaxSize*1024*1024 {
+				break
+			}
+
+			removed = append(removed, c.remove(key, "size"))
+		}
+	}
+
+	sizeBytes.Set(float64(c.size))
+	return removed
+}
+
+// remove must be called with the lock held, it removes the recognition from the index and returns its file to unlink
+func (c *Cache) remove(key string, reason string) string {
+	e := c.entries[key]
+
+	delete(c.entries, key)
+	c.size -= e.size
+	evictionsTotal.Inc(reason)
+	sizeBytes.Set(float64(c.size))
+
+	return c.path(key)
+}
+
+// unlink deletes the files of removed recognitions, outside the lock
+func (c *Cache) unlink(paths []string) {
+	for _, path := range paths {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			logging.New("recognizers").Warn("Could not remove cached recognition", "path", path, "err", err)
+		}
+	}
+}
+
+func (c *Cache) expired(e *entry, now time.Time) bool {
+	return c.config.TTL > 0 && now.Sub(e.created) > time.Duration(c.config.TTL)*time.Hour
+}
+
+// path spreads the recognitions over subdirectories by the start of their key
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.config.Path, key[:2], key+".json")
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/transcriber/recognizers"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	base := recognizers.Config{Provider: "gcp", LanguageCode: "en-US", Model: "video", UseEnhanced: true}
+	key := Key([]byte("audio"), base)
+
+	tests := []struct {
+		name   string
+		audio  string
+		change func(c *recognizers.Config)
+		same   bool
+	}{
+		{"other name", "audio", func(c *recognizers.Config) { c.Name = "primary" }, true},
+		{"other endpoint", "audio", func(c *recognizers.Config) { c.Endpoint = "localhost:9000" }, true},
+		{"other breaker", "audio", func(c *recognizers.Config) { c.Breaker.MaxErrorRate = 0.9 }, true},
+		{"failover", "audio", func(c *recognizers.Config) { c.Failover = []recognizers.Config{{Provider: "fake"}} }, true},
+		{"other audio", "other", func(c *recognizers.Config) {}, false},
+		{"other provider", "audio", func(c *recognizers.Config) { c.Provider = "fake" }, false},
+		{"other model", "audio", func(c *recognizers.Config) { c.Model = "default" }, false},
+		{"other language", "audio", func(c *recognizers.Config) { c.LanguageCode = "fr-FR" }, false},
+		{"alternative languages", "audio", func(c *recognizers.Config) { c.AlternativeLanguages = []string{"es-ES"} }, false},
+		{"diarization", "audio", func(c *recognizers.Config) { c.Diarization = true }, false},
+		{"unenhanced", "audio", func(c *recognizers.Config) { c.UseEnhanced = false }, false},
+		{"ensemble", "audio", func(c *recognizers.Config) { c.Ensemble = []recognizers.Config{{Provider: "fake"}} }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := base
+			test.change(&config)
+
+			if same := Key([]byte(test.audio), config) == key; same != test.same {
+				t.Errorf("same key = %v, want %v", same, test.same)
+			}
+		})
+	}
+}
+
+func open(t *testing.T, config Config) *Cache {
+	if config.Path == "" {
+		dir, err := ioutil.TempDir("", "cache")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		config.Path = dir
+	}
+
+	c, err := Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// recognizer counts its calls, answering with the given text once released
+type recognizer struct {
+	text    string
+	release chan struct{}
+	calls   int32
+}
+
+func (r *recognizer) recognize(ctx context.Context) (recognizers.Response, error) {
+	atomic.AddInt32(&r.calls, 1)
+
+	if r.release != nil {
+		select {
+		case <-r.release:
+		case <-ctx.Done():
+			return recognizers.Response{}, ctx.Err()
+		}
+	}
+
+	return recognizers.Response{Words: []recognizers.TimedWord{{Word: r.text}}}, nil
+}
+
+func TestRecognize(t *testing.T) {
+	c := open(t, Config{MaxSize: 1, TTL: 1})
+	r := &recognizer{text: "hello"}
+
+	first, err := c.Recognize(context.Background(), Key([]byte("audio"), recognizers.Config{}), r.recognize)
+	if err != nil || first.Cached {
+		t.Fatalf("first recognition = %+v, %v, want a fresh one", first, err)
+	}
+
+	second, err := c.Recognize(context.Background(), Key([]byte("audio"), recognizers.Config{}), r.recognize)
+	if err != nil || !second.Cached || second.Words[0].Word != "hello" {
+		t.Fatalf("second recognition = %+v, %v, want the cached one", second, err)
+	}
+
+	if r.calls != 1 {
+		t.Errorf("recognized %d times, want 1", r.calls)
+	}
+
+	// Failures aren't cached
+	_, err = c.Recognize(context.Background(), "ff00", func(ctx context.Context) (recognizers.Response, error) {
+		return recognizers.Response{}, errors.New("unavailable")
+	})
+	if err == nil {
+		t.Fatal("want the recognition error")
+	}
+
+	if _, ok := c.get("ff00", time.Now()); ok {
+		t.Error("a failed recognition was cached")
+	}
+
+	// Recognitions of previous runs are indexed on open
+	reopened := open(t, c.config)
+	resp, err := reopened.Recognize(context.Background(), Key([]byte("audio"), recognizers.Config{}), r.recognize)
+	if err != nil || !resp.Cached {
+		t.Errorf("after reopening = %+v, %v, want the cached recognition", resp, err)
+	}
+}
+
+func TestRecognizeShared(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancelled []bool // Whether each waiting request gives up before the recognition is done
+		results   []bool // Whether each request gets the recognition
+		abandoned bool   // Whether the shared recognition is cancelled
+	}{
+		{name: "every request waits", cancelled: []bool{false, false, false}, results: []bool{true, true, true}},
+		{name: "the first request gives up", cancelled: []bool{true, false, false}, results: []bool{false, true, true}},
+		{name: "every request gives up", cancelled: []bool{true, true}, results: []bool{false, false}, abandoned: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := open(t, Config{MaxSize: 1, TTL: 1})
+			r := &recognizer{text: "hello", release: make(chan struct{})}
+			key := Key([]byte("audio"), recognizers.Config{})
+
+			var wg sync.WaitGroup
+			results := make([]bool, len(test.cancelled))
+			cancels := make([]context.CancelFunc, len(test.cancelled))
+
+			for i := range test.cancelled {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancels[i] = cancel
+				defer cancel()
+
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					resp, err := c.Recognize(ctx, key, r.recognize)
+					results[i] = err == nil && resp.Words[0].Word == "hello"
+				}(i)
+
+				// Joining in order, the first one leads
+				waitFor(t, func() bool {
+					c.mu.Lock()
+					defer c.mu.Unlock()
+					return c.inFlight[key] != nil && c.inFlight[key].waiters == i+1
+				})
+			}
+
+			c.mu.Lock()
+			pending := c.inFlight[key]
+			c.mu.Unlock()
+
+			for i, cancelled := range test.cancelled {
+				if cancelled {
+					cancels[i]()
+				}
+			}
+
+			if test.abandoned {
+				waitFor(t, func() bool { return pending.ctx.Err() != nil })
+			} else {
+				close(r.release)
+			}
+
+			wg.Wait()
+
+			for i := range results {
+				if results[i] != test.results[i] {
+					t.Errorf("request %d got the recognition = %v, want %v", i, results[i], test.results[i])
+				}
+			}
+
+			if calls := atomic.LoadInt32(&r.calls); calls != 1 {
+				t.Errorf("recognized %d times, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestEvict(t *testing.T) {
+	c := open(t, Config{MaxSize: 1, TTL: 1})
+
+	// Three recognitions of 400KB each, only two fit in a megabyte
+	big := strings.Repeat("a", 400*1024)
+	keys := []string{"aa01", "bb02", "cc03"}
+
+	for i, key := range keys {
+		err := c.put(key, recognizers.Response{Words: []recognizers.TimedWord{{Word: big}}}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The first one is used again, the second becomes the least recently used
+		if i == 1 {
+			if _, ok := c.get(keys[0], time.Now()); !ok {
+				t.Fatal("first recognition isn't cached")
+			}
+		}
+	}
+
+	for key, want := range map[string]bool{"aa01": true, "bb02": false, "cc03": true} {
+		if _, ok := c.get(key, time.Now()); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+
+		if _, err := os.Stat(c.path(key)); (err == nil) != want {
+			t.Errorf("%s file exists = %v, want %v", key, err == nil, want)
+		}
+	}
+
+	// Expired once the TTL has passed
+	if _, ok := c.get("cc03", time.Now().Add(2*time.Hour)); ok {
+		t.Error("cc03 is still cached after its TTL")
+	}
+
+	// Recognitions older than the TTL are dropped when indexed
+	old := time.Now().Add(-2 * time.Hour)
+	err := os.Chtimes(filepath.Join(c.config.Path, "aa", "aa01.json"), old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := open(t, c.config)
+	if _, ok := reopened.get("aa01", time.Now()); ok {
+		t.Error("aa01 is still cached after reopening past its TTL")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,205 @@
+package recognizers
+
+import (
+	"strings"
+	"time"
+)
+
+// Config ...
+// Recognition settings for a single channel, providers ignore any settings they don't support
+type Config struct {
+	Provider     string `json:"provider"`       // e.g. "gcp"
+	Name         string `json:"name,omitempty"` // Identifies the recognizer in failover chains and transcripts, the provider by default
+	LanguageCode string `json:"languageCode"`   // BCP-47, e.g. "en-US"
+	// Other languages that may be spoken, the language is then detected per segment
+	AlternativeLanguages []string `json:"alternativeLanguages"`
+	Model                string   `json:"model"` // Provider specific model name, e.g. "video"
+	UseEnhanced          bool     `json:"useEnhanced"`
+	Punctuation          bool     `json:"punctuation"`
+	Diarization          bool     `json:"diarization"` // Tag each word with its speaker
+	MinSpeakers          int      `json:"minSpeakers"` // Expected speaker counts for diarization, 0 leaves it to the provider
+	MaxSpeakers          int      `json:"maxSpeakers"`
+	Endpoint             string   `json:"endpoint"` // Overrides the provider's API endpoint, e.g. a local fake server
+	Insecure             bool     `json:"insecure"` // Plaintext connection without authentication, only for local endpoints
+	// Provider specific settings, e.g. the fake provider's transcript, delay and failureRate
+	Options map[string]string `json:"options,omitempty"`
+	// Recognizers tried in order when this one fails or is unhealthy, they speak the same languages unless set
+	Failover []Config `json:"failover,omitempty"`
+	Breaker  Breaker  `json:"breaker"`
+	// Recognizers sent every segment in parallel with this one, their words are merged by confidence weighted voting
+	Ensemble     []Config `json:"ensemble,omitempty"`
+	EnsembleWait float64  `json:"ensembleWait"`     // Seconds to wait for the others once the first recognizer of an ensemble answered
+	Weight       float64  `json:"weight,omitempty"` // Multiplies the recognizer's confidence in ensemble votes, 1 when not set
+}
+
+// DefaultEnsembleWait ...
+const DefaultEnsembleWait = 5
+
+// Breaker ...
+// Circuit breaker settings of a failover chain, any unset value falls back to its default
+type Breaker struct {
+	Window       int     `json:"window"`       // Recent requests the error rate is computed over
+	MinRequests  int     `json:"minRequests"`  // Requests in the window before the circuit may open
+	MaxErrorRate float64 `json:"maxErrorRate"` // Share of failed or slow requests that opens the circuit
+	SlowCall     float64 `json:"slowCall"`     // Seconds after which a successful request still counts against the recognizer
+	Timeout      float64 `json:"timeout"`      // Seconds before a request is abandoned for the next recognizer
+	Cooldown     float64 `json:"cooldown"`     // Seconds the circuit stays open before a trial request
+}
+
+// DefaultBreaker ...
+var DefaultBreaker = Breaker{
+	Window:       20,
+	MinRequests:  5,
+	MaxErrorRate: 0.5,
+	SlowCall:     8,
+	Timeout:      15,
+	Cooldown:     30,
+}
+
+// WithDefaults ...
+func (b Breaker) WithDefaults() Breaker {
+	if b.Window <= 0 {
+		b.Window = DefaultBreaker.Window
+	}
+
+	if b.MinRequests <= 0 {
+		b.MinRequests = DefaultBreaker.MinRequests
+	}
+
+	if b.MaxErrorRate <= 0 {
+		b.MaxErrorRate = DefaultBreaker.MaxErrorRate
+	}
+
+	if b.SlowCall <= 0 {
+		b.SlowCall = DefaultBreaker.SlowCall
+	}
+
+	if b.Timeout <= 0 {
+		b.Timeout = DefaultBreaker.Timeout
+	}
+
+	if b.Cooldown <= 0 {
+		b.Cooldown = DefaultBreaker.Cooldown
+	}
+
+	return b
+}
+
+// Seconds ...
+func Seconds(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ID ...
+// The recognizer's name, or its provider
+func (c Config) ID() string {
+	if c.Name != "" {
+		return c.Name
+	}
+
+	if c.Provider == "" {
+		return "gcp"
+	}
+
+	return c.Provider
+}
+
+// Chain ...
+// The recognizer followed by its failover recognizers, in order
+func (c Config) Chain() []Config {
+	return c.withMembers(c.Failover)
+}
+
+// Voters ...
+// The recognizer followed by the other recognizers of its ensemble
+func (c Config) Voters() []Config {
+	return c.withMembers(c.Ensemble)
+}
+
+// VoteWeight ...
+func (c Config) VoteWeight() float64 {
+	if c.Weight <= 0 {
+		return 1
+	}
+
+	return c.Weight
+}
+
+// withMembers lists the recognizer, on its own, followed by the given ones speaking its languages unless set
+func (c Config) withMembers(members []Config) []Config {
+	primary := c
+	primary.Failover = nil
+	primary.Ensemble = nil
+
+	result := []Config{primary}
+	for _, member := range members {
+		if member.LanguageCode == "" {
+			member.LanguageCode = c.LanguageCode
+			member.AlternativeLanguages = c.AlternativeLanguages
+		}
+
+		member.Failover = nil
+		member.Ensemble = nil
+		result = append(result, member)
+	}
+
+	return result
+}
+
+// Member ...
+// The recognizer of the failover chain or ensemble with the given ID
+func (c Config) Member(id string) (Config, bool) {
+	for _, member := range append(c.Chain(), c.Voters()[1:]...) {
+		if member.ID() == id {
+			return member, true
+		}
+	}
+
+	return Config{}, false
+}
+
+// Languages ...
+// Every language that may be spoken, the main one first
+func (c Config) Languages() []string {
+	return append([]string{c.LanguageCode}, c.AlternativeLanguages...)
+}
+
+// MatchLanguage ...
+// Maps a detected language onto the configured one it belongs to, providers may report e.g. "fr-ca" for "fr-CA".
+// The main language is assumed when nothing matches.
+func (c Config) MatchLanguage(detected string) string {
+	for _, language := range c.Languages() {
+		if strings.EqualFold(language, detected) {
+			return language
+		}
+	}
+
+	for _, language := range c.Languages() {
+		if detected != "" && BaseLanguage(language) == BaseLanguage(detected) {
+			return language
+		}
+	}
+
+	return c.LanguageCode
+}
+
+// BaseLanguage ...
+// The language of a BCP-47 code without its region or script, lowercased, e.g. "fr" for "fr-CA"
+func BaseLanguage(language string) string {
+	if i := strings.IndexAny(language, "-_"); i > 0 {
+		return strings.ToLower(language[:i])
+	}
+
+	return strings.ToLower(language)
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Provider:     "gcp",
+		LanguageCode: "en-US",
+		Model:        "video",
+		UseEnhanced:  true,
+		Punctuation:  true, // This flag only works on English content
+	}
+}
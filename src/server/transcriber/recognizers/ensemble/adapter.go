@@ -0,0 +1,195 @@
+package ensemble
+
+import (
+	"context"
+	"fmt"
+	"server/logging"
+	"server/metrics"
+	"server/tracing"
+	"server/transcriber/recognizers"
+	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// defaultConfidence ...
+// Votes of recognizers that don't report a confidence
+const defaultConfidence = 0.5
+
+var (
+	requestsTotal = metrics.NewCounterVec(
+		"vsr_ensemble_requests_total",
+		"Ensemble recognition requests, by channel, recognizer and result (ok, error, late)",
+		"channel", "recognizer", "result",
+	)
+	slotsTotal = metrics.NewCounterVec(
+		"vsr_ensemble_slots_total",
+		"Aligned word positions voted on by ensembles, by channel",
+		"channel",
+	)
+	disagreementsTotal = metrics.NewCounterVec(
+		"vsr_ensemble_disagreements_total",
+		"Aligned word positions where the ensemble's recognizers didn't all agree, by channel",
+		"channel",
+	)
+)
+
+// Member ...
+type Member struct {
+	Name    string
+	Adapter recognizers.Adapter
+	Weight  float64 // Multiplies the recognizer's confidence in votes
+}
+
+type result struct {
+	index int
+	resp  recognizers.Response
+	err   error
+}
+
+// Adapter ...
+// Sends every request to all of its recognizers in parallel and merges their words by confidence weighted voting.
+// Recognizers still running some time after the first one answered are left out, as are failed ones.
+type Adapter struct {
+	channel string
+	members []Member
+	wait    time.Duration
+}
+
+// New ...
+func New(channel string, members []Member, wait time.Duration) *Adapter {
+	return &Adapter{
+		channel: channel,
+		members: members,
+		wait:    wait,
+	}
+}
+
+// Init ...
+func (a *Adapter) Init() {
+	for _, m := range a.members {
+		m.Adapter.Init()
+	}
+}
+
+// Input ...
+func (a *Adapter) Input(ctx context.Context, audio []byte) (recognizers.Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, len(a.members))
+	for i, m := range a.members {
+		go func(i int, m Member) {
+			ctx, span := trace.StartSpan(ctx, "vsr.recognizer")
+			span.AddAttributes(trace.StringAttribute("recognizer", m.Name))
+
+			resp, err := m.Adapter.Input(ctx, audio)
+			tracing.End(span, err)
+			results <- result{index: i, resp: resp, err: err}
+		}(i, m)
+	}
+
+	responses := make(map[int]recognizers.Response)
+	failed := make(map[int]bool)
+	errs := make([]string, 0)
+	var deadline <-chan time.Time
+
+	for received := 0; received < len(a.members); received++ {
+		var r result
+		select {
+		case r = <-results:
+		case <-deadline:
+			for i, m := range a.members {
+				if _, ok := responses[i]; !ok && !failed[i] {
+					requestsTotal.Inc(a.channel, m.Name, "late")
+				}
+			}
+
+			return a.merge(responses), nil
+		case <-ctx.Done():
+			return recognizers.Response{}, ctx.Err()
+		}
+
+		name := a.members[r.index].Name
+		if r.err != nil {
+			logging.FromContext(ctx, "recognizers").Warn("Ensemble recognizer failed", "recognizer", name, "err", r.err)
+			requestsTotal.Inc(a.channel, name, "error")
+			failed[r.index] = true
+			errs = append(errs, fmt.Sprintf("%s: %v", name, r.err))
+			continue
+		}
+
+		requestsTotal.Inc(a.channel, name, "ok")
+		responses[r.index] = r.resp
+
+		if deadline == nil {
+			deadline = time.After(a.wait)
+		}
+	}
+
+	if len(responses) == 0 {
+		return recognizers.Response{}, fmt.Errorf("every ensemble recognizer failed, %s", strings.Join(errs, "; "))
+	}
+
+	return a.merge(responses), nil
+}
+
+// merge votes between the responses in the language most of the weight detected, in the recognizers' order
+func (a *Adapter) merge(responses map[int]recognizers.Response) recognizers.Response {
+	weights := make(map[int]float64)
+	languages := make(map[string]float64)
+
+	for i, resp := range responses {
+		confidence := float64(resp.Confidence)
+		if confidence <= 0 {
+			confidence = defaultConfidence
+		}
+
+		weights[i] = a.members[i].Weight * confidence
+		languages[recognizers.BaseLanguage(resp.Language)] += weights[i]
+	}
+
+	language := ""
+	for i := range a.members {
+		if resp, ok := responses[i]; ok && languages[recognizers.BaseLanguage(resp.Language)] > languages[language] {
+			language = recognizers.BaseLanguage(resp.Language)
+		}
+	}
+
+	merged := recognizers.Response{}
+	hypotheses := make([]Hypothesis, 0, len(responses))
+	providers := make([]string, 0, len(responses))
+	var confidence float32
+
+	for i, m := range a.members {
+		resp, ok := responses[i]
+		if !ok || recognizers.BaseLanguage(resp.Language) != language {
+			continue
+		}
+
+		if merged.Language == "" {
+			merged.Language = resp.Language
+		}
+
+		hypotheses = append(hypotheses, Hypothesis{Words: resp.Words, Weight: weights[i]})
+		providers = append(providers, m.Name)
+		confidence += resp.Confidence
+	}
+
+	result := Merge(hypotheses)
+
+	merged.Words = result.Words
+	merged.Confidence = confidence / float32(len(hypotheses))
+	merged.Provider = strings.Join(providers, recognizers.ProviderSeparator)
+
+	if len(hypotheses) > 1 {
+		disagreement := result.Disagreement()
+		merged.Disagreement = &disagreement
+
+		slotsTotal.Add(float64(result.Slots), a.channel)
+		disagreementsTotal.Add(float64(result.Disagreements), a.channel)
+	}
+
+	return merged
+}
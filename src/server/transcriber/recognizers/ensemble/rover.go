@@ -0,0 +1,210 @@
+package ensemble
+
+import (
+	"server/transcriber/recognizers"
+	"time"
+)
+
+// tolerance ...
+// Words further apart than this aren't aligned with each other, even when they're the same
+const tolerance = 500 * time.Millisecond
+
+// Hypothesis ...
+// One recognizer's words, voting with the given weight
+type Hypothesis struct {
+	Words  []recognizers.TimedWord
+	Weight float64
+}
+
+// arc is one hypothesis' word in a slot, or its absence
+type arc struct {
+	word       recognizers.TimedWord
+	null       bool
+	hypothesis int
+}
+
+// slot holds the words the hypotheses align at the same position, one arc per hypothesis
+type slot struct {
+	arcs []arc
+}
+
+// Result ...
+type Result struct {
+	Words         []recognizers.TimedWord
+	Slots         int // Aligned word positions
+	Disagreements int // Slots where the hypotheses didn't all agree
+}
+
+// Disagreement ...
+// The share of slots where the hypotheses didn't all agree
+func (r Result) Disagreement() float64 {
+	if r.Slots == 0 {
+		return 0
+	}
+
+	return float64(r.Disagreements) / float64(r.Slots)
+}
+
+// Merge ...
+// Aligns the hypotheses into a word transition network and keeps the words with the most weight in each of its slots
+// (ROVER)
+func Merge(hypotheses []Hypothesis) Result {
+	network := make([]slot, 0)
+	for i, hypothesis := range hypotheses {
+		network = align(network, hypothesis.Words, i)
+	}
+
+	result := Result{Words: make([]recognizers.TimedWord, 0, len(network)), Slots: len(network)}
+
+	for _, s := range network {
+		votes := make(map[string]float64)
+		best := make(map[string]arc)
+
+		for _, a := range s.arcs {
+			key := ""
+			if !a.null {
+				key = recognizers.Normalize(a.word.Word)
+			}
+
+			votes[key] += hypotheses[a.hypothesis].Weight
+
+			// The most trusted hypothesis' spelling and timing represent the word
+			if current, ok := best[key]; !ok || hypotheses[a.hypothesis].Weight > hypotheses[current.hypothesis].Weight {
+				best[key] = a
+			}
+		}
+
+		if len(votes) > 1 {
+			result.Disagreements++
+		}
+
+		// Ties go to the earliest hypothesis, i.e. the first recognizer
+		winner := ""
+		winnerVotes := -1.0
+		for _, a := range s.arcs {
+			key := ""
+			if !a.null {
+				key = recognizers.Normalize(a.word.Word)
+			}
+
+			if votes[key] > winnerVotes {
+				winner, winnerVotes = key, votes[key]
+			}
+		}
+
+		if winner != "" {
+			result.Words = append(result.Words, best[winner].word)
+		}
+	}
+
+	return result
+}
+
+// align adds a hypothesis to the network with the fewest substitutions, insertions and deletions
+func align(network []slot, words []recognizers.TimedWord, hypothesis int) []slot {
+	rows, cols := len(network)+1, len(words)+1
+
+	costs := make([][]int, rows)
+	for i := range costs {
+		costs[i] = make([]int, cols)
+		costs[i][0] = i
+	}
+
+	for j := 0; j < cols; j++ {
+		costs[0][j] = j
+	}
+
+	for i := 1; i < rows; i++ {
+		for j := 1; j < cols; j++ {
+			costs[i][j] = minOf(
+				costs[i-1][j-1]+substitution(network[i-1], words[j-1]),
+				costs[i-1][j]+1,
+				costs[i][j-1]+1,
+			)
+		}
+	}
+
+	// Walking back from the end, preferring to align the words with a slot
+	result := make([]slot, 0, rows+cols)
+	i, j := len(network), len(words)
+
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && costs[i][j] == costs[i-1][j-1]+substitution(network[i-1], words[j-1]):
+			s := network[i-1]
+			s.arcs = append(append([]arc{}, s.arcs...), arc{word: words[j-1], hypothesis: hypothesis})
+			result = append(result, s)
+			i, j = i-1, j-1
+
+		case i > 0 && costs[i][j] == costs[i-1][j]+1:
+			s := network[i-1]
+			s.arcs = append(append([]arc{}, s.arcs...), arc{null: true, hypothesis: hypothesis})
+			result = append(result, s)
+			i--
+
+		default:
+			// A word no previous hypothesis has, they all get a null arc
+			s := slot{arcs: make([]arc, 0, hypothesis+1)}
+			for previous := 0; previous < hypothesis; previous++ {
+				s.arcs = append(s.arcs, arc{null: true, hypothesis: previous})
+			}
+
+			s.arcs = append(s.arcs, arc{word: words[j-1], hypothesis: hypothesis})
+			result = append(result, s)
+			j--
+		}
+	}
+
+	for left, right := 0, len(result)-1; left < right; left, right = left+1, right-1 {
+		result[left], result[right] = result[right], result[left]
+	}
+
+	return result
+}
+
+// substitution is free for the same word at about the same time, words far apart in time cost more than
+// an insertion and a deletion so they're never aligned
+func substitution(s slot, word recognizers.TimedWord) int {
+	var start, end time.Duration
+	found := false
+	same := false
+
+	for _, a := range s.arcs {
+		if a.null {
+			continue
+		}
+
+		if !found || a.word.Start.Duration() < start {
+			start = a.word.Start.Duration()
+		}
+
+		if !found || a.word.End.Duration() > end {
+			end = a.word.End.Duration()
+		}
+
+		found = true
+		same = same || recognizers.Normalize(a.word.Word) == recognizers.Normalize(word.Word)
+	}
+
+	cost := 1
+	if same {
+		cost = 0
+	}
+
+	if found && (word.Start.Duration() > end+tolerance || word.End.Duration() < start-tolerance) {
+		cost = 3
+	}
+
+	return cost
+}
+
+func minOf(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+
+	return result
+}
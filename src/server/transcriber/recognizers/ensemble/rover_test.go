@@ -0,0 +1,123 @@
+package ensemble
+
+import (
+	"math"
+	"server/transcriber/recognizers"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hypothesis spaces the words half a second apart, starting at the given second
+func hypothesis(weight float64, start float64, text string) Hypothesis {
+	words := make([]recognizers.TimedWord, 0)
+	for i, word := range strings.Fields(text) {
+		at := time.Duration((start + float64(i)*0.5) * float64(time.Second))
+		words = append(words, recognizers.TimedWord{
+			Start: recognizers.FromDuration(at),
+			End:   recognizers.FromDuration(at + 400*time.Millisecond),
+			Word:  word,
+		})
+	}
+
+	return Hypothesis{Words: words, Weight: weight}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name         string
+		hypotheses   []Hypothesis
+		words        string
+		slots        int
+		disagreement float64
+	}{
+		{
+			name:       "no hypotheses",
+			hypotheses: []Hypothesis{},
+		},
+		{
+			name:       "agreement",
+			hypotheses: []Hypothesis{hypothesis(1, 0, "the cat sat"), hypothesis(1, 0, "the cat sat"), hypothesis(1, 0, "the cat sat")},
+			words:      "the cat sat",
+			slots:      3,
+		},
+		{
+			name:         "substitution outvoted",
+			hypotheses:   []Hypothesis{hypothesis(1, 0, "the cat sat"), hypothesis(1, 0, "the hat sat"), hypothesis(1, 0, "the cat sat")},
+			words:        "the cat sat",
+			slots:        3,
+			disagreement: 1.0 / 3,
+		},
+		{
+			name:         "deletion outvoted",
+			hypotheses:   []Hypothesis{hypothesis(1, 0, "the big cat"), hypothesis(1, 0, "the cat"), hypothesis(1, 0, "the big cat")},
+			words:        "the big cat",
+			slots:        3,
+			disagreement: 1.0 / 3,
+		},
+		{
+			name:         "insertion outvoted",
+			hypotheses:   []Hypothesis{hypothesis(1, 0, "the cat"), hypothesis(1, 0, "the fat cat"), hypothesis(1, 0, "the cat")},
+			words:        "the cat",
+			slots:        3,
+			disagreement: 1.0 / 3,
+		},
+		{
+			name:         "weighted majority keeps the trusted word",
+			hypotheses:   []Hypothesis{hypothesis(1, 0, "hello world"), hypothesis(0.5, 0, "yellow world"), hypothesis(0.4, 0, "yellow world")},
+			words:        "hello world",
+			slots:        2,
+			disagreement: 0.5,
+		},
+		{
+			name:         "weighted majority outvotes the trusted word",
+			hypotheses:   []Hypothesis{hypothesis(1, 0, "hello world"), hypothesis(0.6, 0, "yellow world"), hypothesis(0.6, 0, "yellow world")},
+			words:        "yellow world",
+			slots:        2,
+			disagreement: 0.5,
+		},
+		{
+			name:         "ties go to the first recognizer",
+			hypotheses:   []Hypothesis{hypothesis(1, 0, "a b"), hypothesis(1, 0, "a c")},
+			words:        "a b",
+			slots:        2,
+			disagreement: 0.5,
+		},
+		{
+			name:       "spelling of the most trusted",
+			hypotheses: []Hypothesis{hypothesis(0.5, 0, "Paris"), hypothesis(1, 0, "paris,")},
+			words:      "paris,",
+			slots:      1,
+		},
+		{
+			name:         "same word far apart isn't aligned",
+			hypotheses:   []Hypothesis{hypothesis(1, 0, "yes"), hypothesis(1, 5, "yes")},
+			words:        "yes",
+			slots:        2,
+			disagreement: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Merge(test.hypotheses)
+
+			words := make([]string, 0)
+			for _, word := range result.Words {
+				words = append(words, word.Word)
+			}
+
+			if strings.Join(words, " ") != test.words {
+				t.Errorf("words = %q, want %q", strings.Join(words, " "), test.words)
+			}
+
+			if result.Slots != test.slots {
+				t.Errorf("slots = %d, want %d", result.Slots, test.slots)
+			}
+
+			if math.Abs(result.Disagreement()-test.disagreement) > 1e-9 {
+				t.Errorf("disagreement = %v, want %v", result.Disagreement(), test.disagreement)
+			}
+		})
+	}
+}
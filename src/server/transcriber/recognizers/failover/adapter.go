@@ -0,0 +1,259 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"server/logging"
+	"server/metrics"
+	"server/tracing"
+	"server/transcriber/recognizers"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+var (
+	requestsTotal = metrics.NewCounterVec(
+		"vsr_recognizer_requests_total",
+		"Recognition requests of failover chains, by channel, recognizer and result (ok, slow, error, timeout)",
+		"channel", "recognizer", "result",
+	)
+	latencySeconds = metrics.NewHistogramVec(
+		"vsr_recognizer_latency_seconds",
+		"Time taken by each recognition request of a failover chain, by channel and recognizer",
+		metrics.LatencyBuckets,
+		"channel", "recognizer",
+	)
+	circuitState = metrics.NewGaugeVec(
+		"vsr_recognizer_circuit_state",
+		"Circuit breaker state of each recognizer of a failover chain: 0 closed, 1 half-open, 2 open",
+		"channel", "recognizer",
+	)
+	failoversTotal = metrics.NewCounterVec(
+		"vsr_recognizer_failovers_total",
+		"Segments recognized by another recognizer than the first of the chain, by channel and recognizer",
+		"channel", "recognizer",
+	)
+)
+
+var stateValues = map[string]float64{
+	StateClosed:   0,
+	StateHalfOpen: 1,
+	StateOpen:     2,
+}
+
+// Member ...
+type Member struct {
+	Name    string
+	Adapter recognizers.Adapter
+}
+
+type member struct {
+	Member
+	index   int
+	breaker *breaker
+}
+
+// Status ...
+type Status struct {
+	Name      string  `json:"name"`
+	State     string  `json:"state"`
+	ErrorRate float64 `json:"errorRate"` // Failed or slow share of the recent requests
+	Latency   float64 `json:"latency"`   // Average of the recent requests, in seconds
+	Requests  int     `json:"requests"`
+	Failures  int     `json:"failures"`
+	LastError string  `json:"lastError,omitempty"`
+}
+
+// Adapter ...
+// Routes each request to the healthiest of an ordered list of recognizers, failing over to the next ones when it
+// fails or times out. Responses are tagged with the recognizer that produced them.
+type Adapter struct {
+	channel string
+	config  recognizers.Breaker
+	members []*member
+}
+
+// New ...
+func New(channel string, members []Member, config recognizers.Breaker) *Adapter {
+	config = config.WithDefaults()
+
+	a := &Adapter{
+		channel: channel,
+		config:  config,
+		members: make([]*member, 0, len(members)),
+	}
+
+	for i, m := range members {
+		a.members = append(a.members, &member{Member: m, index: i, breaker: newBreaker(config)})
+	}
+
+	return a
+}
+
+// Init ...
+func (a *Adapter) Init() {
+	for _, m := range a.members {
+		m.Adapter.Init()
+		circuitState.Set(stateValues[StateClosed], a.channel, m.Name)
+	}
+}
+
+// Input ...
+func (a *Adapter) Input(ctx context.Context, audio []byte) (recognizers.Response, error) {
+	errs := make([]string, 0)
+	attempts := 0
+
+	for _, m := range a.route(time.Now()) {
+		if ctx.Err() != nil {
+			return recognizers.Response{}, ctx.Err()
+		}
+
+		if !m.breaker.acquire(time.Now(), attempts == 0) {
+			continue
+		}
+
+		attempts++
+
+		attempt, cancel := context.WithTimeout(ctx, recognizers.Seconds(a.config.Timeout))
+		attempt, span := trace.StartSpan(attempt, "vsr.recognizer")
+		span.AddAttributes(trace.StringAttribute("recognizer", m.Name), trace.Int64Attribute("attempt", int64(attempts)))
+
+		start := time.Now()
+		resp, err := m.Adapter.Input(attempt, audio)
+		latency := time.Since(start)
+		timedOut := attempt.Err() == context.DeadlineExceeded
+		tracing.End(span, err)
+		cancel()
+
+		// The caller giving up says nothing about the recognizer's health
+		if err != nil && ctx.Err() != nil {
+			m.breaker.release()
+			return recognizers.Response{}, ctx.Err()
+		}
+
+		a.record(m, err, timedOut, latency)
+
+		if err == nil {
+			if m.index > 0 {
+				failoversTotal.Inc(a.channel, m.Name)
+			}
+
+			if resp.Provider == "" {
+				resp.Provider = m.Name
+			}
+
+			return resp, nil
+		}
+
+		logging.FromContext(ctx, "recognizers").Warn("Recognizer failed, failing over", "recognizer", m.Name, "err", err)
+		errs = append(errs, fmt.Sprintf("%s: %v", m.Name, err))
+	}
+
+	if len(errs) == 0 {
+		return recognizers.Response{}, fmt.Errorf("no recognizer available")
+	}
+
+	return recognizers.Response{}, fmt.Errorf("every recognizer failed, %s", strings.Join(errs, "; "))
+}
+
+// Status ...
+// The health of every recognizer, in order
+func (a *Adapter) Status() []Status {
+	now := time.Now()
+
+	result := make([]Status, 0, len(a.members))
+	for _, m := range a.members {
+		b := m.breaker
+		b.mu.Lock()
+		result = append(result, Status{
+			Name:      m.Name,
+			State:     b.current(now),
+			ErrorRate: b.errorRate(),
+			Latency:   b.latency().Seconds(),
+			Requests:  b.requests,
+			Failures:  b.failures,
+			LastError: b.lastError,
+		})
+		b.mu.Unlock()
+	}
+
+	return result
+}
+
+func (a *Adapter) record(m *member, err error, timedOut bool, latency time.Duration) {
+	m.breaker.record(time.Now(), err, latency)
+
+	result := "ok"
+	switch {
+	case timedOut:
+		result = "timeout"
+	case err != nil:
+		result = "error"
+	case latency > recognizers.Seconds(a.config.SlowCall):
+		result = "slow"
+	}
+
+	requestsTotal.Inc(a.channel, m.Name, result)
+	latencySeconds.Observe(latency.Seconds(), a.channel, m.Name)
+
+	m.breaker.mu.Lock()
+	circuitState.Set(stateValues[m.breaker.current(time.Now())], a.channel, m.Name)
+	m.breaker.mu.Unlock()
+}
+
+// route orders the recognizers for a request: cooled down ones get their trial first, then the healthy ones in order,
+// then the degraded ones by error rate. Open ones come last, and are only tried when nothing else was.
+func (a *Adapter) route(now time.Time) []*member {
+	type candidate struct {
+		member    *member
+		tier      int
+		errorRate float64
+		openedAt  time.Time
+	}
+
+	candidates := make([]candidate, 0, len(a.members))
+	for _, m := range a.members {
+		b := m.breaker
+		b.mu.Lock()
+
+		c := candidate{member: m, errorRate: b.errorRate(), openedAt: b.openedAt}
+		switch b.current(now) {
+		case StateHalfOpen:
+			c.tier = 0
+		case StateClosed:
+			c.tier = 1
+			if b.degraded() {
+				c.tier = 2
+			}
+		default:
+			c.tier = 3
+		}
+
+		b.mu.Unlock()
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		x, y := candidates[i], candidates[j]
+		switch {
+		case x.tier != y.tier:
+			return x.tier < y.tier
+		case x.tier == 2:
+			return x.errorRate < y.errorRate
+		case x.tier == 3:
+			return x.openedAt.Before(y.openedAt)
+		}
+
+		return false
+	})
+
+	result := make([]*member, 0, len(candidates))
+	for _, c := range candidates {
+		result = append(result, c.member)
+	}
+
+	return result
+}
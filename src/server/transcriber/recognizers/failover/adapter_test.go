@@ -0,0 +1,225 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/fake"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBreaker opens after two failures out of the last four requests, and cools down in 50ms
+var testBreaker = recognizers.Breaker{Window: 4, MinRequests: 2, MaxErrorRate: 0.5, SlowCall: 1, Timeout: 0.02, Cooldown: 0.05}
+
+func newChain(adapters ...*fake.Adapter) *Adapter {
+	names := []string{"primary", "secondary", "tertiary"}
+
+	members := make([]Member, 0, len(adapters))
+	for i, adapter := range adapters {
+		members = append(members, Member{Name: names[i], Adapter: adapter})
+	}
+
+	a := New("test", members, testBreaker)
+	a.Init()
+	return a
+}
+
+func states(a *Adapter) []string {
+	result := make([]string, 0)
+	for _, status := range a.Status() {
+		result = append(result, status.State)
+	}
+
+	return result
+}
+
+func TestInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		adapters  []*fake.Adapter
+		requests  int
+		providers []string // Of each request, empty when it failed
+		states    []string // After the requests
+	}{
+		{
+			name:      "healthy primary",
+			adapters:  []*fake.Adapter{{}, {}},
+			requests:  3,
+			providers: []string{"primary", "primary", "primary"},
+			states:    []string{StateClosed, StateClosed},
+		},
+		{
+			name:      "failing primary opens",
+			adapters:  []*fake.Adapter{{FailureRate: 1}, {}},
+			requests:  3,
+			providers: []string{"secondary", "secondary", "secondary"},
+			states:    []string{StateOpen, StateClosed},
+		},
+		{
+			name:      "timing out primary opens",
+			adapters:  []*fake.Adapter{{Delay: time.Second}, {}},
+			requests:  3,
+			providers: []string{"secondary", "secondary", "secondary"},
+			states:    []string{StateOpen, StateClosed},
+		},
+		{
+			name:      "failing over twice",
+			adapters:  []*fake.Adapter{{FailureRate: 1}, {FailureRate: 1}, {}},
+			requests:  3,
+			providers: []string{"tertiary", "tertiary", "tertiary"},
+			states:    []string{StateOpen, StateOpen, StateClosed},
+		},
+		{
+			name:      "every recognizer failing",
+			adapters:  []*fake.Adapter{{FailureRate: 1}, {FailureRate: 1}},
+			requests:  3,
+			providers: []string{"", "", ""},
+			states:    []string{StateOpen, StateOpen},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newChain(test.adapters...)
+
+			for i := 0; i < test.requests; i++ {
+				resp, err := a.Input(context.Background(), nil)
+				if test.providers[i] == "" {
+					if err == nil || !strings.HasPrefix(err.Error(), "every recognizer failed") {
+						t.Fatalf("request %d: err = %v, want every recognizer failed", i, err)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Fatalf("request %d: %v", i, err)
+				}
+
+				if resp.Provider != test.providers[i] {
+					t.Errorf("request %d: provider = %s, want %s", i, resp.Provider, test.providers[i])
+				}
+			}
+
+			got := states(a)
+			if strings.Join(got, ",") != strings.Join(test.states, ",") {
+				t.Errorf("states = %v, want %v", got, test.states)
+			}
+		})
+	}
+}
+
+func TestInputRecovers(t *testing.T) {
+	primary := &fake.Adapter{FailureRate: 1}
+	a := newChain(primary, &fake.Adapter{})
+
+	for i := 0; i < 2; i++ {
+		a.Input(context.Background(), nil)
+	}
+
+	if got := states(a)[0]; got != StateOpen {
+		t.Fatalf("primary is %s, want %s", got, StateOpen)
+	}
+
+	// Once cooled down, the trial goes to the primary before anything else, and closes it
+	primary.FailureRate = 0
+	time.Sleep(recognizers.Seconds(testBreaker.Cooldown))
+
+	if got := states(a)[0]; got != StateHalfOpen {
+		t.Fatalf("primary is %s, want %s", got, StateHalfOpen)
+	}
+
+	resp, err := a.Input(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Provider != "primary" {
+		t.Errorf("trial went to %s, want primary", resp.Provider)
+	}
+
+	if got := states(a)[0]; got != StateClosed {
+		t.Errorf("primary is %s, want %s", got, StateClosed)
+	}
+}
+
+func TestInputCancelled(t *testing.T) {
+	a := newChain(&fake.Adapter{Delay: 10 * time.Millisecond}, &fake.Adapter{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err := a.Input(ctx, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The caller giving up doesn't count against the recognizer
+	if status := a.Status()[0]; status.Requests != 0 {
+		t.Errorf("primary recorded %d requests, want 0", status.Requests)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	failure := errors.New("unavailable")
+	start := time.Now()
+
+	tests := []struct {
+		name    string
+		setup   func(members []*member)
+		after   time.Duration
+		ordered []string
+	}{
+		{
+			name:    "healthy ones in order",
+			setup:   func(members []*member) {},
+			ordered: []string{"primary", "secondary", "tertiary"},
+		},
+		{
+			name: "degraded after healthy",
+			setup: func(members []*member) {
+				members[0].breaker.record(start, failure, 0)
+				members[0].breaker.record(start, nil, 0)
+				members[0].breaker.record(start, nil, 0)
+			},
+			ordered: []string{"secondary", "tertiary", "primary"},
+		},
+		{
+			name: "open last, the earliest opened first",
+			setup: func(members []*member) {
+				members[1].breaker.record(start, failure, 0)
+				members[1].breaker.record(start, failure, 0)
+				members[0].breaker.record(start.Add(time.Millisecond), failure, 0)
+				members[0].breaker.record(start.Add(time.Millisecond), failure, 0)
+			},
+			ordered: []string{"tertiary", "secondary", "primary"},
+		},
+		{
+			name: "half-open first",
+			setup: func(members []*member) {
+				members[2].breaker.record(start, failure, 0)
+				members[2].breaker.record(start, failure, 0)
+			},
+			after:   time.Minute,
+			ordered: []string{"tertiary", "primary", "secondary"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newChain(&fake.Adapter{}, &fake.Adapter{}, &fake.Adapter{})
+			test.setup(a.members)
+
+			names := make([]string, 0)
+			for _, m := range a.route(start.Add(test.after)) {
+				names = append(names, m.Name)
+			}
+
+			if strings.Join(names, ",") != strings.Join(test.ordered, ",") {
+				t.Errorf("route = %v, want %v", names, test.ordered)
+			}
+		})
+	}
+}
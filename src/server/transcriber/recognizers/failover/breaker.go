@@ -0,0 +1,164 @@
+package failover
+
+import (
+	"server/transcriber/recognizers"
+	"sync"
+	"time"
+)
+
+// Circuit states
+const (
+	StateClosed   = "closed"    // Healthy, receives requests in order
+	StateOpen     = "open"      // Failing, only used when every other recognizer is too
+	StateHalfOpen = "half-open" // Cooled down, the next request is a trial
+)
+
+// memory ...
+// Outcomes older than this no longer count, so a demoted recognizer gets requests again
+const memory = 5 * time.Minute
+
+type outcome struct {
+	at      time.Time
+	failed  bool // Failed or slow
+	latency time.Duration
+}
+
+// breaker tracks a recognizer's recent error rate and latency
+type breaker struct {
+	config recognizers.Breaker
+
+	mu        sync.Mutex
+	outcomes  []outcome // The most recent, up to the window
+	state     string
+	openedAt  time.Time
+	trial     bool // A half-open trial request is in flight
+	requests  int
+	failures  int
+	lastError string
+}
+
+func newBreaker(config recognizers.Breaker) *breaker {
+	return &breaker{
+		config:   config,
+		outcomes: make([]outcome, 0, config.Window),
+		state:    StateClosed,
+	}
+}
+
+// current must be called with the lock held, an open circuit becomes half-open once it has cooled down
+func (b *breaker) current(now time.Time) string {
+	for len(b.outcomes) > 0 && now.Sub(b.outcomes[0].at) > memory {
+		b.outcomes = b.outcomes[1:]
+	}
+
+	if b.state == StateOpen && now.Sub(b.openedAt) >= recognizers.Seconds(b.config.Cooldown) {
+		b.state = StateHalfOpen
+		b.trial = false
+	}
+
+	return b.state
+}
+
+// degraded must be called with the lock held, it's whether enough recent requests failed to prefer other recognizers
+func (b *breaker) degraded() bool {
+	return len(b.outcomes) >= b.config.MinRequests && b.errorRate() >= b.config.MaxErrorRate/2
+}
+
+// errorRate must be called with the lock held
+func (b *breaker) errorRate() float64 {
+	if len(b.outcomes) == 0 {
+		return 0
+	}
+
+	failed := 0
+	for _, o := range b.outcomes {
+		if o.failed {
+			failed++
+		}
+	}
+
+	return float64(failed) / float64(len(b.outcomes))
+}
+
+// latency must be called with the lock held
+func (b *breaker) latency() time.Duration {
+	if len(b.outcomes) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, o := range b.outcomes {
+		total += o.latency
+	}
+
+	return total / time.Duration(len(b.outcomes))
+}
+
+// acquire is whether the recognizer may take a request, an open circuit only as a last resort
+func (b *breaker) acquire(now time.Time, lastResort bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current(now) {
+	case StateClosed:
+		return true
+	case StateHalfOpen:
+		if b.trial {
+			return false
+		}
+
+		b.trial = true
+		return true
+	}
+
+	return lastResort
+}
+
+// release gives back a trial whose outcome isn't recorded, e.g. when the request was cancelled
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// record adds a request's outcome, opening or closing the circuit
+func (b *breaker) record(now time.Time, err error, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil || latency > recognizers.Seconds(b.config.SlowCall)
+
+	b.requests++
+	if err != nil {
+		b.failures++
+		b.lastError = err.Error()
+	}
+
+	if b.current(now) == StateHalfOpen {
+		b.trial = false
+		if failed {
+			b.open(now)
+			return
+		}
+
+		// The trial succeeded, the previous failures no longer count
+		b.state = StateClosed
+		b.outcomes = b.outcomes[:0]
+	}
+
+	b.outcomes = append(b.outcomes, outcome{at: now, failed: failed, latency: latency})
+	if len(b.outcomes) > b.config.Window {
+		b.outcomes = b.outcomes[len(b.outcomes)-b.config.Window:]
+	}
+
+	if b.state == StateClosed && len(b.outcomes) >= b.config.MinRequests && b.errorRate() >= b.config.MaxErrorRate {
+		b.open(now)
+	}
+}
+
+// open must be called with the lock held
+func (b *breaker) open(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+}
@@ -0,0 +1,130 @@
+package failover
+
+import (
+	"errors"
+	"server/transcriber/recognizers"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	config := recognizers.Breaker{Window: 4, MinRequests: 2, MaxErrorRate: 0.5, SlowCall: 1, Timeout: 2, Cooldown: 10}
+	failure := errors.New("unavailable")
+
+	type step struct {
+		after   time.Duration // Since the start of the test
+		err     error
+		latency time.Duration
+		state   string // After recording the outcome
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "stays closed under the error rate",
+			steps: []step{
+				{after: 0, state: StateClosed},
+				{after: time.Second, state: StateClosed},
+				{after: 2 * time.Second, err: failure, state: StateClosed},
+				{after: 3 * time.Second, state: StateClosed},
+			},
+		},
+		{
+			name: "opens once enough requests failed",
+			steps: []step{
+				{after: 0, err: failure, state: StateClosed},
+				{after: time.Second, err: failure, state: StateOpen},
+			},
+		},
+		{
+			name: "slow requests count as failures",
+			steps: []step{
+				{after: 0, latency: 2 * time.Second, state: StateClosed},
+				{after: time.Second, latency: 2 * time.Second, state: StateOpen},
+			},
+		},
+		{
+			name: "closes after a successful trial",
+			steps: []step{
+				{after: 0, err: failure, state: StateClosed},
+				{after: time.Second, err: failure, state: StateOpen},
+				{after: 11 * time.Second, state: StateClosed},
+				{after: 12 * time.Second, state: StateClosed},
+			},
+		},
+		{
+			name: "opens again after a failed trial",
+			steps: []step{
+				{after: 0, err: failure, state: StateClosed},
+				{after: time.Second, err: failure, state: StateOpen},
+				{after: 11 * time.Second, err: failure, state: StateOpen},
+				{after: 12 * time.Second, state: StateOpen},
+			},
+		},
+		{
+			name: "forgets old failures",
+			steps: []step{
+				{after: 0, err: failure, state: StateClosed},
+				{after: memory + time.Second, err: failure, state: StateClosed},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newBreaker(config)
+			start := time.Now()
+
+			for i, s := range test.steps {
+				now := start.Add(s.after)
+				if !b.acquire(now, false) && !b.acquire(now, true) {
+					t.Fatalf("step %d: could not acquire the recognizer", i)
+				}
+
+				b.record(now, s.err, s.latency)
+
+				b.mu.Lock()
+				state := b.current(now)
+				b.mu.Unlock()
+
+				if state != s.state {
+					t.Fatalf("step %d: state = %s, want %s", i, state, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerAcquire(t *testing.T) {
+	config := recognizers.Breaker{Window: 4, MinRequests: 1, MaxErrorRate: 0.5, SlowCall: 1, Timeout: 2, Cooldown: 10}
+	start := time.Now()
+
+	b := newBreaker(config)
+	b.record(start, errors.New("unavailable"), 0)
+
+	tests := []struct {
+		name       string
+		after      time.Duration
+		lastResort bool
+		want       bool
+	}{
+		{"open", time.Second, false, false},
+		{"open as a last resort", time.Second, true, true},
+		{"half-open trial", 10 * time.Second, false, true},
+		{"half-open while the trial is in flight", 10 * time.Second, false, false},
+	}
+
+	for _, test := range tests {
+		if got := b.acquire(start.Add(test.after), test.lastResort); got != test.want {
+			t.Errorf("%s: acquire = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// A cancelled trial leaves room for another one
+	b.release()
+	if !b.acquire(start.Add(10*time.Second), false) {
+		t.Errorf("released trial: acquire = false, want true")
+	}
+}
@@ -0,0 +1,104 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"server/transcriber/recognizers"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wordDuration ...
+// Every word of the transcript is given the same time
+const wordDuration = 400 * time.Millisecond
+
+// Adapter ...
+// A local stand-in for a recognition provider, returning the same transcript for any audio. Failures and delays can be
+// injected to exercise failover.
+type Adapter struct {
+	Transcript  string
+	Language    string
+	Confidence  float32
+	Delay       time.Duration // Simulated latency per request
+	FailureRate float64       // Share of requests failing, at random
+}
+
+// New ...
+// Reads the adapter's settings from the recognizer options: transcript, confidence, delay (e.g. "2s") and failureRate
+func New(config recognizers.Config) (*Adapter, error) {
+	a := &Adapter{
+		Transcript: "fake transcript",
+		Language:   config.LanguageCode,
+		Confidence: 0.9,
+	}
+
+	options := config.Options
+	if transcript, ok := options["transcript"]; ok {
+		a.Transcript = transcript
+	}
+
+	if raw, ok := options["confidence"]; ok {
+		confidence, err := strconv.ParseFloat(raw, 32)
+		if err != nil {
+			return nil, fmt.Errorf("fake recognizer: invalid confidence %q", raw)
+		}
+
+		a.Confidence = float32(confidence)
+	}
+
+	if raw, ok := options["delay"]; ok {
+		delay, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("fake recognizer: invalid delay %q", raw)
+		}
+
+		a.Delay = delay
+	}
+
+	if raw, ok := options["failureRate"]; ok {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("fake recognizer: invalid failureRate %q, expected 0 to 1", raw)
+		}
+
+		a.FailureRate = rate
+	}
+
+	return a, nil
+}
+
+// Init ...
+func (a *Adapter) Init() {}
+
+// Input ...
+func (a *Adapter) Input(ctx context.Context, audio []byte) (recognizers.Response, error) {
+	if a.Delay > 0 {
+		select {
+		case <-time.After(a.Delay):
+		case <-ctx.Done():
+			return recognizers.Response{}, ctx.Err()
+		}
+	}
+
+	if a.FailureRate > 0 && rand.Float64() < a.FailureRate {
+		return recognizers.Response{}, fmt.Errorf("fake recognizer: injected failure")
+	}
+
+	words := make([]recognizers.TimedWord, 0)
+	for i, word := range strings.Fields(a.Transcript) {
+		start := time.Duration(i) * wordDuration
+		words = append(words, recognizers.TimedWord{
+			Start: recognizers.FromDuration(start),
+			End:   recognizers.FromDuration(start + wordDuration),
+			Word:  word,
+		})
+	}
+
+	return recognizers.Response{
+		Words:      words,
+		Confidence: a.Confidence,
+		Language:   a.Language,
+	}, nil
+}
@@ -2,6 +2,7 @@ package gcp
 
 import (
 	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/gcp/fields"
 
 	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
 )
@@ -22,7 +23,8 @@ func ToTimedWords(words []*speechpb.WordInfo) []recognizers.TimedWord {
 				Seconds: word.EndTime.GetSeconds(),
 				Nanos:   word.EndTime.GetNanos(),
 			},
-			Word: word.Word,
+			Word:    word.Word,
+			Speaker: int(fields.SpeakerTag(word)),
 		})
 	}
 
@@ -31,13 +33,79 @@ func ToTimedWords(words []*speechpb.WordInfo) []recognizers.TimedWord {
 
 // FromRecognizeResponse ...
 func FromRecognizeResponse(resp *speechpb.RecognizeResponse) recognizers.Response {
+	return FromResults(resp.GetResults())
+}
+
+// FromLongRunningRecognizeResponse ...
+func FromLongRunningRecognizeResponse(resp *speechpb.LongRunningRecognizeResponse) recognizers.Response {
+	return FromResults(resp.GetResults())
+}
+
+// FromResults ...
+// Longer audio is returned as several consecutive results, the most likely alternative of each is used
+func FromResults(results []*speechpb.SpeechRecognitionResult) recognizers.Response {
+	words := make([]recognizers.TimedWord, 0)
+
+	// With diarization the last result repeats every word of the audio, tagged with its speaker
+	diarized := lastDiarized(results)
+	if diarized != nil {
+		words = ToTimedWords(diarized)
+		results = results[:len(results)-1]
+	}
+
+	var confidence float32
+	var count int
+
+	// The language of most of the words, results may differ when the language changes mid-audio
+	languages := make(map[string]int)
+	language := ""
+
+	for _, result := range results {
+		// No speech was detected in this part of the audio
+		if len(result.GetAlternatives()) == 0 {
+			continue
+		}
+
+		alternative := result.Alternatives[0]
+
+		if detected := fields.ResultLanguage(result); detected != "" {
+			languages[detected] += len(alternative.GetWords())
+			if language == "" || languages[detected] > languages[language] {
+				language = detected
+			}
+		}
 
-	confidence := resp.Results[0].Alternatives[0].Confidence
-	words := ToTimedWords(resp.Results[0].Alternatives[0].GetWords())
+		if diarized == nil {
+			words = append(words, ToTimedWords(alternative.GetWords())...)
+		}
+
+		confidence += alternative.Confidence
+		count++
+	}
+
+	if count > 0 {
+		confidence /= float32(count)
+	}
 
 	return recognizers.Response{
 		Confidence: confidence,
 		Words:      words,
+		Language:   language,
+	}
+}
+
+// lastDiarized returns the words of the last result when they carry speaker tags
+func lastDiarized(results []*speechpb.SpeechRecognitionResult) []*speechpb.WordInfo {
+	if len(results) == 0 || len(results[len(results)-1].GetAlternatives()) == 0 {
+		return nil
+	}
+
+	words := results[len(results)-1].Alternatives[0].GetWords()
+	for _, word := range words {
+		if fields.SpeakerTag(word) != 0 {
+			return words
+		}
 	}
 
+	return nil
 }
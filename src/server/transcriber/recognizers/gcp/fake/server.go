@@ -0,0 +1,216 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResultsFunc ...
+// Produces the recognition results for the submitted audio
+type ResultsFunc func(config *speechpb.RecognitionConfig, audio []byte) ([]*speechpb.SpeechRecognitionResult, error)
+
+// Server ...
+// A local stand-in for the Speech-to-Text and long-running operations APIs, point the gcp adapter at Addr with `insecure` set
+type Server struct {
+	Addr string
+
+	// Number of polls an operation stays pending for before completing
+	PollsUntilDone int
+
+	results  ResultsFunc
+	listener net.Listener
+	grpc     *grpc.Server
+
+	mu         sync.Mutex
+	operations map[string]*operation
+	nextID     int
+}
+
+type operation struct {
+	polls  int
+	result []*speechpb.SpeechRecognitionResult
+	err    error
+	proto  *longrunningpb.Operation
+}
+
+// NewServer ...
+// Listens on a random local port
+func NewServer(results ResultsFunc) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Addr:           listener.Addr().String(),
+		PollsUntilDone: 1,
+		results:        results,
+		listener:       listener,
+		grpc:           grpc.NewServer(),
+		operations:     make(map[string]*operation),
+	}
+
+	speechpb.RegisterSpeechServer(s.grpc, &speechServer{s})
+	longrunningpb.RegisterOperationsServer(s.grpc, &operationsServer{s})
+
+	go s.grpc.Serve(listener)
+	return s, nil
+}
+
+// Close ...
+func (s *Server) Close() {
+	s.grpc.Stop()
+}
+
+// Operation ...
+// Whether the named operation exists and was cancelled, for assertions
+func (s *Server) Operation(name string) (exists bool, cancelled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.operations[name]
+	if !ok {
+		return false, false
+	}
+
+	return true, op.proto.GetError().GetCode() == int32(codes.Canceled)
+}
+
+// snapshot copies the operation, as it's modified after being returned
+func (op *operation) snapshot() *longrunningpb.Operation {
+	return proto.Clone(op.proto).(*longrunningpb.Operation)
+}
+
+type speechServer struct {
+	*Server
+}
+
+// Recognize ...
+func (s *speechServer) Recognize(ctx context.Context, req *speechpb.RecognizeRequest) (*speechpb.RecognizeResponse, error) {
+	results, err := s.results(req.GetConfig(), req.GetAudio().GetContent())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &speechpb.RecognizeResponse{Results: results}, nil
+}
+
+// LongRunningRecognize ...
+func (s *speechServer) LongRunningRecognize(ctx context.Context, req *speechpb.LongRunningRecognizeRequest) (*longrunningpb.Operation, error) {
+	results, err := s.results(req.GetConfig(), req.GetAudio().GetContent())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	op := &operation{
+		result: results,
+		err:    err,
+		proto:  &longrunningpb.Operation{Name: fmt.Sprintf("%d", s.nextID)},
+	}
+	s.operations[op.proto.Name] = op
+
+	return op.snapshot(), nil
+}
+
+// StreamingRecognize ...
+func (s *speechServer) StreamingRecognize(speechpb.Speech_StreamingRecognizeServer) error {
+	return status.Error(codes.Unimplemented, "streaming recognition is not supported by the fake server")
+}
+
+type operationsServer struct {
+	*Server
+}
+
+// GetOperation ...
+// Completes the operation once it has been polled PollsUntilDone times
+func (s *operationsServer) GetOperation(ctx context.Context, req *longrunningpb.GetOperationRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.operations[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "operation %s not found", req.GetName())
+	}
+
+	op.polls++
+	if op.proto.Done || op.polls < s.PollsUntilDone {
+		return op.snapshot(), nil
+	}
+
+	op.proto.Done = true
+
+	if op.err != nil {
+		op.proto.Result = &longrunningpb.Operation_Error{
+			Error: &statuspb.Status{Code: int32(codes.Internal), Message: op.err.Error()},
+		}
+		return op.snapshot(), nil
+	}
+
+	response, err := ptypes.MarshalAny(&speechpb.LongRunningRecognizeResponse{Results: op.result})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	op.proto.Result = &longrunningpb.Operation_Response{Response: response}
+	return op.snapshot(), nil
+}
+
+// WaitOperation ...
+func (s *operationsServer) WaitOperation(ctx context.Context, req *longrunningpb.WaitOperationRequest) (*longrunningpb.Operation, error) {
+	return nil, status.Error(codes.Unimplemented, "waiting is not supported by the fake server, poll instead")
+}
+
+// CancelOperation ...
+func (s *operationsServer) CancelOperation(ctx context.Context, req *longrunningpb.CancelOperationRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.operations[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "operation %s not found", req.GetName())
+	}
+
+	if !op.proto.Done {
+		op.proto.Done = true
+		op.proto.Result = &longrunningpb.Operation_Error{
+			Error: &statuspb.Status{Code: int32(codes.Canceled), Message: "cancelled"},
+		}
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// ListOperations ...
+func (s *operationsServer) ListOperations(ctx context.Context, req *longrunningpb.ListOperationsRequest) (*longrunningpb.ListOperationsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &longrunningpb.ListOperationsResponse{}
+	for _, op := range s.operations {
+		resp.Operations = append(resp.Operations, op.snapshot())
+	}
+
+	return resp, nil
+}
+
+// DeleteOperation ...
+func (s *operationsServer) DeleteOperation(ctx context.Context, req *longrunningpb.DeleteOperationRequest) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.operations, req.GetName())
+	return &empty.Empty{}, nil
+}
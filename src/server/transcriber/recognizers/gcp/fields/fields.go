@@ -0,0 +1,169 @@
+// Package fields reads and writes Speech-to-Text v1 API fields that are newer than the vendored client.
+// They travel as unknown fields, which the protobuf runtime keeps on each message and marshals as they are.
+package fields
+
+import (
+	"github.com/golang/protobuf/proto"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+// Field numbers from google/cloud/speech/v1/cloud_speech.proto
+const (
+	recognitionConfigAlternatives = 18 // RecognitionConfig.alternative_language_codes
+	recognitionConfigDiarization  = 19 // RecognitionConfig.diarization_config
+	resultLanguageCode            = 5  // SpeechRecognitionResult.language_code
+	diarizationEnable             = 1  // SpeakerDiarizationConfig.enable_speaker_diarization
+	diarizationMinSpeakers        = 2  // SpeakerDiarizationConfig.min_speaker_count
+	diarizationMaxSpeakers        = 3  // SpeakerDiarizationConfig.max_speaker_count
+	wordInfoSpeakerTag            = 5  // WordInfo.speaker_tag
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// SetDiarization ...
+// Requests speaker diarization, speaker counts of zero are left to the API
+func SetDiarization(config *speechpb.RecognitionConfig, minSpeakers int32, maxSpeakers int32) {
+	diarization := appendVarint(nil, diarizationEnable, 1)
+	if minSpeakers > 0 {
+		diarization = appendVarint(diarization, diarizationMinSpeakers, uint64(minSpeakers))
+	}
+
+	if maxSpeakers > 0 {
+		diarization = appendVarint(diarization, diarizationMaxSpeakers, uint64(maxSpeakers))
+	}
+
+	config.XXX_unrecognized = appendBytes(config.XXX_unrecognized, recognitionConfigDiarization, diarization)
+}
+
+// Diarization ...
+// Whether the config requests speaker diarization, and with which speaker counts
+func Diarization(config *speechpb.RecognitionConfig) (bool, int32, int32) {
+	raw, ok := last(config.XXX_unrecognized, recognitionConfigDiarization, wireBytes)
+	if !ok {
+		return false, 0, 0
+	}
+
+	enabled, _ := last(raw, diarizationEnable, wireVarint)
+	min, _ := last(raw, diarizationMinSpeakers, wireVarint)
+	max, _ := last(raw, diarizationMaxSpeakers, wireVarint)
+
+	return varint(enabled) == 1, int32(varint(min)), int32(varint(max))
+}
+
+// SetAlternativeLanguages ...
+// Lets the API pick the spoken language among the config's language and these, per result
+func SetAlternativeLanguages(config *speechpb.RecognitionConfig, languages []string) {
+	for _, language := range languages {
+		config.XXX_unrecognized = appendBytes(config.XXX_unrecognized, recognitionConfigAlternatives, []byte(language))
+	}
+}
+
+// AlternativeLanguages ...
+func AlternativeLanguages(config *speechpb.RecognitionConfig) []string {
+	result := make([]string, 0)
+	for _, raw := range all(config.XXX_unrecognized, recognitionConfigAlternatives, wireBytes) {
+		result = append(result, string(raw))
+	}
+
+	return result
+}
+
+// SetResultLanguage ...
+func SetResultLanguage(result *speechpb.SpeechRecognitionResult, language string) {
+	result.XXX_unrecognized = appendBytes(result.XXX_unrecognized, resultLanguageCode, []byte(language))
+}
+
+// ResultLanguage ...
+// The language detected in the result, empty when the API didn't report one
+func ResultLanguage(result *speechpb.SpeechRecognitionResult) string {
+	raw, _ := last(result.XXX_unrecognized, resultLanguageCode, wireBytes)
+	return string(raw)
+}
+
+// SetSpeakerTag ...
+func SetSpeakerTag(word *speechpb.WordInfo, tag int32) {
+	word.XXX_unrecognized = appendVarint(word.XXX_unrecognized, wordInfoSpeakerTag, uint64(tag))
+}
+
+// SpeakerTag ...
+// The speaker of a word when diarization was requested, 0 otherwise
+func SpeakerTag(word *speechpb.WordInfo) int32 {
+	raw, _ := last(word.XXX_unrecognized, wordInfoSpeakerTag, wireVarint)
+	return int32(varint(raw))
+}
+
+func appendVarint(raw []byte, field int, value uint64) []byte {
+	b := proto.NewBuffer(raw)
+	b.EncodeVarint(uint64(field)<<3 | wireVarint)
+	b.EncodeVarint(value)
+	return b.Bytes()
+}
+
+func appendBytes(raw []byte, field int, value []byte) []byte {
+	b := proto.NewBuffer(raw)
+	b.EncodeVarint(uint64(field)<<3 | wireBytes)
+	b.EncodeRawBytes(value)
+	return b.Bytes()
+}
+
+// all returns the encoded values of every occurrence of the field, varints are returned still encoded
+func all(raw []byte, field int, wire int) [][]byte {
+	result := make([][]byte, 0)
+	b := proto.NewBuffer(raw)
+
+	for {
+		key, err := b.DecodeVarint()
+		if err != nil {
+			return result
+		}
+
+		var value []byte
+		switch int(key & 7) {
+		case wireVarint:
+			v, e := b.DecodeVarint()
+			value, err = proto.EncodeVarint(v), e
+		case wireFixed64:
+			_, err = b.DecodeFixed64()
+		case wireBytes:
+			value, err = b.DecodeRawBytes(true)
+		case wireFixed32:
+			_, err = b.DecodeFixed32()
+		default:
+			// Groups are long deprecated, nothing after them can be read reliably
+			return result
+		}
+
+		if err != nil {
+			return result
+		}
+
+		if int(key>>3) == field && int(key&7) == wire {
+			result = append(result, value)
+		}
+	}
+}
+
+// last returns the last occurrence of the field, which wins for singular fields
+func last(raw []byte, field int, wire int) ([]byte, bool) {
+	values := all(raw, field, wire)
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	return values[len(values)-1], true
+}
+
+func varint(raw []byte) uint64 {
+	if raw == nil {
+		return 0
+	}
+
+	v, _ := proto.DecodeVarint(raw)
+	return v
+}
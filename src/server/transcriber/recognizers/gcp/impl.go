@@ -3,48 +3,106 @@ package gcp
 import (
 	"context"
 	"errors"
-	"fmt"
+	"server/logging"
 	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/gcp/fields"
+	"sync"
 
 	speech "cloud.google.com/go/speech/apiv1"
+	"go.opencensus.io/plugin/ocgrpc"
+	"google.golang.org/api/option"
 	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+	"google.golang.org/grpc"
 )
 
 // Adapter ...
 type Adapter struct {
+	Config recognizers.Config
+
+	mu     sync.Mutex
+	client *speech.Client
 }
 
 // Init ...
 func (a *Adapter) Init() {
+	_, err := a.getClient()
+	if err != nil {
+		// Not fatal, creating the client will be retried on the next input
+		logging.New("recognizers", "provider", "gcp").Warn("Could not create speech client", "err", err)
+	}
+}
+
+func (a *Adapter) getClient() (*speech.Client, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client != nil {
+		return a.client, nil
+	}
+
+	// Recognition RPCs are traced as children of the segment's span, and the trace context is sent along with them
+	opts := []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithStatsHandler(&ocgrpc.ClientHandler{})),
+	}
+
+	if a.Config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(a.Config.Endpoint))
+	}
+
+	if a.Config.Insecure {
+		opts = append(opts, option.WithoutAuthentication(), option.WithGRPCDialOption(grpc.WithInsecure()))
+	}
+
+	client, err := speech.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
 
+	a.client = client
+	return client, nil
 }
 
 // Input ...
-func (a *Adapter) Input(audio []byte) (recognizers.Response, error) {
-	ctx := context.Background()
-
-	client, err := speech.NewClient(ctx)
+func (a *Adapter) Input(ctx context.Context, audio []byte) (recognizers.Response, error) {
+	client, err := a.getClient()
 	if err != nil {
-		fmt.Println("[Input] Could not create speech client: ", err)
+		logging.FromContext(ctx, "recognizers").Error("Could not create speech client", "provider", "gcp", "err", err)
 		return recognizers.Response{}, errors.New("100/Error preparing")
 	}
 
 	// Detects speech in the audio file.
 	resp, err := client.Recognize(ctx, &speechpb.RecognizeRequest{
-		// TODO parameterize
-		Config: &speechpb.RecognitionConfig{
-			Encoding:                   speechpb.RecognitionConfig_OGG_OPUS,
-			SampleRateHertz:            16000,
-			LanguageCode:               "en-US",
-			Model:                      "video",
-			UseEnhanced:                true,
-			EnableWordTimeOffsets:      true,
-			EnableAutomaticPunctuation: true, // This flag only works on English content
-		},
+		Config: a.recognitionConfig(),
 		Audio: &speechpb.RecognitionAudio{
 			AudioSource: &speechpb.RecognitionAudio_Content{Content: audio},
 		},
 	})
+	if err != nil {
+		logging.FromContext(ctx, "recognizers").Error("Recognize request failed", "provider", "gcp", "err", err)
+		return recognizers.Response{}, err
+	}
 
 	return FromRecognizeResponse(resp), nil
 }
+
+func (a *Adapter) recognitionConfig() *speechpb.RecognitionConfig {
+	config := &speechpb.RecognitionConfig{
+		Encoding:                   speechpb.RecognitionConfig_OGG_OPUS,
+		SampleRateHertz:            16000,
+		LanguageCode:               a.Config.LanguageCode,
+		Model:                      a.Config.Model,
+		UseEnhanced:                a.Config.UseEnhanced,
+		EnableWordTimeOffsets:      true,
+		EnableAutomaticPunctuation: a.Config.Punctuation,
+	}
+
+	if len(a.Config.AlternativeLanguages) > 0 {
+		fields.SetAlternativeLanguages(config, a.Config.AlternativeLanguages)
+	}
+
+	if a.Config.Diarization {
+		fields.SetDiarization(config, int32(a.Config.MinSpeakers), int32(a.Config.MaxSpeakers))
+	}
+
+	return config
+}
@@ -0,0 +1,58 @@
+package gcp
+
+import (
+	"context"
+	"server/transcriber/recognizers"
+
+	speech "cloud.google.com/go/speech/apiv1"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+)
+
+// operation ...
+type operation struct {
+	client *speech.Client
+	op     *speech.LongRunningRecognizeOperation
+}
+
+// StartLongRunning ...
+// Audio is sent inline, no cloud storage bucket is needed for up to 10MB of audio (~20 minutes at 64k)
+func (a *Adapter) StartLongRunning(ctx context.Context, audio []byte) (recognizers.Operation, error) {
+	client, err := a.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := client.LongRunningRecognize(ctx, &speechpb.LongRunningRecognizeRequest{
+		Config: a.recognitionConfig(),
+		Audio: &speechpb.RecognitionAudio{
+			AudioSource: &speechpb.RecognitionAudio_Content{Content: audio},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &operation{client: client, op: op}, nil
+}
+
+// Name ...
+func (o *operation) Name() string {
+	return o.op.Name()
+}
+
+// Poll ...
+func (o *operation) Poll(ctx context.Context) (*recognizers.Response, error) {
+	resp, err := o.op.Poll(ctx)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	result := FromLongRunningRecognizeResponse(resp)
+	return &result, nil
+}
+
+// Cancel ...
+func (o *operation) Cancel(ctx context.Context) error {
+	return o.client.LROClient.CancelOperation(ctx, &longrunningpb.CancelOperationRequest{Name: o.op.Name()})
+}
@@ -0,0 +1,120 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/gcp/fake"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/duration"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+// transcribe recognizes every audio as the same two words
+func transcribe(config *speechpb.RecognitionConfig, audio []byte) ([]*speechpb.SpeechRecognitionResult, error) {
+	return []*speechpb.SpeechRecognitionResult{{
+		Alternatives: []*speechpb.SpeechRecognitionAlternative{{
+			Transcript: "hello world",
+			Confidence: 0.8,
+			Words: []*speechpb.WordInfo{
+				{Word: "hello", StartTime: &duration.Duration{}, EndTime: &duration.Duration{Nanos: 500000000}},
+				{Word: "world", StartTime: &duration.Duration{Nanos: 500000000}, EndTime: &duration.Duration{Seconds: 1}},
+			},
+		}},
+	}}, nil
+}
+
+func fail(config *speechpb.RecognitionConfig, audio []byte) ([]*speechpb.SpeechRecognitionResult, error) {
+	return nil, errors.New("audio could not be decoded")
+}
+
+func newAdapter(t *testing.T, results fake.ResultsFunc, pollsUntilDone int) (*Adapter, *fake.Server) {
+	server, err := fake.NewServer(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.PollsUntilDone = pollsUntilDone
+	t.Cleanup(server.Close)
+
+	return &Adapter{Config: recognizers.Config{LanguageCode: "en-US", Endpoint: server.Addr, Insecure: true}}, server
+}
+
+func TestAwait(t *testing.T) {
+	tests := []struct {
+		name           string
+		results        fake.ResultsFunc
+		pollsUntilDone int
+		words          string
+		err            string
+	}{
+		{name: "done on the first poll", results: transcribe, pollsUntilDone: 1, words: "hello world"},
+		{name: "done after several polls", results: transcribe, pollsUntilDone: 4, words: "hello world"},
+		{name: "failed", results: fail, pollsUntilDone: 2, err: "audio could not be decoded"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			adapter, _ := newAdapter(t, test.results, test.pollsUntilDone)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			op, err := adapter.StartLongRunning(ctx, []byte("audio"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := recognizers.Await(ctx, op, time.Millisecond)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("err = %v, want %q", err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			words := make([]string, 0)
+			for _, word := range resp.Words {
+				words = append(words, word.Word)
+			}
+
+			if strings.Join(words, " ") != test.words {
+				t.Errorf("words = %q, want %q", strings.Join(words, " "), test.words)
+			}
+
+			if resp.Words[1].End.Seconds != 1 {
+				t.Errorf("last word ends at %v, want 1s", resp.Words[1].End)
+			}
+		})
+	}
+}
+
+func TestAwaitCancelled(t *testing.T) {
+	adapter, server := newAdapter(t, transcribe, 1000)
+
+	op, err := adapter.StartLongRunning(context.Background(), []byte("audio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = recognizers.Await(ctx, op, 5*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	exists, cancelled := server.Operation(op.Name())
+	if !exists || !cancelled {
+		t.Errorf("operation %s exists = %v, cancelled = %v, want it cancelled", op.Name(), exists, cancelled)
+	}
+}
@@ -0,0 +1,57 @@
+package recognizers
+
+import (
+	"context"
+	"fmt"
+	"server/logging"
+	"time"
+)
+
+// Operation ...
+// A long-running batch recognition job
+type Operation interface {
+	Name() string
+	Poll(ctx context.Context) (*Response, error) // The response is nil until the job is done
+	Cancel(ctx context.Context) error
+}
+
+// LongRunningAdapter ...
+// Implemented by providers that accept long audio as a batch job instead of a single synchronous request
+type LongRunningAdapter interface {
+	Adapter
+	StartLongRunning(ctx context.Context, audio []byte) (Operation, error)
+}
+
+// Await ...
+// Polls the operation until it's done, cancelling the job when the context is done first
+func Await(ctx context.Context, op Operation, interval time.Duration) (Response, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := op.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			return Response{}, fmt.Errorf("operation %s failed: %v", op.Name(), err)
+		}
+
+		if resp != nil {
+			return *resp, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			// The context is done already, giving the cancellation a moment of its own
+			cancelCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			cancelErr := op.Cancel(cancelCtx)
+			if cancelErr != nil {
+				logging.FromContext(ctx, "recognizers").Warn("Could not cancel operation", "operation", op.Name(), "err", cancelErr)
+			}
+
+			return Response{}, ctx.Err()
+
+		case <-ticker.C:
+		}
+	}
+}
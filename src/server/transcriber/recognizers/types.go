@@ -1,5 +1,12 @@
 package recognizers
 
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+)
+
 // PreciseTime ...
 type PreciseTime struct {
 	Seconds int64 `json:"seconds"`
@@ -8,19 +15,58 @@ type PreciseTime struct {
 
 // TimedWord ...
 type TimedWord struct {
-	Start PreciseTime `json:"start"`
-	End   PreciseTime `json:"end"`
-	Word  string      `json:"word"`
+	Start   PreciseTime `json:"start"`
+	End     PreciseTime `json:"end"`
+	Word    string      `json:"word"`
+	Speaker int         `json:"speaker,omitempty"` // 1 and up when diarization is enabled, 0 when unknown
 }
 
 // Response ...
 type Response struct {
 	Words      []TimedWord `json:"words"`
 	Confidence float32     `json:"confidence"`
+	Language   string      `json:"language,omitempty"` // Detected spoken language, as reported by the provider
+	Provider   string      `json:"provider,omitempty"` // Recognizer that produced the words, tagged by failover chains and ensembles
+	// Share of aligned words the recognizers of an ensemble didn't agree on, only set when several answered
+	Disagreement *float64 `json:"disagreement,omitempty"`
+	Cached       bool     `json:"cached,omitempty"` // Served from the recognition cache, the provider wasn't called
 }
 
+// ProviderSeparator ...
+// Joins the recognizers of an ensemble in a response's provider
+const ProviderSeparator = "+"
+
 // Adapter ...
 type Adapter interface {
 	Init()
-	Input(audio []byte) (Response, error)
+	Input(ctx context.Context, audio []byte) (Response, error)
+}
+
+// Normalize ...
+// Reduces a word to what's compared when matching words, lowercased and without surrounding punctuation
+func Normalize(word string) string {
+	return strings.TrimFunc(strings.ToLower(word), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Duration ...
+func (p PreciseTime) Duration() time.Duration {
+	return time.Duration(p.Seconds)*time.Second + time.Duration(p.Nanos)
+}
+
+// FromDuration ...
+func FromDuration(d time.Duration) PreciseTime {
+	return PreciseTime{
+		Seconds: int64(d / time.Second),
+		Nanos:   int32(d % time.Second),
+	}
+}
+
+// Offset ...
+// Shifts the word by the given duration, e.g. from segment relative to absolute media time
+func (w TimedWord) Offset(d time.Duration) TimedWord {
+	w.Start = FromDuration(w.Start.Duration() + d)
+	w.End = FromDuration(w.End.Duration() + d)
+	return w
 }
@@ -0,0 +1,113 @@
+package slo
+
+import (
+	"fmt"
+	"server/transcriber/recognizers"
+	"time"
+)
+
+// Degradation modes, each one is added to the ones before it.
+// There's no mode reducing overlap, live segments are transcribed whole without sharing audio with their neighbours.
+const (
+	Concurrency = "concurrency" // Transcribe several segments at once
+	FastModel   = "fast-model"  // Switch to a faster, unenhanced model without ensemble
+	Skip        = "skip"        // Publish empty captions for segments too far behind, jumping to the live edge
+)
+
+var modes = map[string]bool{Concurrency: true, FastModel: true, Skip: true}
+
+// Config ...
+// A segment's lag is how long ago it was written, which is when it was at the live edge of the playlist.
+// While segments lag behind the target, the modes are applied one after the other, and reverted once caught up.
+type Config struct {
+	Enabled     bool     `json:"enabled"`
+	Target      float64  `json:"target"`      // Seconds behind the live edge captions should stay within
+	Recover     float64  `json:"recover"`     // Seconds behind under which the latest mode is reverted
+	Hold        float64  `json:"hold"`        // Seconds between two transitions, so each mode gets a chance to work
+	Modes       []string `json:"modes"`       // In the order they're applied: concurrency, fast-model, skip
+	Concurrency int      `json:"concurrency"` // Segments transcribed at once in the concurrency mode
+	FastModel   string   `json:"fastModel"`   // Recognizer model of the fast-model mode
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Enabled:     false,
+		Target:      30,
+		Recover:     12,
+		Hold:        20,
+		Modes:       []string{Concurrency, FastModel, Skip},
+		Concurrency: 3,
+		FastModel:   "default",
+	}
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Target <= 0 || c.Recover <= 0 || c.Recover >= c.Target {
+		return fmt.Errorf("latency: recover (%vs) must be positive and below the target (%vs)", c.Recover, c.Target)
+	}
+
+	if c.Hold < 0 {
+		return fmt.Errorf("latency: hold must not be negative, got %v", c.Hold)
+	}
+
+	seen := make(map[string]bool)
+	for _, mode := range c.Modes {
+		if !modes[mode] {
+			return fmt.Errorf("latency: unknown mode %q, expected %s, %s or %s", mode, Concurrency, FastModel, Skip)
+		}
+
+		if seen[mode] {
+			return fmt.Errorf("latency: mode %s is listed twice", mode)
+		}
+		seen[mode] = true
+	}
+
+	if seen[Concurrency] && c.Concurrency < 2 {
+		return fmt.Errorf("latency: concurrency must be at least 2, got %d", c.Concurrency)
+	}
+
+	if seen[FastModel] && c.FastModel == "" {
+		return fmt.Errorf("latency: the fast-model mode needs a fastModel")
+	}
+
+	return nil
+}
+
+// Uses ...
+func (c Config) Uses(mode string) bool {
+	if !c.Enabled {
+		return false
+	}
+
+	for _, m := range c.Modes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Recognizer ...
+// The recognizer of the fast-model mode, the channel's own with the fast model, unenhanced and without ensemble
+func (c Config) Recognizer(primary recognizers.Config) (recognizers.Config, bool) {
+	if !c.Uses(FastModel) {
+		return recognizers.Config{}, false
+	}
+
+	fast := primary
+	fast.Model = c.FastModel
+	fast.UseEnhanced = false
+	fast.Ensemble = nil
+	return fast, true
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,89 @@
+package slo
+
+import (
+	"sync"
+	"time"
+)
+
+// Transition ...
+type Transition struct {
+	Mode     string // The mode applied or reverted
+	Degraded bool   // Whether the mode was applied
+	Lag      float64
+}
+
+// Controller ...
+// Escalates through the configured modes while segments lag behind the target, one step per hold period,
+// and reverts them one by one once segments are back under the recover threshold
+type Controller struct {
+	config Config
+
+	mu    sync.Mutex
+	level int // Number of modes applied
+	since time.Time
+}
+
+// New ...
+func New(config Config) *Controller {
+	return &Controller{config: config}
+}
+
+// Observe ...
+// Records a segment's lag, returning the transition it caused if any
+func (c *Controller) Observe(now time.Time, lag float64) (Transition, bool) {
+	if !c.config.Enabled {
+		return Transition{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.since) < seconds(c.config.Hold) {
+		return Transition{}, false
+	}
+
+	switch {
+	case lag > c.config.Target && c.level < len(c.config.Modes):
+		c.level++
+		c.since = now
+		return Transition{Mode: c.config.Modes[c.level-1], Degraded: true, Lag: lag}, true
+
+	case lag < c.config.Recover && c.level > 0:
+		c.level--
+		c.since = now
+		return Transition{Mode: c.config.Modes[c.level], Degraded: false, Lag: lag}, true
+	}
+
+	return Transition{}, false
+}
+
+// Active ...
+func (c *Controller) Active(mode string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, m := range c.config.Modes[:c.level] {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Modes ...
+// The modes currently applied, in the order they were
+func (c *Controller) Modes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.config.Modes[:c.level]...)
+}
+
+// Level ...
+func (c *Controller) Level() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.level
+}
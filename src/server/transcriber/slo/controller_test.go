@@ -0,0 +1,121 @@
+package slo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestObserve(t *testing.T) {
+	enabled := DefaultConfig()
+	enabled.Enabled = true
+
+	type step struct {
+		at    float64 // Seconds since the first observation
+		lag   float64
+		mode  string // Mode of the expected transition, empty for none
+		apply bool
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		steps  []step
+		modes  []string // Applied after the last step
+	}{
+		{
+			name:   "on time",
+			config: enabled,
+			steps:  []step{{at: 0, lag: 8}, {at: 30, lag: 30}, {at: 60, lag: 12}},
+			modes:  []string{},
+		},
+		{
+			name:   "escalates one mode per hold period",
+			config: enabled,
+			steps: []step{
+				{at: 0, lag: 31, mode: Concurrency, apply: true},
+				{at: 10, lag: 45},
+				{at: 20, lag: 50, mode: FastModel, apply: true},
+				{at: 40, lag: 60, mode: Skip, apply: true},
+				{at: 60, lag: 80},
+			},
+			modes: []string{Concurrency, FastModel, Skip},
+		},
+		{
+			name:   "holds between the thresholds",
+			config: enabled,
+			steps: []step{
+				{at: 0, lag: 40, mode: Concurrency, apply: true},
+				{at: 20, lag: 30},
+				{at: 40, lag: 12},
+				{at: 60, lag: 20},
+			},
+			modes: []string{Concurrency},
+		},
+		{
+			name:   "recovers in reverse order",
+			config: enabled,
+			steps: []step{
+				{at: 0, lag: 40, mode: Concurrency, apply: true},
+				{at: 20, lag: 40, mode: FastModel, apply: true},
+				{at: 30, lag: 5},
+				{at: 40, lag: 5, mode: FastModel},
+				{at: 60, lag: 5, mode: Concurrency},
+				{at: 80, lag: 5},
+			},
+			modes: []string{},
+		},
+		{
+			name:   "configured modes only",
+			config: Config{Enabled: true, Target: 10, Recover: 5, Hold: 0, Modes: []string{Skip}},
+			steps: []step{
+				{at: 0, lag: 11, mode: Skip, apply: true},
+				{at: 1, lag: 20},
+			},
+			modes: []string{Skip},
+		},
+		{
+			name:   "disabled",
+			config: Config{Enabled: false, Target: 10, Recover: 5, Modes: []string{Skip}},
+			steps:  []step{{at: 0, lag: 60}, {at: 30, lag: 60}},
+			modes:  []string{},
+		},
+	}
+
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			c := New(test.config)
+			for _, step := range test.steps {
+				transition, ok := c.Observe(start.Add(seconds(step.at)), step.lag)
+				if ok != (step.mode != "") || transition.Mode != step.mode || transition.Degraded != step.apply {
+					t.Fatalf("at %vs with a %vs lag: transition %+v, %v, want %s applied %v", step.at, step.lag, transition, ok, step.mode, step.apply)
+				}
+			}
+
+			if got := c.Modes(); !reflect.DeepEqual(got, test.modes) {
+				t.Errorf("modes = %v, want %v", got, test.modes)
+			}
+
+			if c.Level() != len(test.modes) {
+				t.Errorf("level = %d, want %d", c.Level(), len(test.modes))
+			}
+
+			for _, mode := range test.config.Modes {
+				applied := false
+				for _, m := range test.modes {
+					applied = applied || m == mode
+				}
+
+				if c.Active(mode) != applied {
+					t.Errorf("%s active = %v, want %v", mode, c.Active(mode), applied)
+				}
+			}
+		})
+	}
+}
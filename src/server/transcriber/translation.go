@@ -0,0 +1,18 @@
+package transcriber
+
+import (
+	"fmt"
+	"server/transcriber/translators"
+	"server/transcriber/translators/fake"
+)
+
+// NewTranslator ...
+// Builds the translator adapter for the configured provider
+func NewTranslator(config translators.Config) (translators.Adapter, error) {
+	switch config.Provider {
+	case "fake":
+		return &fake.Translator{Dictionary: config.Dictionary}, nil
+	}
+
+	return nil, fmt.Errorf("unknown translator provider %q", config.Provider)
+}
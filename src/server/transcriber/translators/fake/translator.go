@@ -0,0 +1,76 @@
+package fake
+
+import (
+	"context"
+	"server/captions"
+	"server/transcriber/recognizers"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Translator ...
+// A local stand-in for a translation service, translating word by word from a dictionary.
+// Unknown words are kept as they are, which also keeps glossary placeholders intact.
+type Translator struct {
+	Dictionary map[string]map[string]string // Target language to source word to translation
+	Delay      time.Duration                // Simulated latency per request
+}
+
+// Translate ...
+func (t *Translator) Translate(ctx context.Context, cues []captions.Cue, source string, target string) ([]captions.Cue, error) {
+	if t.Delay > 0 {
+		select {
+		case <-time.After(t.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	dictionary := t.Dictionary[target]
+	if dictionary == nil {
+		dictionary = t.Dictionary[recognizers.BaseLanguage(target)]
+	}
+
+	result := make([]captions.Cue, 0, len(cues))
+	for _, cue := range cues {
+		words := strings.Fields(cue.Text)
+		for i, word := range words {
+			words[i] = translate(dictionary, word)
+		}
+
+		cue.Text = strings.Join(words, " ")
+		result = append(result, cue)
+	}
+
+	return result, nil
+}
+
+// translate looks the word up without its case and punctuation, which are put back around the translation
+func translate(dictionary map[string]string, word string) string {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' }
+
+	start := strings.IndexFunc(word, isWord)
+	end := strings.LastIndexFunc(word, isWord)
+	if start < 0 {
+		return word
+	}
+
+	_, size := utf8.DecodeRuneInString(word[end:])
+	end += size
+
+	core := word[start:end]
+	translation, ok := dictionary[strings.ToLower(core)]
+	if !ok {
+		return word
+	}
+
+	if first := []rune(core)[0]; unicode.IsUpper(first) && translation != "" {
+		runes := []rune(translation)
+		runes[0] = unicode.ToUpper(runes[0])
+		translation = string(runes)
+	}
+
+	return word[:start] + translation + word[end:]
+}
@@ -0,0 +1,109 @@
+package translators
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"server/captions"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Glossary ...
+// Keeps terms untranslated by swapping them for placeholders the adapter passes through unchanged
+type Glossary struct {
+	pattern *regexp.Regexp
+}
+
+// placeholder ...
+// Digits and underscores only, so translation services leave it alone
+const placeholder = "__%d__"
+
+// NewGlossary ...
+func NewGlossary(terms []string) *Glossary {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if strings.TrimSpace(term) != "" {
+			quoted = append(quoted, regexp.QuoteMeta(strings.TrimSpace(term)))
+		}
+	}
+
+	if len(quoted) == 0 {
+		return &Glossary{}
+	}
+
+	// Longest first, "New York City" wins over "New York"
+	sort.Slice(quoted, func(i, j int) bool {
+		return len(quoted[i]) > len(quoted[j])
+	})
+
+	return &Glossary{
+		pattern: regexp.MustCompile(`(?i)` + strings.Join(quoted, "|")),
+	}
+}
+
+// Translate ...
+// Translates the cues through the adapter, keeping glossary terms as they were spoken
+func (g *Glossary) Translate(ctx context.Context, adapter Adapter, cues []captions.Cue, source string, target string) ([]captions.Cue, error) {
+	if g.pattern == nil {
+		return adapter.Translate(ctx, cues, source, target)
+	}
+
+	protected := make([]captions.Cue, len(cues))
+	terms := make([][]string, len(cues))
+
+	for i, cue := range cues {
+		var text strings.Builder
+		last := 0
+
+		for _, loc := range g.pattern.FindAllStringIndex(cue.Text, -1) {
+			// Only whole words, "Paris" must not match inside "Parisian"
+			if !boundary(cue.Text, loc[0], loc[1]) {
+				continue
+			}
+
+			text.WriteString(cue.Text[last:loc[0]])
+			fmt.Fprintf(&text, placeholder, len(terms[i]))
+			terms[i] = append(terms[i], cue.Text[loc[0]:loc[1]])
+			last = loc[1]
+		}
+
+		text.WriteString(cue.Text[last:])
+		cue.Text = text.String()
+		protected[i] = cue
+	}
+
+	translated, err := adapter.Translate(ctx, protected, source, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(translated) != len(cues) {
+		return nil, fmt.Errorf("translator returned %d cues for %d", len(translated), len(cues))
+	}
+
+	for i := range translated {
+		for j, term := range terms[i] {
+			translated[i].Text = strings.Replace(translated[i].Text, fmt.Sprintf(placeholder, j), term, 1)
+		}
+	}
+
+	return translated, nil
+}
+
+// boundary checks the text around [start, end) isn't part of the same word
+func boundary(text string, start int, end int) bool {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWord(before) {
+		return false
+	}
+
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWord(after) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,85 @@
+package translators
+
+import (
+	"context"
+	"server/captions"
+	"server/transcriber/translators/fake"
+	"strings"
+	"testing"
+)
+
+var dictionary = map[string]map[string]string{
+	"fr": {
+		"paris":     "lutèce",
+		"parisian":  "parisien",
+		"new":       "nouveau",
+		"york":      "yorkais",
+		"city":      "ville",
+		"is":        "est",
+		"big":       "grande",
+		"again":     "encore",
+		"and":       "et",
+		"met":       "ont rencontré",
+		"beautiful": "belle",
+	},
+}
+
+func TestGlossaryTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		glossary []string
+		text     string
+		want     string
+	}{
+		{"no glossary", nil, "Paris is beautiful", "Lutèce est belle"},
+		{"term kept", []string{"Paris"}, "Paris is beautiful", "Paris est belle"},
+		{"kept as spoken", []string{"paris"}, "PARIS is beautiful", "PARIS est belle"},
+		{"whole words only", []string{"Paris"}, "Parisian Paris", "Parisien Paris"},
+		{"next to punctuation", []string{"Paris"}, "Paris, again", "Paris, encore"},
+		{"longest term first", []string{"New York", "New York City"}, "New York City is big", "New York City est grande"},
+		{"shorter term alone", []string{"New York", "New York City"}, "New York is big", "New York est grande"},
+		{
+			name:     "more than ten terms",
+			glossary: []string{"Ann", "Bob", "Cid", "Dan", "Eve", "Fay", "Gus", "Hal", "Ivy", "Jon", "Kim"},
+			text:     "Ann Bob Cid Dan Eve Fay Gus Hal Ivy Jon and Kim met",
+			want:     "Ann Bob Cid Dan Eve Fay Gus Hal Ivy Jon et Kim ont rencontré",
+		},
+		{"blank terms ignored", []string{" ", ""}, "Paris is big", "Lutèce est grande"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cues := []captions.Cue{{Text: test.text}, {Text: "is big"}}
+
+			translated, err := NewGlossary(test.glossary).Translate(context.Background(), &fake.Translator{Dictionary: dictionary},
+				cues, "en", "fr-FR")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(translated) != 2 || translated[0].Text != test.want || translated[1].Text != "est grande" {
+				t.Errorf("translated = %+v, want %q then %q", translated, test.want, "est grande")
+			}
+
+			// The placeholders never leak into the originals
+			if cues[0].Text != test.text {
+				t.Errorf("original cue changed to %q", cues[0].Text)
+			}
+		})
+	}
+}
+
+// dropping loses the last cue
+type dropping struct{}
+
+func (dropping) Translate(ctx context.Context, cues []captions.Cue, source string, target string) ([]captions.Cue, error) {
+	return cues[:len(cues)-1], nil
+}
+
+func TestGlossaryTranslateMissingCues(t *testing.T) {
+	_, err := NewGlossary([]string{"Paris"}).Translate(context.Background(), dropping{},
+		[]captions.Cue{{Text: "Paris"}, {Text: "again"}}, "en", "fr")
+	if err == nil || !strings.Contains(err.Error(), "returned 1 cues for 2") {
+		t.Errorf("err = %v, want the missing cue reported", err)
+	}
+}
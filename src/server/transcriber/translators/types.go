@@ -0,0 +1,27 @@
+package translators
+
+import (
+	"context"
+	"server/captions"
+)
+
+// Adapter ...
+// Translates finished caption cues, the translated cues keep the timing of the originals
+type Adapter interface {
+	Translate(ctx context.Context, cues []captions.Cue, source string, target string) ([]captions.Cue, error)
+}
+
+// Config ...
+type Config struct {
+	Provider  string   `json:"provider"`  // Only the "fake" provider is built in
+	Languages []string `json:"languages"` // Target languages (BCP-47), each published as its own subtitle rendition
+	Glossary  []string `json:"glossary"`  // Terms kept untranslated, e.g. names
+
+	// Word translations by target language, used by the fake provider
+	Dictionary map[string]map[string]string `json:"dictionary,omitempty"`
+}
+
+// Enabled ...
+func (c Config) Enabled() bool {
+	return len(c.Languages) > 0
+}
@@ -1,13 +1,37 @@
 package transcriber
 
 import (
+	"context"
+	"server/costs"
+	"server/logging"
+	"server/store"
+	"server/subtitles"
+	"server/transcriber/filters"
 	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/cache"
+	"server/transcriber/slo"
+	"server/transcriber/vad"
+	"sync"
+	"time"
 )
 
 // SegmentInfo ...
 type SegmentInfo struct {
-	Filename string
-	State    string
+	ID         string // Correlates the segment's log lines, from discovery to publication
+	Filename   string
+	State      string
+	Sequence   int
+	MediaStart float64 // Seconds since the start of the channel's media timeline
+	Duration   float64
+	WallClock  *time.Time
+
+	Language      string        // Detected spoken language, once transcribed
+	Discontinuity bool          // First segment after an encoder restart
+	PeriodStart   float64       // Media start of the segment following the latest encoder (re)start, where its timestamps begin
+	Speech        *vad.Decision // Voice activity, when detection is enabled
+	Paused        bool          // Not recognized, the channel's budget is exhausted
+	Skipped       bool          // Not recognized, too far behind the live edge
+	Written       time.Time     // When the encoder finished writing the segment
 }
 
 // PlaylistInfo ...
@@ -16,13 +40,58 @@ type PlaylistInfo struct {
 	Segments map[string]SegmentInfo
 }
 
-// State ...
-type State struct {
+// Config ...
+type Config struct {
+	EncoderPath  string
+	OutputPath   string
+	SegmentsPath string
+	Recognizer   recognizers.Config
+	Filter       filters.Config
+	VAD          vad.Config           // Optional, skips recognition of segments without speech
+	Subtitles    *subtitles.Publisher // Optional, publishes live subtitle renditions
+	Channel      string
+	Store        *store.Store // Optional, retains the channel's transcript history
+	Meter        *costs.Meter // Optional, meters the audio sent for recognition
+	Budget       costs.Budget // Enforced by the meter
+	Cache        *cache.Cache // Optional, recognitions of audio already recognized are reused
+	Latency      slo.Config   // Optional, degrades the transcription when captions fall behind the live edge
+}
+
+// Transcriber ...
+type Transcriber struct {
 	encoderPath  string
 	outputPath   string
 	segmentsPath string
+	channel      string
+	log          *logging.Logger
+	store        *store.Store
+	recognition  recognizers.Config // Languages the channel may speak
+	filter       *filters.Filter
+	subtitles    *subtitles.Publisher
+	vad          vad.Config
+	mediaEnd     float64 // End of the newest segment on the media timeline, -1 until the first segment
+	periodStart  float64 // Media start of the current encoder period, -1 until the first segment
 	recognizer   recognizers.Adapter
+	meter        *costs.Meter
+	fallback     recognizers.Adapter // Used once the budget is exhausted, nil to pause instead
+	fallbackWith recognizers.Config
+	latency      slo.Config
+	degradation  *slo.Controller
+	fast         recognizers.Adapter // Used in the fast-model degradation mode, nil without it
+	fastWith     recognizers.Config
 	processing   bool
 	pruning      bool
 	playlistInfo PlaylistInfo
+	listed       []SegmentInfo // The segments of the latest playlist, the live window
+	published    time.Time     // When captions were last published
+	failures     int           // Consecutive segments that failed
+
+	mu        sync.Mutex
+	intervals []chan bool
+	stopping  bool
+
+	// Cancelled when in-flight work should be abandoned
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inFlight sync.WaitGroup
 }
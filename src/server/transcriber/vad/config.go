@@ -0,0 +1,97 @@
+package vad
+
+import (
+	"fmt"
+	"time"
+)
+
+// SampleRate ...
+// Audio is analysed as 16 bit mono PCM at the rate it's sent for recognition
+const SampleRate = 16000
+
+// Config ...
+// Energy and zero-crossing based voice activity detection. Thresholds are relative to the segment's own noise floor,
+// music beds are told apart from speech by their steadier energy.
+type Config struct {
+	Enabled     bool    `json:"enabled"`
+	Frame       int     `json:"frame"`       // Analysis frame, in milliseconds
+	Window      int     `json:"window"`      // Sub-window classified as speech or not, in milliseconds
+	MinEnergy   float64 `json:"minEnergy"`   // dBFS below which a frame is always silence
+	Margin      float64 `json:"margin"`      // dB above the noise floor for a frame to be active
+	MaxZCR      float64 `json:"maxZcr"`      // Zero crossings per sample above which an active frame is noise rather than voice
+	MinActive   float64 `json:"minActive"`   // Share of active frames for a window to hold speech
+	MinVariance float64 `json:"minVariance"` // Standard deviation of active frame energies, in dB, below which a window is steady music or tone
+	Padding     int     `json:"padding"`     // Audio kept around the speech when trimming, in milliseconds
+}
+
+// DefaultConfig ...
+// Any unset value falls back to its default
+func DefaultConfig() Config {
+	return Config{
+		Enabled:     false,
+		Frame:       30,
+		Window:      1000,
+		MinEnergy:   -50,
+		Margin:      10,
+		MaxZCR:      0.35,
+		MinActive:   0.2,
+		MinVariance: 4,
+		Padding:     300,
+	}
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	c = c.withDefaults()
+	if c.Window < c.Frame {
+		return fmt.Errorf("vad: the window (%dms) must hold at least one frame (%dms)", c.Window, c.Frame)
+	}
+
+	return nil
+}
+
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+
+	if c.Frame <= 0 {
+		c.Frame = defaults.Frame
+	}
+
+	if c.Window <= 0 {
+		c.Window = defaults.Window
+	}
+
+	if c.MinEnergy == 0 {
+		c.MinEnergy = defaults.MinEnergy
+	}
+
+	if c.Margin == 0 {
+		c.Margin = defaults.Margin
+	}
+
+	if c.MaxZCR == 0 {
+		c.MaxZCR = defaults.MaxZCR
+	}
+
+	if c.MinActive == 0 {
+		c.MinActive = defaults.MinActive
+	}
+
+	if c.MinVariance == 0 {
+		c.MinVariance = defaults.MinVariance
+	}
+
+	return c
+}
+
+func (c Config) frameSamples() int {
+	return SampleRate * c.Frame / 1000
+}
+
+func (c Config) padding() time.Duration {
+	return time.Duration(c.Padding) * time.Millisecond
+}
@@ -0,0 +1,160 @@
+package vad
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"time"
+)
+
+// Window ...
+type Window struct {
+	Start  float64 `json:"start"` // Seconds from the segment start
+	End    float64 `json:"end"`
+	Speech bool    `json:"speech"`
+}
+
+// Decision ...
+// Whether a segment holds speech, recorded with its transcript. Only the audio before the first speech window and
+// after the last one is trimmed, pauses between them are still sent for recognition.
+type Decision struct {
+	Speech  bool     `json:"speech"`
+	Ratio   float64  `json:"ratio"` // Share of the segment classified as speech
+	Start   float64  `json:"start"` // Audio sent for recognition, in seconds from the segment start, the rest was trimmed
+	End     float64  `json:"end"`
+	Windows []Window `json:"windows"`
+}
+
+// Trimmed ...
+// Whether only part of the segment needs to be recognized
+func (d Decision) Trimmed(duration float64) bool {
+	return d.Speech && (d.Start > 0 || d.End < duration)
+}
+
+// Detect ...
+// Classifies 16 bit little-endian mono PCM at SampleRate, per sub-window
+func Detect(config Config, pcm []byte) Decision {
+	config = config.withDefaults()
+	samples := len(pcm) / 2
+	frameSize := config.frameSamples()
+	frames := samples / frameSize
+
+	duration := float64(samples) / SampleRate
+	if frames == 0 {
+		return Decision{Speech: false, End: duration, Windows: make([]Window, 0)}
+	}
+
+	energies := make([]float64, frames)
+	zcrs := make([]float64, frames)
+
+	for f := 0; f < frames; f++ {
+		var sum float64
+		var crossings int
+		previous := 0.0
+
+		for i := 0; i < frameSize; i++ {
+			offset := (f*frameSize + i) * 2
+			sample := float64(int16(binary.LittleEndian.Uint16(pcm[offset:]))) / 32768
+
+			sum += sample * sample
+			if i > 0 && (sample >= 0) != (previous >= 0) {
+				crossings++
+			}
+
+			previous = sample
+		}
+
+		energies[f] = 10 * math.Log10(sum/float64(frameSize)+1e-10)
+		zcrs[f] = float64(crossings) / float64(frameSize)
+	}
+
+	// The quietest tenth of the segment is taken as its noise floor
+	sorted := append([]float64{}, energies...)
+	sort.Float64s(sorted)
+	threshold := math.Max(config.MinEnergy, sorted[len(sorted)/10]+config.Margin)
+
+	framesPerWindow := config.Window / config.Frame
+	frameDuration := float64(config.Frame) / 1000
+
+	decision := Decision{Windows: make([]Window, 0)}
+	speechFrames := 0
+	first, last := -1, -1
+
+	for start := 0; start < frames; start += framesPerWindow {
+		end := start + framesPerWindow
+		if end > frames {
+			end = frames
+		}
+
+		active := make([]float64, 0, end-start)
+		for f := start; f < end; f++ {
+			if energies[f] > threshold && zcrs[f] < config.MaxZCR {
+				active = append(active, energies[f])
+			}
+		}
+
+		speech := float64(len(active))/float64(end-start) >= config.MinActive && deviation(active) >= config.MinVariance
+		if speech {
+			speechFrames += end - start
+			if first < 0 {
+				first = start
+			}
+
+			last = end
+		}
+
+		decision.Windows = append(decision.Windows, Window{
+			Start:  float64(start) * frameDuration,
+			End:    float64(end) * frameDuration,
+			Speech: speech,
+		})
+	}
+
+	decision.Speech = first >= 0
+	decision.Ratio = float64(speechFrames) / float64(frames)
+	decision.End = duration
+
+	if decision.Speech {
+		padding := config.padding().Seconds()
+		decision.Start = math.Max(0, float64(first)*frameDuration-padding)
+		decision.End = math.Min(duration, float64(last)*frameDuration+padding)
+	}
+
+	return decision
+}
+
+// Trim ...
+// The part of the PCM the decision keeps, and where it starts
+func Trim(decision Decision, pcm []byte) ([]byte, time.Duration) {
+	start := int(decision.Start*SampleRate) * 2
+	end := int(decision.End*SampleRate) * 2
+
+	if start < 0 {
+		start = 0
+	}
+
+	if end > len(pcm) || end <= start {
+		end = len(pcm) - len(pcm)%2
+	}
+
+	return pcm[start:end], time.Duration(decision.Start * float64(time.Second))
+}
+
+func deviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	return math.Sqrt(variance / float64(len(values)))
+}
@@ -0,0 +1,162 @@
+package vad
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// part of a synthetic segment, at most one of tone and burst is set
+type part struct {
+	seconds float64
+	tone    float64 // Frequency of a steady sine, in Hz
+	bursts  bool    // Syllable-like bursts of a 200 Hz carrier, four per second
+}
+
+// synthesize renders the parts over a low-level noise bed unless silent, as 16 bit little-endian PCM at SampleRate
+func synthesize(silent bool, parts ...part) []byte {
+	random := rand.New(rand.NewSource(1))
+
+	var samples []float64
+	for _, p := range parts {
+		n := int(p.seconds * SampleRate)
+		for i := 0; i < n; i++ {
+			t := float64(i) / SampleRate
+
+			var sample float64
+			if !silent {
+				sample = (random.Float64()*2 - 1) * 0.005
+			}
+
+			switch {
+			case p.tone > 0:
+				sample += 0.5 * math.Sin(2*math.Pi*p.tone*t)
+			case p.bursts:
+				envelope := 0.5 * (1 - math.Cos(2*math.Pi*4*t))
+				sample += 0.5 * envelope * math.Sin(2*math.Pi*200*t)
+			}
+
+			samples = append(samples, sample)
+		}
+	}
+
+	pcm := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(sample*32767)))
+	}
+
+	return pcm
+}
+
+func TestDetect(t *testing.T) {
+	config := DefaultConfig()
+	config.Enabled = true
+
+	// Windows are 33 frames of 30ms, 0.99s long
+	tests := []struct {
+		name    string
+		pcm     []byte
+		speech  bool
+		windows []bool
+		start   float64
+		end     float64
+	}{
+		{
+			name:    "digital silence",
+			pcm:     synthesize(true, part{seconds: 3}),
+			windows: []bool{false, false, false, false},
+			end:     3,
+		},
+		{
+			name:    "low-level noise",
+			pcm:     synthesize(false, part{seconds: 3}),
+			windows: []bool{false, false, false, false},
+			end:     3,
+		},
+		{
+			name:    "steady tone",
+			pcm:     synthesize(false, part{seconds: 1}, part{seconds: 2, tone: 440}, part{seconds: 1}),
+			windows: []bool{false, false, false, false, false},
+			end:     4,
+		},
+		{
+			name:    "speech-like bursts",
+			pcm:     synthesize(false, part{seconds: 1}, part{seconds: 2, bursts: true}, part{seconds: 1}),
+			speech:  true,
+			windows: []bool{false, true, true, false, false},
+			start:   0.99 - 0.3,
+			end:     2.97 + 0.3,
+		},
+		{
+			name: "pause between bursts",
+			pcm: synthesize(false, part{seconds: 1}, part{seconds: 1, bursts: true}, part{seconds: 1},
+				part{seconds: 1, bursts: true}, part{seconds: 1}),
+			speech:  true,
+			windows: []bool{false, true, false, true, false, false},
+			start:   0.99 - 0.3,
+			end:     3.96 + 0.3,
+		},
+		{
+			name:    "shorter than a frame",
+			pcm:     synthesize(false, part{seconds: 0.01, bursts: true}),
+			windows: []bool{},
+			end:     0.01,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := Detect(config, test.pcm)
+
+			if decision.Speech != test.speech {
+				t.Errorf("speech = %v, want %v", decision.Speech, test.speech)
+			}
+
+			windows := make([]bool, len(decision.Windows))
+			for i, window := range decision.Windows {
+				windows[i] = window.Speech
+			}
+
+			if len(windows) != len(test.windows) {
+				t.Fatalf("windows = %v, want %v", windows, test.windows)
+			}
+
+			for i := range windows {
+				if windows[i] != test.windows[i] {
+					t.Fatalf("windows = %v, want %v", windows, test.windows)
+				}
+			}
+
+			if math.Abs(decision.Start-test.start) > 1e-6 || math.Abs(decision.End-test.end) > 1e-6 {
+				t.Errorf("kept %.3f-%.3f, want %.3f-%.3f", decision.Start, decision.End, test.start, test.end)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	pcm := synthesize(false, part{seconds: 4})
+
+	tests := []struct {
+		name     string
+		decision Decision
+		length   int
+		offset   time.Duration
+	}{
+		{"whole segment", Decision{End: 4}, len(pcm), 0},
+		{"both edges", Decision{Speech: true, Start: 0.5, End: 3}, 2.5 * SampleRate * 2, 500 * time.Millisecond},
+		{"end past the audio", Decision{Speech: true, Start: 1, End: 5}, 3 * SampleRate * 2, time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trimmed, offset := Trim(test.decision, pcm)
+
+			if len(trimmed) != test.length || offset != test.offset {
+				t.Errorf("got %d bytes from %v, want %d bytes from %v", len(trimmed), offset, test.length, test.offset)
+			}
+		})
+	}
+}
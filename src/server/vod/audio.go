@@ -0,0 +1,94 @@
+package vod
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var durationPattern = regexp.MustCompile(`Duration: (\d+):(\d+):(\d+(?:\.\d+)?)`)
+
+// probeDuration reads the input's duration from ffmpeg's stream information
+func probeDuration(ctx context.Context, encoderPath string, input string) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx, encoderPath, "-hide_banner", "-i", input)
+
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+
+	// ffmpeg exits with an error without an output file, the stream information is all that's needed
+	_ = cmd.Run()
+
+	match := durationPattern.FindStringSubmatch(errb.String())
+	if match == nil {
+		return 0, fmt.Errorf("could not determine the duration of %s, is it a finished asset?", input)
+	}
+
+	hours, _ := strconv.Atoi(match[1])
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.ParseFloat(match[3], 64)
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second)), nil
+}
+
+// extractAudio converts a section of the input's audio to the format the recognizers expect
+func extractAudio(ctx context.Context, encoderPath string, input string, start time.Duration, duration time.Duration) ([]byte, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		encoderPath,
+		"-hide_banner",
+		"-loglevel", "error",
+		"-ss", fmt.Sprintf("%.3f", start.Seconds()),
+		"-i", input,
+		"-t", fmt.Sprintf("%.3f", duration.Seconds()),
+		"-f", "opus",
+		"-fflags", "+bitexact", // Identical audio gives identical bytes, so re-runs hit the recognition cache
+		"-vn",
+		"-acodec", "libopus",
+		"-b:a", "64k",
+		"-ar", "16000",
+		"-ac", "1",
+		"pipe:1",
+	)
+
+	var outb, errb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("could not extract audio at %v: %v %s", start, err, errb.String())
+	}
+
+	return outb.Bytes(), nil
+}
+
+// planChunks splits the duration into overlapping chunks, each keeping the words up to the middle of its overlap
+func planChunks(total time.Duration, chunkDuration time.Duration, overlap time.Duration) []chunk {
+	chunks := make([]chunk, 0)
+
+	for start := time.Duration(0); start < total; start += chunkDuration {
+		duration := chunkDuration + overlap
+		if start+duration > total {
+			duration = total - start
+		}
+
+		chunks = append(chunks, chunk{
+			Index:     len(chunks),
+			Start:     start,
+			Duration:  duration,
+			KeepFrom:  start + overlap/2,
+			KeepUntil: start + chunkDuration + overlap/2,
+		})
+	}
+
+	if len(chunks) > 0 {
+		chunks[0].KeepFrom = 0
+		chunks[len(chunks)-1].KeepUntil = total + time.Second
+	}
+
+	return chunks
+}
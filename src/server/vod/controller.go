@@ -0,0 +1,267 @@
+package vod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/logging"
+	"server/transcriber"
+	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/cache"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chunksDirName holds per-chunk results, which are reused when a job is run again
+const chunksDirName = ".chunks"
+
+// Run ...
+// Transcribes an entire asset and writes the complete caption set to the output directory:
+// transcript.json, captions.srt, captions.vtt and subtitles/playlist.m3u8 with its WebVTT segments
+func Run(ctx context.Context, config Config) (Transcript, error) {
+	transcript := Transcript{Source: config.Input}
+
+	// Every line of the job carries its ID, those of a chunk, recognizers included, carry the chunk index too
+	log := logging.New("vod", "job", logging.NewID(), "input", config.Input)
+	ctx = logging.NewContext(ctx, log)
+
+	recognizer, err := transcriber.NewRecognizer(config.Recognizer, "vod")
+	if err != nil {
+		return transcript, err
+	}
+	recognizer.Init()
+
+	if config.LongRunning {
+		if _, ok := recognizer.(recognizers.LongRunningAdapter); !ok {
+			return transcript, fmt.Errorf("recognizer provider %q does not support long-running recognition", config.Recognizer.Provider)
+		}
+	}
+
+	chunksPath := filepath.Join(config.OutputPath, chunksDirName)
+	err = os.MkdirAll(chunksPath, 0777)
+	if err != nil {
+		return transcript, err
+	}
+
+	duration, err := probeDuration(ctx, config.EncoderPath, config.Input)
+	if err != nil {
+		return transcript, err
+	}
+	transcript.Duration = duration.Seconds()
+
+	chunks := planChunks(duration, config.ChunkDuration, config.Overlap)
+	log.Info("Transcribing", "duration", duration, "chunks", len(chunks), "parallelism", config.Parallelism, "longRunning", config.LongRunning)
+
+	tracker := newProgressTracker(log, config.OutputPath, len(chunks))
+	results := make([]chunkResult, len(chunks))
+	pending := make(chan chunk)
+
+	var wg sync.WaitGroup
+	var failure error
+	var failureMu sync.Mutex
+
+	parallelism := config.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for c := range pending {
+				chunkLog := log.With("chunk", c.Index)
+				result, resumed, err := transcribeChunk(logging.NewContext(ctx, chunkLog), config, recognizer, chunksPath, c)
+				if err != nil {
+					chunkLog.Error("Chunk failed", "err", err)
+
+					failureMu.Lock()
+					if failure == nil {
+						failure = err
+					}
+					failureMu.Unlock()
+
+					tracker.failed(err)
+					continue
+				}
+
+				results[c.Index] = result
+				tracker.done(resumed)
+			}
+		}()
+	}
+
+	for _, c := range chunks {
+		select {
+		case pending <- c:
+		case <-ctx.Done():
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	if ctx.Err() != nil && failure == nil {
+		failure = ctx.Err()
+	}
+
+	if failure != nil {
+		tracker.finish(failure)
+		return transcript, fmt.Errorf("transcription incomplete, run again to resume: %v", failure)
+	}
+
+	transcript.Words, transcript.Confidence = merge(chunks, results)
+
+	err = writeOutputs(config, transcript)
+	tracker.finish(err)
+	if err != nil {
+		return transcript, err
+	}
+
+	log.Info("Wrote the complete caption set", "output", config.OutputPath, "words", len(transcript.Words))
+	return transcript, nil
+}
+
+// transcribeChunk returns the persisted result when one exists, otherwise recognizes the chunk with retries
+func transcribeChunk(ctx context.Context, config Config, recognizer recognizers.Adapter, chunksPath string, c chunk) (chunkResult, bool, error) {
+	// The chunk boundaries are part of the name, changing the chunking invalidates previous results
+	resultPath := filepath.Join(chunksPath, fmt.Sprintf("%05d-%d-%d.json", c.Index, c.Start.Milliseconds(), c.Duration.Milliseconds()))
+	log := logging.FromContext(ctx, "vod")
+
+	raw, err := ioutil.ReadFile(resultPath)
+	if err == nil {
+		var result chunkResult
+		err = json.Unmarshal(raw, &result)
+		if err == nil {
+			log.Debug("Resuming from the persisted result", "path", resultPath)
+			return result, true, nil
+		}
+
+		log.Warn("Ignoring unreadable result", "path", resultPath, "err", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= config.Retries; attempt++ {
+		if attempt > 0 {
+			log.Warn("Retrying chunk", "attempt", attempt, "err", lastErr)
+
+			select {
+			case <-ctx.Done():
+				return chunkResult{}, false, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		var audio []byte
+		audio, lastErr = extractAudio(ctx, config.EncoderPath, config.Input, c.Start, c.Duration)
+		if lastErr != nil {
+			continue
+		}
+
+		var resp recognizers.Response
+		if config.Cache != nil {
+			resp, lastErr = config.Cache.Recognize(ctx, cache.Key(audio, config.Recognizer), func(ctx context.Context) (recognizers.Response, error) {
+				return recognize(ctx, config, recognizer, audio)
+			})
+		} else {
+			resp, lastErr = recognize(ctx, config, recognizer, audio)
+		}
+		if lastErr != nil {
+			continue
+		}
+
+		result := chunkResult{
+			Index:    c.Index,
+			Start:    c.Start.Seconds(),
+			Duration: c.Duration.Seconds(),
+			Response: resp,
+		}
+
+		raw, err := json.Marshal(result)
+		if err == nil {
+			err = ioutil.WriteFile(resultPath, raw, 0644)
+		}
+		if err != nil {
+			log.Warn("Could not persist result, it won't be resumable", "path", resultPath, "err", err)
+		}
+
+		return result, false, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no attempts made")
+	}
+
+	return chunkResult{}, false, lastErr
+}
+
+func recognize(ctx context.Context, config Config, recognizer recognizers.Adapter, audio []byte) (recognizers.Response, error) {
+	if !config.LongRunning {
+		return recognizer.Input(ctx, audio)
+	}
+
+	op, err := recognizer.(recognizers.LongRunningAdapter).StartLongRunning(ctx, audio)
+	if err != nil {
+		return recognizers.Response{}, err
+	}
+
+	logging.FromContext(ctx, "vod").Info("Started long-running operation", "operation", op.Name())
+	return recognizers.Await(ctx, op, config.PollInterval)
+}
+
+// merge offsets each chunk's words to absolute time, dropping the ones its neighbours are responsible for
+func merge(chunks []chunk, results []chunkResult) ([]recognizers.TimedWord, float32) {
+	words := make([]recognizers.TimedWord, 0)
+
+	var confidence float64
+	var weight int
+
+	for i, c := range chunks {
+		kept := 0
+
+		for _, word := range results[i].Response.Words {
+			word = word.Offset(c.Start)
+			middle := (word.Start.Duration() + word.End.Duration()) / 2
+
+			if middle < c.KeepFrom || middle >= c.KeepUntil {
+				continue
+			}
+
+			words = append(words, word)
+			kept++
+		}
+
+		confidence += float64(results[i].Response.Confidence) * float64(kept)
+		weight += kept
+	}
+
+	sort.SliceStable(words, func(i, j int) bool {
+		return words[i].Start.Duration() < words[j].Start.Duration()
+	})
+
+	// Neighbours time a boundary word slightly differently, both may keep it when its middle is near the boundary
+	deduplicated := words[:0]
+	for _, word := range words {
+		if n := len(deduplicated); n > 0 {
+			previous := deduplicated[n-1]
+			if strings.EqualFold(previous.Word, word.Word) && word.Start.Duration() < previous.End.Duration() {
+				continue
+			}
+		}
+
+		deduplicated = append(deduplicated, word)
+	}
+	words = deduplicated
+
+	if weight == 0 {
+		return words, 0
+	}
+
+	return words, float32(confidence / float64(weight))
+}
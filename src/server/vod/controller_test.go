@@ -0,0 +1,188 @@
+package vod
+
+import (
+	"fmt"
+	"reflect"
+	"server/transcriber/recognizers"
+	"strings"
+	"testing"
+	"time"
+)
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func TestPlanChunks(t *testing.T) {
+	tests := []struct {
+		name           string
+		total          float64
+		chunk, overlap float64
+		want           []chunk
+	}{
+		{"empty", 0, 55, 5, []chunk{}},
+		{
+			"shorter than a chunk", 30, 55, 5,
+			[]chunk{{Index: 0, Start: 0, Duration: seconds(30), KeepFrom: 0, KeepUntil: seconds(31)}},
+		},
+		{
+			"overlapping", 120, 55, 5,
+			[]chunk{
+				{Index: 0, Start: 0, Duration: seconds(60), KeepFrom: 0, KeepUntil: seconds(57.5)},
+				{Index: 1, Start: seconds(55), Duration: seconds(60), KeepFrom: seconds(57.5), KeepUntil: seconds(112.5)},
+				{Index: 2, Start: seconds(110), Duration: seconds(10), KeepFrom: seconds(112.5), KeepUntil: seconds(121)},
+			},
+		},
+		{
+			"last chunk within the overlap", 112, 55, 5,
+			[]chunk{
+				{Index: 0, Start: 0, Duration: seconds(60), KeepFrom: 0, KeepUntil: seconds(57.5)},
+				{Index: 1, Start: seconds(55), Duration: seconds(57), KeepFrom: seconds(57.5), KeepUntil: seconds(112.5)},
+				{Index: 2, Start: seconds(110), Duration: seconds(2), KeepFrom: seconds(112.5), KeepUntil: seconds(113)},
+			},
+		},
+		{
+			"without overlap", 100, 50, 0,
+			[]chunk{
+				{Index: 0, Start: 0, Duration: seconds(50), KeepFrom: 0, KeepUntil: seconds(50)},
+				{Index: 1, Start: seconds(50), Duration: seconds(50), KeepFrom: seconds(50), KeepUntil: seconds(101)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := planChunks(seconds(test.total), seconds(test.chunk), seconds(test.overlap))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("planChunks(%v, %v, %v) = %+v, want %+v", test.total, test.chunk, test.overlap, got, test.want)
+			}
+		})
+	}
+}
+
+// words are "word@start" pairs, in seconds relative to the chunk, each word lasting 0.8s
+func words(pairs ...string) []recognizers.TimedWord {
+	result := make([]recognizers.TimedWord, 0, len(pairs))
+	for _, pair := range pairs {
+		var start float64
+		fields := strings.Split(pair, "@")
+		fmt.Sscan(fields[1], &start)
+
+		result = append(result, recognizers.TimedWord{
+			Start: recognizers.FromDuration(seconds(start)),
+			End:   recognizers.FromDuration(seconds(start + 0.8)),
+			Word:  fields[0],
+		})
+	}
+
+	return result
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name           string
+		total          float64
+		chunk, overlap float64
+		responses      []recognizers.Response
+		want           string // "word@start" in absolute seconds
+		confidence     float32
+	}{
+		{
+			name:  "single chunk",
+			total: 10, chunk: 55, overlap: 5,
+			responses:  []recognizers.Response{{Words: words("hello@0", "world@1"), Confidence: 0.9}},
+			want:       "hello@0 world@1",
+			confidence: 0.9,
+		},
+		{
+			// Chunks [0, 14) and [10, 20), the boundary is at 12
+			name:  "overlap recognized by both chunks",
+			total: 20, chunk: 10, overlap: 4,
+			responses: []recognizers.Response{
+				{Words: words("a@0", "b@5", "c@10.5", "d@11.8", "ex@13.4"), Confidence: 0.9},
+				{Words: words("c@0.5", "d@1.8", "e@3", "f@8"), Confidence: 0.6},
+			},
+			want:       "a@0 b@5 c@10.5 d@11.8 e@13 f@18",
+			confidence: 0.75,
+		},
+		{
+			// A word centered on the boundary belongs to the later chunk only
+			name:  "word centered on the boundary",
+			total: 20, chunk: 10, overlap: 4,
+			responses: []recognizers.Response{
+				{Words: words("a@1", "mid@11.6"), Confidence: 1},
+				{Words: words("mid@1.6", "b@5"), Confidence: 1},
+			},
+			want:       "a@1 mid@11.6 b@15",
+			confidence: 1,
+		},
+		{
+			// Timed slightly later by the second chunk, the word's middle falls on either side of the boundary
+			name:  "word timed differently by each chunk",
+			total: 20, chunk: 10, overlap: 4,
+			responses: []recognizers.Response{
+				{Words: words("a@10", "Base@11.5"), Confidence: 1},
+				{Words: words("base@1.7", "b@3"), Confidence: 1},
+			},
+			want:       "a@10 Base@11.5 b@13",
+			confidence: 1,
+		},
+		{
+			name:  "word repeated across the boundary",
+			total: 20, chunk: 10, overlap: 4,
+			responses: []recognizers.Response{
+				{Words: words("no@11"), Confidence: 1},
+				{Words: words("no@1", "no@2"), Confidence: 1},
+			},
+			want:       "no@11 no@12",
+			confidence: 1,
+		},
+		{
+			name:  "three chunks",
+			total: 30, chunk: 10, overlap: 2,
+			responses: []recognizers.Response{
+				{Words: words("one@2", "two@10.2", "x@11"), Confidence: 0.5},
+				{Words: words("y@0", "two@0.2", "three@5", "four@10.5"), Confidence: 0.5},
+				{Words: words("four@0.5", "five@6"), Confidence: 0.5},
+			},
+			want:       "one@2 two@10.2 three@15 four@20.5 five@26",
+			confidence: 0.5,
+		},
+		{
+			name:  "silence",
+			total: 20, chunk: 10, overlap: 4,
+			responses:  []recognizers.Response{{Words: words()}, {Words: words()}},
+			want:       "",
+			confidence: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunks := planChunks(seconds(test.total), seconds(test.chunk), seconds(test.overlap))
+			if len(chunks) != len(test.responses) {
+				t.Fatalf("%d chunks for %d responses", len(chunks), len(test.responses))
+			}
+
+			results := make([]chunkResult, len(chunks))
+			for i, c := range chunks {
+				results[i] = chunkResult{Index: i, Start: c.Start.Seconds(), Duration: c.Duration.Seconds(), Response: test.responses[i]}
+			}
+
+			merged, confidence := merge(chunks, results)
+
+			got := make([]string, 0, len(merged))
+			for _, word := range merged {
+				got = append(got, fmt.Sprintf("%s@%v", word.Word, word.Start.Duration().Seconds()))
+			}
+
+			if strings.Join(got, " ") != test.want {
+				t.Errorf("merged %q, want %q", strings.Join(got, " "), test.want)
+			}
+
+			if confidence != test.confidence {
+				t.Errorf("confidence = %v, want %v", confidence, test.confidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package vod
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/captions"
+	"server/logging"
+	"sync"
+	"time"
+)
+
+// subtitlesDirName holds the subtitle playlist and its WebVTT segments
+const subtitlesDirName = "subtitles"
+
+func writeOutputs(config Config, transcript Transcript) error {
+	raw, err := json.MarshalIndent(transcript, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(config.OutputPath, "transcript.json"), raw, 0644)
+	if err != nil {
+		return err
+	}
+
+	cues := captions.Build(transcript.Words, captions.DefaultOptions)
+
+	var srt bytes.Buffer
+	captions.WriteSRT(&srt, config.Captions.LabelSRT(cues))
+	err = ioutil.WriteFile(filepath.Join(config.OutputPath, "captions.srt"), srt.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	var vtt bytes.Buffer
+	captions.WriteVTT(&vtt, config.Captions.LabelVTT(cues))
+	err = ioutil.WriteFile(filepath.Join(config.OutputPath, "captions.vtt"), vtt.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return writeSubtitlePlaylist(config, transcript, cues)
+}
+
+// writeSubtitlePlaylist splits the cues into segments, writing a complete HLS subtitle playlist
+func writeSubtitlePlaylist(config Config, transcript Transcript, cues []captions.Cue) error {
+	subtitlesPath := filepath.Join(config.OutputPath, subtitlesDirName)
+
+	err := os.MkdirAll(subtitlesPath, 0777)
+	if err != nil {
+		return err
+	}
+
+	total := time.Duration(transcript.Duration * float64(time.Second))
+	segments := make([]captions.Segment, 0)
+
+	for start := time.Duration(0); start < total; start += config.SegmentDuration {
+		duration := config.SegmentDuration
+		if start+duration > total {
+			duration = total - start
+		}
+
+		uri := fmt.Sprintf("%04d.vtt", len(segments))
+
+		var vtt bytes.Buffer
+		captions.WriteVTTSegment(&vtt, config.Captions.LabelVTT(captions.Window(cues, start, start+duration)), "MPEGTS:0,LOCAL:00:00:00.000")
+
+		err = ioutil.WriteFile(filepath.Join(subtitlesPath, uri), vtt.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+
+		segments = append(segments, captions.Segment{URI: uri, Duration: duration})
+	}
+
+	var playlist bytes.Buffer
+	captions.WritePlaylist(&playlist, segments, 0, 0, true)
+
+	return ioutil.WriteFile(filepath.Join(subtitlesPath, "playlist.m3u8"), playlist.Bytes(), 0644)
+}
+
+// progressTracker reports progress to the log and progress.json
+type progressTracker struct {
+	path string
+	log  *logging.Logger
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+func newProgressTracker(log *logging.Logger, outputPath string, total int) *progressTracker {
+	t := &progressTracker{
+		path:     filepath.Join(outputPath, "progress.json"),
+		log:      log,
+		progress: Progress{State: "running", Total: total},
+	}
+
+	t.write()
+	return t
+}
+
+func (t *progressTracker) done(resumed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.Done++
+	if resumed {
+		t.progress.Resumed++
+	}
+
+	t.log.Info("Progress", "done", t.progress.Done, "total", t.progress.Total, "resumed", t.progress.Resumed,
+		"percent", fmt.Sprintf("%.0f", 100*float64(t.progress.Done)/float64(t.progress.Total)))
+	t.write()
+}
+
+func (t *progressTracker) failed(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.Failed++
+	t.progress.Error = err.Error()
+	t.write()
+}
+
+func (t *progressTracker) finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.State = "complete"
+	if err != nil {
+		t.progress.State = "failed"
+		t.progress.Error = err.Error()
+	}
+
+	t.write()
+}
+
+// write must be called with the lock held
+func (t *progressTracker) write() {
+	t.progress.UpdatedAt = time.Now()
+
+	raw, err := json.MarshalIndent(t.progress, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(t.path, raw, 0644)
+	}
+
+	if err != nil {
+		t.log.Warn("Could not write progress", "path", t.path, "err", err)
+	}
+}
@@ -0,0 +1,82 @@
+package vod
+
+import (
+	"server/captions"
+	"server/transcriber/recognizers"
+	"server/transcriber/recognizers/cache"
+	"time"
+)
+
+// Config ...
+type Config struct {
+	EncoderPath     string
+	Input           string // A media file or a finished HLS playlist
+	OutputPath      string
+	ChunkDuration   time.Duration // Audio sent per recognition request, synchronous recognition is capped at one minute
+	Overlap         time.Duration // Audio shared by consecutive chunks so words cut at a boundary are recognized whole
+	Parallelism     int
+	Retries         int
+	SegmentDuration time.Duration // Duration of the WebVTT segments in the subtitle playlist
+	LongRunning     bool          // Transcribe chunks as long-running batch jobs, allowing much longer chunks
+	PollInterval    time.Duration // How often long-running jobs are polled
+	Recognizer      recognizers.Config
+	Captions        captions.Style
+	Cache           *cache.Cache // Optional, chunks already recognized with the same settings aren't sent again
+}
+
+// LongRunningChunkDuration ...
+// Inline audio is limited to 10MB, about 20 minutes at 64k
+const LongRunningChunkDuration = 15 * time.Minute
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		ChunkDuration:   50 * time.Second,
+		Overlap:         5 * time.Second,
+		Parallelism:     4,
+		Retries:         3,
+		SegmentDuration: 10 * time.Second,
+		PollInterval:    10 * time.Second,
+		Recognizer:      recognizers.DefaultConfig(),
+	}
+}
+
+// Transcript ...
+// The complete transcript for the asset, word times are absolute media times
+type Transcript struct {
+	Source     string                  `json:"source"`
+	Duration   float64                 `json:"duration"`
+	Confidence float32                 `json:"confidence"`
+	Words      []recognizers.TimedWord `json:"words"`
+}
+
+// Progress ...
+// Written to progress.json in the output directory as chunks complete
+type Progress struct {
+	State     string    `json:"state"` // "running", "failed" or "complete"
+	Total     int       `json:"total"`
+	Done      int       `json:"done"`
+	Resumed   int       `json:"resumed"` // Chunks reused from a previous run
+	Failed    int       `json:"failed"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// chunk is a section of the input's audio, transcribed in a single request
+type chunk struct {
+	Index    int
+	Start    time.Duration
+	Duration time.Duration
+
+	// The part of this chunk's words that are kept, the rest is covered by its neighbours
+	KeepFrom  time.Duration
+	KeepUntil time.Duration
+}
+
+// chunkResult is persisted per chunk so a failed job can be resumed
+type chunkResult struct {
+	Index    int                  `json:"index"`
+	Start    float64              `json:"start"`
+	Duration float64              `json:"duration"`
+	Response recognizers.Response `json:"response"`
+}